@@ -0,0 +1,11 @@
+ephemeral "chef_client_credentials" "deploy" {
+  client = "deploy"
+  ttl    = "30m"
+}
+
+provider "chef" {
+  alias        = "deploy"
+  server_url   = "https://chef.example.com/organizations/example/"
+  client_name  = "deploy"
+  key_material = ephemeral.chef_client_credentials.deploy.private_key_pem
+}
@@ -0,0 +1,5 @@
+ephemeral "chef_data_bag_item" "db" {
+  data_bag_name = "secrets"
+  item_name     = "database"
+  secret        = var.data_bag_secret
+}
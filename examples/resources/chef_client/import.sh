@@ -0,0 +1,2 @@
+# Clients are imported by name.
+terraform import chef_client.example my-client
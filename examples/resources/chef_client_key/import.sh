@@ -0,0 +1,2 @@
+# Client keys are imported as client_name+key_name.
+terraform import chef_client_key.example my-client+my-key
@@ -0,0 +1,2 @@
+# Data bags are imported by name.
+terraform import chef_data_bag.example my-bag
@@ -0,0 +1,2 @@
+# Data bag items are imported as data_bag_name/item_id.
+terraform import chef_data_bag_item.example my-bag/my-item
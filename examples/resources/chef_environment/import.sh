@@ -0,0 +1,2 @@
+# Environments are imported by name.
+terraform import chef_environment.example production
@@ -0,0 +1,2 @@
+# Nodes are imported by name.
+terraform import chef_node.example web01.example.com
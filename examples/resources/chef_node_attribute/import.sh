@@ -0,0 +1,2 @@
+# Node attributes are imported by node name, precedence and JSON pointer.
+terraform import chef_node_attribute.example web01.example.com/normal/nginx/port
@@ -0,0 +1,2 @@
+# Roles are imported by name.
+terraform import chef_role.example web
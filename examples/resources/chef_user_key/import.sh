@@ -0,0 +1,2 @@
+# User keys are imported as user_name+key_name.
+terraform import chef_user_key.example my-user+my-key
@@ -0,0 +1,248 @@
+package chefzero
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+var permissions = []string{"create", "read", "update", "delete", "grant"}
+
+// systemGroups are created with the organization and cannot be deleted.
+var systemGroups = map[string]bool{"admins": true, "billing-admins": true, "clients": true, "users": true}
+
+type aclEntry struct {
+	Actors []string `json:"actors"`
+	Groups []string `json:"groups"`
+}
+
+type acl map[string]aclEntry
+
+// defaultACL is the ACL of a new object, which grants everything to the
+// actor that created it and to admins, and lets users and clients read.
+func defaultACL(creator string) acl {
+	result := acl{}
+	for _, perm := range permissions {
+		entry := aclEntry{Actors: []string{creator}, Groups: []string{"admins"}}
+		if perm == "read" {
+			entry.Groups = append(entry.Groups, "clients", "users")
+		}
+		result[perm] = entry
+	}
+	return result
+}
+
+type group struct {
+	Name    string
+	Users   []string
+	Clients []string
+	Groups  []string
+}
+
+func (s *Server) groupJSON(g *group) map[string]interface{} {
+	return map[string]interface{}{
+		"name":      g.Name,
+		"groupname": g.Name,
+		"orgname":   s.Organization,
+		"actors":    append(append([]string{}, g.Users...), g.Clients...),
+		"users":     append([]string{}, g.Users...),
+		"clients":   append([]string{}, g.Clients...),
+		"groups":    append([]string{}, g.Groups...),
+	}
+}
+
+// objectExists reports whether the object that an ACL belongs to exists.
+func (s *Server) objectExists(collection, name string) bool {
+	var ok bool
+	switch collection {
+	case "nodes":
+		_, ok = s.org.nodes[name]
+	case "roles":
+		_, ok = s.org.roles[name]
+	case "environments":
+		_, ok = s.org.environments[name]
+	case "clients":
+		_, ok = s.org.clients[name]
+	case "data":
+		_, ok = s.org.dataBags[name]
+	case "groups":
+		_, ok = s.org.groups[name]
+	}
+	return ok
+}
+
+func (s *Server) serveACL(w http.ResponseWriter, r *request) {
+	collection, name := r.Segments[0], r.Segments[1]
+	if !s.objectExists(collection, name) || len(r.Segments) > 4 {
+		writeError(w, http.StatusNotFound, "Cannot load %s %s", collection, name)
+		return
+	}
+
+	// Objects created with the organization get their ACL on first use.
+	id := collection + "/" + name
+	objectACL, ok := s.org.acls[id]
+	if !ok {
+		objectACL = defaultACL(s.ClientName)
+		s.org.acls[id] = objectACL
+	}
+
+	if len(r.Segments) == 3 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, objectACL)
+		return
+	}
+
+	perm := r.Segments[3]
+	if _, ok := objectACL[perm]; !ok {
+		writeError(w, http.StatusNotFound, "Unknown permission %s", perm)
+		return
+	}
+	if r.Method != http.MethodPut {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	var body map[string]struct {
+		Actors  []string `json:"actors"`
+		Users   []string `json:"users"`
+		Clients []string `json:"clients"`
+		Groups  []string `json:"groups"`
+	}
+	if err := json.Unmarshal(r.Body, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "Request body is not a valid ACE")
+		return
+	}
+	ace, ok := body[perm]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Field '%s' missing", perm)
+		return
+	}
+
+	entry := aclEntry{Actors: []string{}, Groups: []string{}}
+	for _, actors := range [][]string{ace.Actors, ace.Users, ace.Clients} {
+		for _, actor := range actors {
+			_, isClient := s.org.clients[actor]
+			_, isUser := s.users[actor]
+			if !isClient && !isUser {
+				writeError(w, http.StatusBadRequest, "Invalid/missing actors: %s", actor)
+				return
+			}
+			entry.Actors = append(entry.Actors, actor)
+		}
+	}
+	for _, g := range ace.Groups {
+		if _, ok := s.org.groups[g]; !ok {
+			writeError(w, http.StatusBadRequest, "Invalid/missing groups: %s", g)
+			return
+		}
+		entry.Groups = append(entry.Groups, g)
+	}
+
+	objectACL[perm] = entry
+	writeJSON(w, http.StatusOK, map[string]interface{}{perm: entry})
+}
+
+func (s *Server) serveGroups(w http.ResponseWriter, r *request) {
+	if len(r.Segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.listing("groups", sortedNames(s.org.groups)))
+		case http.MethodPost:
+			body, err := decodeObject(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+				return
+			}
+			name, _ := body["groupname"].(string)
+			if name == "" {
+				name, _ = body["name"].(string)
+			}
+			if !validName(name) {
+				writeError(w, http.StatusBadRequest, "Field 'groupname' invalid")
+				return
+			}
+			if _, ok := s.org.groups[name]; ok {
+				writeError(w, http.StatusConflict, "Group already exists")
+				return
+			}
+			s.org.groups[name] = &group{Name: name}
+			s.org.acls["groups/"+name] = defaultACL(r.Requestor)
+			writeJSON(w, http.StatusCreated, map[string]string{"uri": s.url("groups", name)})
+		default:
+			writeMethodNotAllowed(w)
+		}
+		return
+	}
+
+	name := r.Segments[1]
+	g, ok := s.org.groups[name]
+	if !ok || len(r.Segments) > 2 {
+		writeError(w, http.StatusNotFound, "Cannot load group %s", name)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.groupJSON(g))
+	case http.MethodPut:
+		var body struct {
+			Actors struct {
+				Users   []string `json:"users"`
+				Clients []string `json:"clients"`
+				Groups  []string `json:"groups"`
+			} `json:"actors"`
+		}
+		if err := json.Unmarshal(r.Body, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "Request body is not a valid group")
+			return
+		}
+		for _, u := range body.Actors.Users {
+			if _, ok := s.users[u]; !ok {
+				writeError(w, http.StatusBadRequest, "Invalid/missing users: %s", u)
+				return
+			}
+		}
+		for _, c := range body.Actors.Clients {
+			if _, ok := s.org.clients[c]; !ok {
+				writeError(w, http.StatusBadRequest, "Invalid/missing clients: %s", c)
+				return
+			}
+		}
+		for _, member := range body.Actors.Groups {
+			if _, ok := s.org.groups[member]; !ok || member == name {
+				writeError(w, http.StatusBadRequest, "Invalid/missing groups: %s", member)
+				return
+			}
+		}
+		g.Users = append([]string{}, body.Actors.Users...)
+		g.Clients = append([]string{}, body.Actors.Clients...)
+		g.Groups = append([]string{}, body.Actors.Groups...)
+
+		// Updates are answered in the shape of the request.
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"name":      g.Name,
+			"groupname": g.Name,
+			"orgname":   s.Organization,
+			"actors": map[string][]string{
+				"users":   g.Users,
+				"clients": g.Clients,
+				"groups":  g.Groups,
+			},
+		})
+	case http.MethodDelete:
+		if systemGroups[name] {
+			writeError(w, http.StatusForbidden, "The %s group cannot be deleted", name)
+			return
+		}
+		delete(s.org.groups, name)
+		delete(s.org.acls, "groups/"+name)
+		for _, other := range s.org.groups {
+			other.Groups = without(other.Groups, name)
+		}
+		writeJSON(w, http.StatusOK, s.groupJSON(g))
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
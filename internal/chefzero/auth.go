@@ -0,0 +1,146 @@
+package chefzero
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// allowedClockSkew is how far the X-Ops-Timestamp of a request may be from
+// the server's clock, matching the Chef server default.
+const allowedClockSkew = 15 * time.Minute
+
+// authenticate verifies the signature of a request and returns the name of
+// the client or user that signed it. Requests to the organization may be
+// signed by a client or a user; requests outside it by a user, or by a
+// client of the organization since this server only serves one.
+func (s *Server) authenticate(r *http.Request, body []byte, global bool) (string, error) {
+	name := r.Header.Get("X-Ops-UserId")
+	if name == "" {
+		return "", fmt.Errorf("Missing X-Ops-UserId header")
+	}
+	failed := fmt.Errorf("Failed to authenticate as '%s'. Ensure that your node_name and client key are correct.", name)
+
+	actor, ok := s.org.clients[name]
+	if !ok || global {
+		if user, ok := s.users[name]; ok {
+			actor = user
+		}
+	}
+	if actor == nil {
+		return "", failed
+	}
+
+	version, err := signVersion(r.Header.Get("X-Ops-Sign"))
+	if err != nil {
+		return "", err
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, r.Header.Get("X-Ops-Timestamp"))
+	if err != nil {
+		return "", fmt.Errorf("Invalid X-Ops-Timestamp header")
+	}
+	if skew := s.now().Sub(timestamp); skew > allowedClockSkew || skew < -allowedClockSkew {
+		return "", fmt.Errorf("Failed to authenticate as '%s'. Synchronize the clock on your host.", name)
+	}
+
+	contentHash := r.Header.Get("X-Ops-Content-Hash")
+	if contentHash != hashBody(version, body) {
+		return "", fmt.Errorf("Invalid X-Ops-Content-Hash header")
+	}
+
+	var encoded strings.Builder
+	for i := 1; ; i++ {
+		part := r.Header.Get(fmt.Sprintf("X-Ops-Authorization-%d", i))
+		if part == "" {
+			break
+		}
+		encoded.WriteString(part)
+	}
+	signature, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil || len(signature) == 0 {
+		return "", failed
+	}
+
+	content := canonicalRequest(version, r, name)
+	for _, key := range actor.Keys {
+		if key.expired(s.now()) {
+			continue
+		}
+		if verifySignature(version, key.publicKey, content, signature) {
+			return name, nil
+		}
+	}
+	return "", failed
+}
+
+func signVersion(header string) (string, error) {
+	var version, algorithm string
+	for _, part := range strings.Split(header, ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "version":
+			version = v
+		case "algorithm":
+			algorithm = v
+		}
+	}
+
+	switch {
+	case version == "1.0" && (algorithm == "" || algorithm == "sha1"):
+		return version, nil
+	case version == "1.3" && (algorithm == "" || algorithm == "sha256"):
+		return version, nil
+	}
+	return "", fmt.Errorf("Unsupported authentication protocol version %q", header)
+}
+
+func hashBody(version string, body []byte) string {
+	if version == "1.3" {
+		sum := sha256.Sum256(body)
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+	sum := sha1.Sum(body)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// canonicalRequest builds the string that the client signed.
+func canonicalRequest(version string, r *http.Request, name string) string {
+	if version == "1.3" {
+		return strings.Join([]string{
+			"Method:" + r.Method,
+			"Path:" + r.URL.Path,
+			"X-Ops-Content-Hash:" + r.Header.Get("X-Ops-Content-Hash"),
+			"X-Ops-Sign:version=1.3",
+			"X-Ops-Timestamp:" + r.Header.Get("X-Ops-Timestamp"),
+			"X-Ops-UserId:" + name,
+			"X-Ops-Server-API-Version:" + r.Header.Get("X-Ops-Server-API-Version"),
+		}, "\n")
+	}
+
+	hashedPath := sha1.Sum([]byte(r.URL.Path))
+	return strings.Join([]string{
+		"Method:" + r.Method,
+		"Hashed Path:" + base64.StdEncoding.EncodeToString(hashedPath[:]),
+		"X-Ops-Content-Hash:" + r.Header.Get("X-Ops-Content-Hash"),
+		"X-Ops-Timestamp:" + r.Header.Get("X-Ops-Timestamp"),
+		"X-Ops-UserId:" + name,
+	}, "\n")
+}
+
+// verifySignature checks a signature over the canonical request. Version 1.0
+// signs the request itself with raw PKCS #1 v1.5 padding, as OpenSSL's
+// RSA_private_encrypt does; version 1.3 signs its SHA-256 digest.
+func verifySignature(version string, key *rsa.PublicKey, content string, signature []byte) bool {
+	if version == "1.3" {
+		digest := sha256.Sum256([]byte(content))
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	return rsa.VerifyPKCS1v15(key, crypto.Hash(0), []byte(content), signature) == nil
+}
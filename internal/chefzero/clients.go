@@ -0,0 +1,333 @@
+package chefzero
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// actor is a client or user, which authenticates with any of its keys.
+type actor struct {
+	Name      string
+	Validator bool
+	Keys      map[string]*key
+}
+
+type key struct {
+	PublicKey      string
+	ExpirationDate string
+
+	publicKey *rsa.PublicKey
+}
+
+// newActor returns an actor with a newly generated default key, and the
+// private half of that key.
+func newActor(name string) (*actor, string, error) {
+	privateKey, publicKey, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	k, err := newKey(publicKey, "infinity")
+	if err != nil {
+		return nil, "", err
+	}
+	return &actor{Name: name, Keys: map[string]*key{"default": k}}, privateKey, nil
+}
+
+func newKey(publicKey, expirationDate string) (*key, error) {
+	if expirationDate != "infinity" {
+		if _, err := time.Parse(time.RFC3339, expirationDate); err != nil || !strings.HasSuffix(expirationDate, "Z") {
+			return nil, fmt.Errorf("Invalid date, expected 'infinity' or the format YYYY-MM-DDThh:mm:ssZ")
+		}
+	}
+
+	pub, err := parsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &key{PublicKey: publicKey, ExpirationDate: expirationDate, publicKey: pub}, nil
+}
+
+func (k *key) expired(now time.Time) bool {
+	if k.ExpirationDate == "infinity" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, k.ExpirationDate)
+	return err != nil || !now.Before(t)
+}
+
+func parsePublicKey(s string) (*rsa.PublicKey, error) {
+	invalid := fmt.Errorf("Invalid public key")
+
+	block, _ := pem.Decode([]byte(strings.TrimSpace(s)))
+	if block == nil {
+		return nil, invalid
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, invalid
+		}
+		if rsaPub, ok := pub.(*rsa.PublicKey); ok {
+			return rsaPub, nil
+		}
+	case "RSA PUBLIC KEY":
+		pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err == nil {
+			return pub, nil
+		}
+	}
+	return nil, invalid
+}
+
+func (s *Server) clientJSON(c *actor) map[string]interface{} {
+	return map[string]interface{}{
+		"name":       c.Name,
+		"clientname": c.Name,
+		"orgname":    s.Organization,
+		"validator":  c.Validator,
+		"json_class": "Chef::ApiClient",
+		"chef_type":  "client",
+	}
+}
+
+func (s *Server) serveClients(w http.ResponseWriter, r *request) {
+	if len(r.Segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.listing("clients", sortedNames(s.org.clients)))
+		case http.MethodPost:
+			s.createClient(w, r)
+		default:
+			writeMethodNotAllowed(w)
+		}
+		return
+	}
+
+	name := r.Segments[1]
+	client, ok := s.org.clients[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Cannot load client %s", name)
+		return
+	}
+
+	if len(r.Segments) >= 3 {
+		if r.Segments[2] != "keys" {
+			writeError(w, http.StatusNotFound, "No route for %s", r.URL.Path)
+			return
+		}
+		s.serveKeys(w, r, client, s.url("clients", name), r.Segments[3:])
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.clientJSON(client))
+	case http.MethodPut:
+		body, err := decodeObject(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		if newName, _ := body["name"].(string); newName != "" && newName != name {
+			writeError(w, http.StatusBadRequest, "Renaming clients is not supported")
+			return
+		}
+		if validator, ok := body["validator"].(bool); ok {
+			client.Validator = validator
+		}
+		writeJSON(w, http.StatusOK, s.clientJSON(client))
+	case http.MethodDelete:
+		delete(s.org.clients, name)
+		delete(s.org.acls, "clients/"+name)
+		for _, g := range s.org.groups {
+			g.Clients = without(g.Clients, name)
+		}
+		writeJSON(w, http.StatusOK, s.clientJSON(client))
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) createClient(w http.ResponseWriter, r *request) {
+	body, err := decodeObject(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	name, _ := body["name"].(string)
+	if name == "" {
+		name, _ = body["clientname"].(string)
+	}
+	if !validName(name) {
+		writeError(w, http.StatusBadRequest, "Field 'name' invalid")
+		return
+	}
+	if _, ok := s.org.clients[name]; ok {
+		writeError(w, http.StatusConflict, "Client already exists")
+		return
+	}
+
+	client := &actor{Name: name, Keys: map[string]*key{}}
+	client.Validator, _ = body["validator"].(bool)
+
+	result := map[string]interface{}{"uri": s.url("clients", name)}
+	if createKey, _ := body["create_key"].(bool); createKey {
+		privateKey, publicKey, err := generateKey()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "%s", err)
+			return
+		}
+		client.Keys["default"], _ = newKey(publicKey, "infinity")
+		result["chef_key"] = map[string]interface{}{
+			"name":            "default",
+			"public_key":      publicKey,
+			"private_key":     privateKey,
+			"expiration_date": "infinity",
+			"uri":             s.url("clients", name, "keys", "default"),
+		}
+	}
+
+	s.org.clients[name] = client
+	s.org.acls["clients/"+name] = defaultACL(r.Requestor)
+	s.org.groups["clients"].Clients = append(s.org.groups["clients"].Clients, name)
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// serveKeys implements the keys endpoints of a client or user, below the
+// given base URL.
+func (s *Server) serveKeys(w http.ResponseWriter, r *request, owner *actor, baseURL string, segments []string) {
+	keyJSON := func(name string, k *key) map[string]interface{} {
+		return map[string]interface{}{
+			"name":            name,
+			"public_key":      k.PublicKey,
+			"expiration_date": k.ExpirationDate,
+		}
+	}
+	itemJSON := func(name string, k *key) map[string]interface{} {
+		return map[string]interface{}{
+			"name":    name,
+			"uri":     baseURL + "/keys/" + name,
+			"expired": k.expired(s.now()),
+		}
+	}
+
+	if len(segments) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			items := []map[string]interface{}{}
+			for _, name := range sortedNames(owner.Keys) {
+				items = append(items, itemJSON(name, owner.Keys[name]))
+			}
+			writeJSON(w, http.StatusOK, items)
+		case http.MethodPost:
+			body, err := decodeObject(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+				return
+			}
+			name, _ := body["name"].(string)
+			if !validName(name) {
+				writeError(w, http.StatusBadRequest, "Field 'name' invalid")
+				return
+			}
+			if _, ok := owner.Keys[name]; ok {
+				writeError(w, http.StatusConflict, "Key already exists")
+				return
+			}
+			publicKey, _ := body["public_key"].(string)
+			expirationDate, _ := body["expiration_date"].(string)
+			k, err := newKey(publicKey, expirationDate)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+				return
+			}
+			owner.Keys[name] = k
+			writeJSON(w, http.StatusCreated, itemJSON(name, k))
+		default:
+			writeMethodNotAllowed(w)
+		}
+		return
+	}
+
+	name := segments[0]
+	k, ok := owner.Keys[name]
+	if !ok || len(segments) > 1 {
+		writeError(w, http.StatusNotFound, "Cannot load key %s", name)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, keyJSON(name, k))
+	case http.MethodPut:
+		body, err := decodeObject(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		publicKey, expirationDate := k.PublicKey, k.ExpirationDate
+		if v, ok := body["public_key"].(string); ok && v != "" {
+			publicKey = v
+		}
+		if v, ok := body["expiration_date"].(string); ok && v != "" {
+			expirationDate = v
+		}
+		updated, err := newKey(publicKey, expirationDate)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+
+		status := http.StatusOK
+		if newName, _ := body["name"].(string); newName != "" && newName != name {
+			if _, ok := owner.Keys[newName]; ok {
+				writeError(w, http.StatusConflict, "Key already exists")
+				return
+			}
+			delete(owner.Keys, name)
+			name = newName
+			status = http.StatusCreated
+		}
+		owner.Keys[name] = updated
+		writeJSON(w, status, keyJSON(name, updated))
+	case http.MethodDelete:
+		delete(owner.Keys, name)
+		writeJSON(w, http.StatusOK, keyJSON(name, k))
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// validName reports whether name is acceptable as the name of an object,
+// which the Chef server restricts to letters, digits and "_-.:".
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("_-.:", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func without(names []string, name string) []string {
+	result := make([]string, 0, len(names))
+	for _, n := range names {
+		if n != name {
+			result = append(result, n)
+		}
+	}
+	return result
+}
@@ -0,0 +1,359 @@
+package chefzero
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Cookbook versions are stored as uploaded, once the files they refer to
+// are in the file store, and returned with the URLs to download the files.
+// Cookbook artifacts are not supported.
+
+// serveCookbooks implements the cookbooks endpoints and the universe.
+func (s *Server) serveCookbooks(w http.ResponseWriter, r *request) {
+	switch {
+	case r.Segments[0] == "universe":
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		s.serveUniverse(w)
+		return
+	case r.Segments[0] == "cookbook_artifacts":
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		if len(r.Segments) == 1 {
+			writeJSON(w, http.StatusOK, map[string]interface{}{})
+			return
+		}
+		writeError(w, http.StatusNotFound, "Cannot find a cookbook named %s", r.Segments[1])
+		return
+	}
+
+	if len(r.Segments) <= 2 && r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+	switch {
+	case len(r.Segments) == 1:
+		s.serveCookbookListing(w, r, sortedNames(s.org.cookbooks), 1, nil)
+	case r.Segments[1] == "_latest":
+		latest := map[string]string{}
+		for name, versions := range s.org.cookbooks {
+			latest[name] = s.url("cookbooks", name, sortedVersions(versions)[0])
+		}
+		writeJSON(w, http.StatusOK, latest)
+	case r.Segments[1] == "_recipes":
+		var recipes []string
+		for _, name := range sortedNames(s.org.cookbooks) {
+			versions := s.org.cookbooks[name]
+			recipes = append(recipes, cookbookRecipes(name, versions[sortedVersions(versions)[0]])...)
+		}
+		writeJSON(w, http.StatusOK, nonNil(recipes))
+	case len(r.Segments) == 2:
+		if _, ok := s.org.cookbooks[r.Segments[1]]; !ok {
+			writeError(w, http.StatusNotFound, "Cannot find a cookbook named %s", r.Segments[1])
+			return
+		}
+		s.serveCookbookListing(w, r, []string{r.Segments[1]}, -1, nil)
+	case len(r.Segments) == 3:
+		s.serveCookbookVersion(w, r, r.Segments[1], r.Segments[2])
+	default:
+		writeError(w, http.StatusNotFound, "No route for %s", r.URL.Path)
+	}
+}
+
+// serveCookbookListing lists the versions of the named cookbooks, newest
+// first, that satisfy the constraints. The num_versions parameter limits
+// the versions listed per cookbook, to defaultVersions if it is not given;
+// a negative number lists them all.
+func (s *Server) serveCookbookListing(w http.ResponseWriter, r *request, names []string, defaultVersions int, constraints map[string]interface{}) {
+	limit := defaultVersions
+	if v := r.URL.Query().Get("num_versions"); v == "all" {
+		limit = -1
+	} else if v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "You have requested an invalid number of versions (x >= 0 || 'all')")
+			return
+		}
+		limit = n
+	}
+
+	result := map[string]interface{}{}
+	for _, name := range names {
+		constraint, _ := constraints[name].(string)
+		versions := []interface{}{}
+		for _, version := range sortedVersions(s.org.cookbooks[name]) {
+			if limit >= 0 && len(versions) >= limit {
+				break
+			}
+			if constraint != "" && !satisfiesConstraint(version, constraint) {
+				continue
+			}
+			versions = append(versions, map[string]string{
+				"version": version,
+				"url":     s.url("cookbooks", name, version),
+			})
+		}
+		result[name] = map[string]interface{}{
+			"url":      s.url("cookbooks", name),
+			"versions": versions,
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// serveCookbookVersion reads, uploads and deletes a cookbook version.
+// Frozen versions can only be uploaded again with the force parameter.
+func (s *Server) serveCookbookVersion(w http.ResponseWriter, r *request, name, version string) {
+	versions := s.org.cookbooks[name]
+	if version == "_latest" && len(versions) > 0 {
+		version = sortedVersions(versions)[0]
+	}
+	cookbook, exists := versions[version]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "Cannot find a cookbook named %s with version %s", name, version)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.withFileURLs(cookbook))
+	case http.MethodPut:
+		if _, ok := parseVersion(version); !ok {
+			writeError(w, http.StatusBadRequest, "Invalid cookbook version '%s'.", version)
+			return
+		}
+		body, err := decodeObject(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		if body["cookbook_name"] != name {
+			writeError(w, http.StatusBadRequest, "Field 'cookbook_name' invalid")
+			return
+		}
+		if body["version"] != version {
+			writeError(w, http.StatusBadRequest, "Field 'version' invalid")
+			return
+		}
+		if missing := s.missingChecksums(body); len(missing) > 0 {
+			writeError(w, http.StatusBadRequest, "Manifest has checksum %s but it hasn't yet been uploaded", missing[0])
+			return
+		}
+		if exists && cookbook["frozen?"] == true && r.URL.Query().Get("force") != "true" {
+			writeError(w, http.StatusConflict, "The cookbook %s at version %s is frozen. Use the 'force' option to override.", name, version)
+			return
+		}
+		setDefault(body, "name", name+"-"+version)
+		setDefault(body, "json_class", "Chef::CookbookVersion")
+		setDefault(body, "chef_type", "cookbook_version")
+		setDefault(body, "frozen?", false)
+		setDefault(body, "metadata", map[string]interface{}{})
+		if versions == nil {
+			versions = map[string]map[string]interface{}{}
+			s.org.cookbooks[name] = versions
+		}
+		versions[version] = copyObject(body)
+		status := http.StatusCreated
+		if exists {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, body)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "Cannot find a cookbook named %s with version %s", name, version)
+			return
+		}
+		delete(versions, version)
+		if len(versions) == 0 {
+			delete(s.org.cookbooks, name)
+		}
+		writeJSON(w, http.StatusOK, cookbook)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// withFileURLs returns a copy of a cookbook version in which every file has
+// the URL to download it from the file store.
+func (s *Server) withFileURLs(cookbook map[string]interface{}) map[string]interface{} {
+	cookbook = copyObject(cookbook)
+	for _, items := range cookbook {
+		list, ok := items.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, item := range list {
+			if file, ok := item.(map[string]interface{}); ok {
+				if sum, ok := file["checksum"].(string); ok {
+					file["url"] = s.URL + fileStorePrefix + sum
+				}
+			}
+		}
+	}
+	return cookbook
+}
+
+// serveUniverse lists every cookbook version with its dependencies.
+func (s *Server) serveUniverse(w http.ResponseWriter) {
+	universe := map[string]interface{}{}
+	for name, versions := range s.org.cookbooks {
+		entries := map[string]interface{}{}
+		for version, cookbook := range versions {
+			metadata, _ := cookbook["metadata"].(map[string]interface{})
+			dependencies, _ := metadata["dependencies"].(map[string]interface{})
+			if dependencies == nil {
+				dependencies = map[string]interface{}{}
+			}
+			entries[version] = map[string]interface{}{
+				"location_path": s.url("cookbooks"),
+				"location_type": "chef_server",
+				"dependencies":  dependencies,
+			}
+		}
+		universe[name] = entries
+	}
+	writeJSON(w, http.StatusOK, universe)
+}
+
+// environmentRecipes lists the recipes of the newest version of every
+// cookbook that the cookbook constraints of an environment allow.
+func (s *Server) environmentRecipes(constraints map[string]interface{}) []string {
+	var recipes []string
+	for _, name := range sortedNames(s.org.cookbooks) {
+		constraint, _ := constraints[name].(string)
+		for _, version := range sortedVersions(s.org.cookbooks[name]) {
+			if constraint == "" || satisfiesConstraint(version, constraint) {
+				recipes = append(recipes, cookbookRecipes(name, s.org.cookbooks[name][version])...)
+				break
+			}
+		}
+	}
+	return nonNil(recipes)
+}
+
+// cookbookRecipes returns the qualified names of the recipes of a cookbook
+// version, sorted, with the default recipe named after the cookbook. Recipes
+// are read from the all_files segment that current clients upload, or from
+// the recipes segment of older ones.
+func cookbookRecipes(name string, cookbook map[string]interface{}) []string {
+	var files []string
+	for _, segment := range []string{"all_files", "recipes"} {
+		items, _ := cookbook[segment].([]interface{})
+		for _, item := range items {
+			file, _ := item.(map[string]interface{})
+			fileName, _ := file["name"].(string)
+			if segment == "all_files" {
+				var ok bool
+				if fileName, ok = strings.CutPrefix(fileName, "recipes/"); !ok {
+					continue
+				}
+			}
+			if recipe, ok := strings.CutSuffix(fileName, ".rb"); ok && !strings.Contains(recipe, "/") {
+				files = append(files, recipe)
+			}
+		}
+	}
+	sort.Strings(files)
+
+	var recipes []string
+	for i, recipe := range files {
+		if i > 0 && recipe == files[i-1] {
+			continue
+		}
+		if recipe == "default" {
+			recipes = append(recipes, name)
+		} else {
+			recipes = append(recipes, name+"::"+recipe)
+		}
+	}
+	return recipes
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// sortedVersions returns the versions of a cookbook, newest first.
+func sortedVersions(versions map[string]map[string]interface{}) []string {
+	names := sortedNames(versions)
+	sort.SliceStable(names, func(i, j int) bool {
+		vi, _ := parseVersion(names[i])
+		vj, _ := parseVersion(names[j])
+		return compareVersions(vi, vj) > 0
+	})
+	return names
+}
+
+// parseVersion parses a cookbook version of two or three numbers.
+func parseVersion(v string) ([3]int, bool) {
+	var parsed [3]int
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+// satisfiesConstraint reports whether a version satisfies a cookbook version
+// constraint such as "= 1.0.0", ">= 1.2" or "~> 2.1". Constraints that do
+// not parse are satisfied by no version.
+func satisfiesConstraint(version, constraint string) bool {
+	op, target := "=", strings.TrimSpace(constraint)
+	if fields := strings.Fields(constraint); len(fields) == 2 {
+		op, target = fields[0], fields[1]
+	}
+	v, ok := parseVersion(version)
+	t, tok := parseVersion(target)
+	if !ok || !tok {
+		return false
+	}
+
+	cmp := compareVersions(v, t)
+	switch op {
+	case "=":
+		return cmp == 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "~>":
+		// ~> 2.1 allows 2.x from 2.1 on, and ~> 2.1.3 allows 2.1.x from
+		// 2.1.3 on.
+		upper := [3]int{t[0], t[1] + 1, 0}
+		if strings.Count(target, ".") == 1 {
+			upper = [3]int{t[0] + 1, 0, 0}
+		}
+		return cmp >= 0 && compareVersions(v, upper) < 0
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package chefzero
+
+import (
+	"net/http"
+)
+
+func (s *Server) serveDataBags(w http.ResponseWriter, r *request) {
+	if len(r.Segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.listing("data", sortedNames(s.org.dataBags)))
+		case http.MethodPost:
+			body, err := decodeObject(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+				return
+			}
+			name, _ := body["name"].(string)
+			if !validName(name) {
+				writeError(w, http.StatusBadRequest, "Field 'name' invalid")
+				return
+			}
+			if _, ok := s.org.dataBags[name]; ok {
+				writeError(w, http.StatusConflict, "Data bag already exists")
+				return
+			}
+			s.org.dataBags[name] = map[string]map[string]interface{}{}
+			s.org.acls["data/"+name] = defaultACL(r.Requestor)
+			writeJSON(w, http.StatusCreated, map[string]string{"uri": s.url("data", name)})
+		default:
+			writeMethodNotAllowed(w)
+		}
+		return
+	}
+
+	bagName := r.Segments[1]
+	bag, ok := s.org.dataBags[bagName]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Cannot load data bag %s", bagName)
+		return
+	}
+
+	if len(r.Segments) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.listing("data/"+bagName, sortedNames(bag)))
+		case http.MethodPost:
+			item, ok := s.decodeDataBagItem(w, r, "")
+			if !ok {
+				return
+			}
+			id := item["id"].(string)
+			if _, exists := bag[id]; exists {
+				writeError(w, http.StatusConflict, "Data Bag Item already exists")
+				return
+			}
+			bag[id] = item
+			writeJSON(w, http.StatusCreated, item)
+		case http.MethodDelete:
+			delete(s.org.dataBags, bagName)
+			delete(s.org.acls, "data/"+bagName)
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"name":       bagName,
+				"json_class": "Chef::DataBag",
+				"chef_type":  "data_bag",
+			})
+		default:
+			writeMethodNotAllowed(w)
+		}
+		return
+	}
+
+	id := r.Segments[2]
+	item, ok := bag[id]
+	if !ok || len(r.Segments) > 3 {
+		writeError(w, http.StatusNotFound, "Cannot load data bag item %s for data bag %s", id, bagName)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, item)
+	case http.MethodPut:
+		updated, ok := s.decodeDataBagItem(w, r, id)
+		if !ok {
+			return
+		}
+		bag[id] = updated
+		writeJSON(w, http.StatusOK, updated)
+	case http.MethodDelete:
+		delete(bag, id)
+		writeJSON(w, http.StatusOK, item)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// decodeDataBagItem decodes an item from a request body. Items may be sent
+// as is or wrapped in raw_data, as older clients do.
+func (s *Server) decodeDataBagItem(w http.ResponseWriter, r *request, id string) (map[string]interface{}, bool) {
+	item, err := decodeObject(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return nil, false
+	}
+	if raw, ok := item["raw_data"].(map[string]interface{}); ok {
+		item = raw
+	}
+
+	itemId, _ := item["id"].(string)
+	switch {
+	case id == "" && !validName(itemId):
+		writeError(w, http.StatusBadRequest, "Field 'id' missing")
+		return nil, false
+	case id != "" && itemId == "":
+		item["id"] = id
+	case id != "" && itemId != id:
+		writeError(w, http.StatusBadRequest, "DataBagItem name mismatch.")
+		return nil, false
+	}
+	return item, true
+}
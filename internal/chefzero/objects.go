@@ -0,0 +1,287 @@
+package chefzero
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// objectType describes one of the collections of JSON objects that the
+// server stores as given, after filling in defaults.
+type objectType struct {
+	Collection string
+	Kind       string
+
+	// Normalize fills in defaults and rejects invalid objects.
+	Normalize func(map[string]interface{}) (map[string]interface{}, error)
+
+	// ReadOnly names objects that cannot be modified or deleted.
+	ReadOnly map[string]string
+}
+
+var (
+	nodeType = objectType{
+		Collection: "nodes",
+		Kind:       "node",
+		Normalize:  nodeDefaults,
+	}
+	roleType = objectType{
+		Collection: "roles",
+		Kind:       "role",
+		Normalize:  roleDefaults,
+	}
+	environmentType = objectType{
+		Collection: "environments",
+		Kind:       "environment",
+		Normalize: func(v map[string]interface{}) (map[string]interface{}, error) {
+			return environmentDefaults(v), nil
+		},
+		ReadOnly: map[string]string{"_default": "The '_default' environment cannot be modified."},
+	}
+)
+
+// serveObjects implements listing, creating, reading, updating and deleting
+// the objects of a collection.
+func (s *Server) serveObjects(w http.ResponseWriter, r *request, store map[string]map[string]interface{}, t objectType) {
+	if len(r.Segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.listing(t.Collection, sortedNames(store)))
+		case http.MethodPost:
+			obj, ok := s.decodeNamedObject(w, r, t, "")
+			if !ok {
+				return
+			}
+			name := obj["name"].(string)
+			if _, exists := store[name]; exists {
+				writeError(w, http.StatusConflict, "%s already exists", capitalize(t.Kind))
+				return
+			}
+			store[name] = obj
+			s.org.acls[t.Collection+"/"+name] = defaultACL(r.Requestor)
+			writeJSON(w, http.StatusCreated, map[string]string{"uri": s.url(t.Collection, name)})
+		default:
+			writeMethodNotAllowed(w)
+		}
+		return
+	}
+
+	name := r.Segments[1]
+	obj, exists := store[name]
+	if !exists {
+		writeError(w, http.StatusNotFound, "Cannot load %s %s", t.Kind, name)
+		return
+	}
+	if len(r.Segments) > 2 {
+		writeError(w, http.StatusNotFound, "No route for %s", r.URL.Path)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, obj)
+	case http.MethodPut:
+		if msg, ok := t.ReadOnly[name]; ok {
+			writeError(w, http.StatusMethodNotAllowed, "%s", msg)
+			return
+		}
+		updated, ok := s.decodeNamedObject(w, r, t, name)
+		if !ok {
+			return
+		}
+		store[name] = updated
+		writeJSON(w, http.StatusOK, updated)
+	case http.MethodDelete:
+		if msg, ok := t.ReadOnly[name]; ok {
+			writeError(w, http.StatusMethodNotAllowed, "%s", msg)
+			return
+		}
+		delete(store, name)
+		delete(s.org.acls, t.Collection+"/"+name)
+		writeJSON(w, http.StatusOK, obj)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// decodeNamedObject decodes and normalizes the object in a request body. For
+// updates, name is the name in the URL, which the body must match if it
+// names the object at all.
+func (s *Server) decodeNamedObject(w http.ResponseWriter, r *request, t objectType, name string) (map[string]interface{}, bool) {
+	obj, err := decodeObject(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return nil, false
+	}
+
+	bodyName, _ := obj["name"].(string)
+	switch {
+	case name == "" && !validName(bodyName):
+		writeError(w, http.StatusBadRequest, "Field 'name' invalid")
+		return nil, false
+	case name != "" && bodyName == "":
+		obj["name"] = name
+	case name != "" && bodyName != name:
+		writeError(w, http.StatusBadRequest, "%s name mismatch.", capitalize(t.Kind))
+		return nil, false
+	}
+
+	obj, err = t.Normalize(obj)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return nil, false
+	}
+	return obj, true
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func setDefault(obj map[string]interface{}, key string, value interface{}) {
+	if v, ok := obj[key]; !ok || v == nil {
+		obj[key] = value
+	}
+}
+
+func nodeDefaults(obj map[string]interface{}) (map[string]interface{}, error) {
+	setDefault(obj, "chef_environment", "_default")
+	setDefault(obj, "json_class", "Chef::Node")
+	setDefault(obj, "chef_type", "node")
+	for _, key := range []string{"normal", "default", "override", "automatic"} {
+		setDefault(obj, key, map[string]interface{}{})
+	}
+
+	runList, err := normalizeRunList(obj["run_list"])
+	if err != nil {
+		return nil, err
+	}
+	obj["run_list"] = runList
+	return obj, nil
+}
+
+func roleDefaults(obj map[string]interface{}) (map[string]interface{}, error) {
+	setDefault(obj, "description", "")
+	setDefault(obj, "json_class", "Chef::Role")
+	setDefault(obj, "chef_type", "role")
+	setDefault(obj, "default_attributes", map[string]interface{}{})
+	setDefault(obj, "override_attributes", map[string]interface{}{})
+
+	runList, err := normalizeRunList(obj["run_list"])
+	if err != nil {
+		return nil, err
+	}
+	obj["run_list"] = runList
+
+	envRunLists, _ := obj["env_run_lists"].(map[string]interface{})
+	normalized := make(map[string]interface{}, len(envRunLists))
+	for env, v := range envRunLists {
+		runList, err := normalizeRunList(v)
+		if err != nil {
+			return nil, err
+		}
+		normalized[env] = runList
+	}
+	obj["env_run_lists"] = normalized
+	return obj, nil
+}
+
+func environmentDefaults(obj map[string]interface{}) map[string]interface{} {
+	setDefault(obj, "description", "")
+	setDefault(obj, "json_class", "Chef::Environment")
+	setDefault(obj, "chef_type", "environment")
+	setDefault(obj, "cookbook_versions", map[string]interface{}{})
+	setDefault(obj, "default_attributes", map[string]interface{}{})
+	setDefault(obj, "override_attributes", map[string]interface{}{})
+	return obj
+}
+
+var runListItemRegexp = regexp.MustCompile(`^(recipe\[[\w.:-]+(@\d+\.\d+(\.\d+)?)?\]|role\[[\w.-]+\])$`)
+
+// normalizeRunList qualifies naked recipes the way the Chef server does.
+func normalizeRunList(v interface{}) ([]interface{}, error) {
+	items, ok := v.([]interface{})
+	if v != nil && !ok {
+		return nil, fmt.Errorf("Field 'run_list' is not a valid run list")
+	}
+
+	result := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("Field 'run_list' is not a valid run list")
+		}
+		if !strings.Contains(s, "[") {
+			s = "recipe[" + s + "]"
+		}
+		if !runListItemRegexp.MatchString(s) {
+			return nil, fmt.Errorf("Field 'run_list' is not a valid run list")
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func (s *Server) serveNodes(w http.ResponseWriter, r *request) {
+	s.serveObjects(w, r, s.org.nodes, nodeType)
+}
+
+func (s *Server) serveRoles(w http.ResponseWriter, r *request) {
+	if len(r.Segments) >= 3 && r.Segments[2] == "environments" {
+		role, ok := s.org.roles[r.Segments[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Cannot load role %s", r.Segments[1])
+			return
+		}
+		envRunLists, _ := role["env_run_lists"].(map[string]interface{})
+		if len(r.Segments) == 3 {
+			writeJSON(w, http.StatusOK, append([]string{"_default"}, sortedNames(envRunLists)...))
+			return
+		}
+		runList, ok := envRunLists[r.Segments[3]]
+		if !ok {
+			runList = role["run_list"]
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"run_list": runList})
+		return
+	}
+	s.serveObjects(w, r, s.org.roles, roleType)
+}
+
+func (s *Server) serveEnvironments(w http.ResponseWriter, r *request) {
+	if len(r.Segments) < 3 {
+		s.serveObjects(w, r, s.org.environments, environmentType)
+		return
+	}
+
+	name := r.Segments[1]
+	if _, ok := s.org.environments[name]; !ok {
+		writeError(w, http.StatusNotFound, "Cannot load environment %s", name)
+		return
+	}
+
+	switch r.Segments[2] {
+	case "nodes":
+		var names []string
+		for _, nodeName := range sortedNames(s.org.nodes) {
+			if s.org.nodes[nodeName]["chef_environment"] == name {
+				names = append(names, nodeName)
+			}
+		}
+		writeJSON(w, http.StatusOK, s.listing("nodes", names))
+	case "cookbooks":
+		constraints, _ := s.org.environments[name]["cookbook_versions"].(map[string]interface{})
+		s.serveCookbookListing(w, r, sortedNames(s.org.cookbooks), 1, constraints)
+	case "recipes":
+		constraints, _ := s.org.environments[name]["cookbook_versions"].(map[string]interface{})
+		writeJSON(w, http.StatusOK, s.environmentRecipes(constraints))
+	default:
+		writeError(w, http.StatusNotFound, "No route for %s", r.URL.Path)
+	}
+}
@@ -0,0 +1,141 @@
+package chefzero
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cookbook files are uploaded the way knife does: a sandbox is created for
+// the checksums of the files, the files the server lacks are uploaded to
+// the file store, and the sandbox is committed before the cookbook version
+// that refers to them. File store URLs stand in for the presigned URLs of
+// the real server, so they are not authenticated.
+
+// fileStorePrefix is the path of the file store below the server root.
+const fileStorePrefix = "/file_store/checksums/"
+
+type sandbox struct {
+	Checksums []string
+	Created   time.Time
+}
+
+// serveSandboxes creates and commits sandboxes.
+func (s *Server) serveSandboxes(w http.ResponseWriter, r *request) {
+	switch {
+	case len(r.Segments) == 1 && r.Method == http.MethodPost:
+		body, err := decodeObject(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		sums, _ := body["checksums"].(map[string]interface{})
+		if len(sums) == 0 {
+			writeError(w, http.StatusBadRequest, "Field 'checksums' missing")
+			return
+		}
+
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			writeError(w, http.StatusInternalServerError, "%s", err)
+			return
+		}
+		box := &sandbox{Checksums: sortedNames(sums), Created: s.now().UTC()}
+		checksums := map[string]interface{}{}
+		for _, sum := range box.Checksums {
+			_, uploaded := s.org.checksums[sum]
+			checksums[sum] = map[string]interface{}{
+				"url":          s.URL + fileStorePrefix + sum,
+				"needs_upload": !uploaded,
+			}
+		}
+		s.org.sandboxes[hex.EncodeToString(id)] = box
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"sandbox_id": hex.EncodeToString(id),
+			"uri":        s.url("sandboxes", hex.EncodeToString(id)),
+			"checksums":  checksums,
+		})
+	case len(r.Segments) == 2 && r.Method == http.MethodPut:
+		id := r.Segments[1]
+		box, ok := s.org.sandboxes[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "No such sandbox '%s'.", id)
+			return
+		}
+		body, err := decodeObject(r.Body)
+		if err != nil || body["is_completed"] != true {
+			writeError(w, http.StatusBadRequest, "Field 'is_completed' invalid")
+			return
+		}
+		var missing []string
+		for _, sum := range box.Checksums {
+			if _, ok := s.org.checksums[sum]; !ok {
+				missing = append(missing, sum)
+			}
+		}
+		if len(missing) > 0 {
+			writeError(w, http.StatusServiceUnavailable, "Checksums not uploaded: %s", strings.Join(missing, ", "))
+			return
+		}
+		delete(s.org.sandboxes, id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"guid":         id,
+			"name":         id,
+			"checksums":    box.Checksums,
+			"create_time":  box.Created.Format(time.RFC3339),
+			"is_completed": true,
+		})
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// serveFileStore stores and returns the content of cookbook files by their
+// MD5 checksum.
+func (s *Server) serveFileStore(w http.ResponseWriter, r *http.Request, body []byte) {
+	sum := strings.TrimPrefix(r.URL.Path, fileStorePrefix)
+	switch r.Method {
+	case http.MethodGet:
+		content, ok := s.org.checksums[sum]
+		if !ok {
+			writeError(w, http.StatusNotFound, "No such file %s", sum)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-binary")
+		w.Write(content)
+	case http.MethodPut:
+		digest := md5.Sum(body)
+		if hex.EncodeToString(digest[:]) != sum {
+			writeError(w, http.StatusBadRequest, "The content does not match checksum %s", sum)
+			return
+		}
+		s.org.checksums[sum] = body
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// missingChecksums returns the checksums of the files of a cookbook version
+// that were never uploaded, sorted.
+func (s *Server) missingChecksums(cookbook map[string]interface{}) []string {
+	missing := map[string]bool{}
+	for _, items := range cookbook {
+		list, ok := items.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, item := range list {
+			file, _ := item.(map[string]interface{})
+			if sum, ok := file["checksum"].(string); ok {
+				if _, uploaded := s.org.checksums[sum]; !uploaded {
+					missing[sum] = true
+				}
+			}
+		}
+	}
+	return sortedNames(missing)
+}
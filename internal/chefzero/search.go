@@ -0,0 +1,447 @@
+package chefzero
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// searchDocument is an object as seen by search: the object returned in
+// results, and the fields it is indexed under.
+type searchDocument struct {
+	Name   string
+	URL    string
+	Object map[string]interface{}
+
+	// Attributes is what partial search paths are resolved against.
+	Attributes map[string]interface{}
+	Fields     map[string][]string
+}
+
+func (s *Server) serveSearch(w http.ResponseWriter, r *request) {
+	if len(r.Segments) == 1 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		indexes := []string{"client", "environment", "node", "role"}
+		result := make(map[string]string)
+		for _, index := range append(indexes, sortedNames(s.org.dataBags)...) {
+			result[index] = s.url("search", index)
+		}
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	docs, ok := s.searchDocuments(r.Segments[1])
+	if !ok || len(r.Segments) > 2 {
+		writeError(w, http.StatusNotFound, "I don't know how to search for %s data objects.", r.Segments[1])
+		return
+	}
+
+	params := r.URL.Query()
+	q := params.Get("q")
+	if q == "" {
+		q = "*:*"
+	}
+	query, err := parseQuery(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid search query: '%s': %s", q, err)
+		return
+	}
+
+	var matches []searchDocument
+	for _, doc := range docs {
+		if query.matches(doc.Fields) {
+			matches = append(matches, doc)
+		}
+	}
+
+	start, _ := strconv.Atoi(params.Get("start"))
+	rows, err := strconv.Atoi(params.Get("rows"))
+	if err != nil || rows <= 0 {
+		rows = 1000
+	}
+	page := matches
+	if start >= len(page) {
+		page = nil
+	} else {
+		page = page[start:]
+	}
+	if len(page) > rows {
+		page = page[:rows]
+	}
+
+	result := []interface{}{}
+	switch r.Method {
+	case http.MethodGet:
+		for _, doc := range page {
+			result = append(result, doc.Object)
+		}
+	case http.MethodPost:
+		var paths map[string][]string
+		if err := json.Unmarshal(r.Body, &paths); err != nil {
+			writeError(w, http.StatusBadRequest, "Request body is not a map of names to attribute paths")
+			return
+		}
+		for _, doc := range page {
+			data := make(map[string]interface{}, len(paths))
+			for name, path := range paths {
+				data[name] = lookupPath(doc.Attributes, path)
+			}
+			result = append(result, map[string]interface{}{"url": doc.URL, "data": data})
+		}
+	default:
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total": len(matches),
+		"start": start,
+		"rows":  result,
+	})
+}
+
+// searchDocuments returns the documents of an index sorted by name, and
+// false if there is no such index.
+func (s *Server) searchDocuments(index string) ([]searchDocument, bool) {
+	var docs []searchDocument
+	switch index {
+	case "node":
+		for _, name := range sortedNames(s.org.nodes) {
+			docs = append(docs, s.nodeDocument(name, s.org.nodes[name]))
+		}
+	case "role":
+		for _, name := range sortedNames(s.org.roles) {
+			docs = append(docs, s.objectDocument("roles", name, s.org.roles[name]))
+		}
+	case "environment":
+		for _, name := range sortedNames(s.org.environments) {
+			docs = append(docs, s.objectDocument("environments", name, s.org.environments[name]))
+		}
+	case "client":
+		for _, name := range sortedNames(s.org.clients) {
+			docs = append(docs, s.objectDocument("clients", name, s.clientJSON(s.org.clients[name])))
+		}
+	default:
+		bag, ok := s.org.dataBags[index]
+		if !ok {
+			return nil, false
+		}
+		for _, id := range sortedNames(bag) {
+			item := bag[id]
+			doc := s.objectDocument("data/"+index, id, item)
+			doc.Object = map[string]interface{}{
+				"name":       "data_bag_item_" + index + "_" + id,
+				"json_class": "Chef::DataBagItem",
+				"chef_type":  "data_bag_item",
+				"data_bag":   index,
+				"raw_data":   item,
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, true
+}
+
+func (s *Server) objectDocument(collection, name string, obj map[string]interface{}) searchDocument {
+	fields := map[string][]string{}
+	indexFields(fields, "", obj)
+	return searchDocument{
+		Name:       name,
+		URL:        s.url(collection, name),
+		Object:     obj,
+		Attributes: obj,
+		Fields:     fields,
+	}
+}
+
+// nodeDocument indexes a node by its merged attributes, as well as its name,
+// environment and run list. Run list entries are also indexed as recipe and
+// role, as the Chef server does.
+func (s *Server) nodeDocument(name string, node map[string]interface{}) searchDocument {
+	var merged interface{} = map[string]interface{}{}
+	for _, precedence := range []string{"default", "normal", "override", "automatic"} {
+		merged = hashOnlyMerge(merged, node[precedence])
+	}
+	attributes, _ := merged.(map[string]interface{})
+	attributes = copyObject(attributes)
+	for _, key := range []string{"name", "chef_environment", "run_list", "policy_name", "policy_group"} {
+		if v, ok := node[key]; ok {
+			attributes[key] = v
+		}
+	}
+
+	fields := map[string][]string{}
+	indexFields(fields, "", attributes)
+	runList, _ := node["run_list"].([]interface{})
+	for _, entry := range runList {
+		entry, _ := entry.(string)
+		if m := runListEntryRegexp.FindStringSubmatch(entry); m != nil {
+			fields[m[1]] = append(fields[m[1]], m[2])
+		}
+	}
+
+	return searchDocument{
+		Name:       name,
+		URL:        s.url("nodes", name),
+		Object:     node,
+		Attributes: attributes,
+		Fields:     fields,
+	}
+}
+
+var runListEntryRegexp = regexp.MustCompile(`^(recipe|role)\[([^@\]]+)`)
+
+// indexFields flattens an object into search fields. Nested keys are joined
+// with underscores, and every value is also indexed under its own key.
+func indexFields(fields map[string][]string, prefix string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			key := k
+			if prefix != "" {
+				key = prefix + "_" + k
+				indexFields(fields, k, e)
+			}
+			indexFields(fields, key, e)
+		}
+	case []interface{}:
+		for _, e := range t {
+			indexFields(fields, prefix, e)
+		}
+	case nil:
+	default:
+		if prefix != "" {
+			fields[prefix] = append(fields[prefix], fmt.Sprint(t))
+		}
+	}
+}
+
+func lookupPath(v interface{}, path []string) interface{} {
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+// hashOnlyMerge merges b onto a, replacing everything but objects, the way
+// the precedence levels of node attributes are combined.
+func hashOnlyMerge(a, b interface{}) interface{} {
+	aMap, aOk := a.(map[string]interface{})
+	bMap, bOk := b.(map[string]interface{})
+	if !aOk || !bOk {
+		if b == nil {
+			return a
+		}
+		return b
+	}
+
+	result := make(map[string]interface{}, len(aMap)+len(bMap))
+	for k, v := range aMap {
+		result[k] = v
+	}
+	for k, v := range bMap {
+		result[k] = hashOnlyMerge(result[k], v)
+	}
+	return result
+}
+
+// searchQuery is a parsed query in disjunctive normal form: it matches if
+// every term of any of the clauses matches.
+type searchQuery [][]searchTerm
+
+type searchTerm struct {
+	Field  string
+	Value  *regexp.Regexp
+	Negate bool
+}
+
+// parseQuery parses the subset of the Solr query syntax that this server
+// supports: field:value terms with * and ? wildcards, optionally quoted or
+// negated with NOT or -, combined with AND and OR. AND binds tighter than
+// OR, and terms that are only separated by space are combined with OR, as
+// in Solr. Grouping and ranges are not supported.
+func parseQuery(q string) (searchQuery, error) {
+	tokens, err := tokenizeQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	query := searchQuery{nil}
+	negate := false
+	expectTerm := true
+	for _, token := range tokens {
+		switch token {
+		case "AND", "&&":
+			if expectTerm {
+				return nil, fmt.Errorf("unexpected %s", token)
+			}
+			expectTerm = true
+			continue
+		case "OR", "||":
+			if expectTerm {
+				return nil, fmt.Errorf("unexpected %s", token)
+			}
+			query = append(query, nil)
+			expectTerm = true
+			continue
+		case "NOT", "!":
+			negate = !negate
+			continue
+		}
+
+		if !expectTerm {
+			query = append(query, nil)
+		}
+		if strings.HasPrefix(token, "-") || strings.HasPrefix(token, "!") {
+			negate = !negate
+			token = token[1:]
+		}
+		term, err := parseTerm(token)
+		if err != nil {
+			return nil, err
+		}
+		term.Negate = negate
+		query[len(query)-1] = append(query[len(query)-1], term)
+		negate = false
+		expectTerm = false
+	}
+	if expectTerm {
+		return nil, fmt.Errorf("query ends with an operator")
+	}
+	return query, nil
+}
+
+func tokenizeQuery(q string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes, escaped := false, false
+	for _, c := range q {
+		switch {
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(c)
+		case !inQuotes && (c == ' ' || c == '\t' || c == '\n'):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		case !inQuotes && (c == '(' || c == ')' || c == '[' || c == ']' || c == '{' || c == '}'):
+			return nil, fmt.Errorf("grouping and ranges are not supported")
+		default:
+			current.WriteRune(c)
+		}
+	}
+	if inQuotes || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// parseTerm parses field:value. Backslash escapes the next character, which
+// is how colons in recipe names are written.
+func parseTerm(token string) (searchTerm, error) {
+	var field strings.Builder
+	var pattern strings.Builder
+	inField := true
+	quoted := false
+	escaped := false
+	for _, c := range token {
+		switch {
+		case escaped:
+			if inField {
+				field.WriteRune(c)
+			} else {
+				pattern.WriteString(regexp.QuoteMeta(string(c)))
+			}
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case inField && c == ':':
+			inField = false
+		case inField:
+			field.WriteRune(c)
+		case c == '"':
+			quoted = !quoted
+		case c == '*' && !quoted:
+			pattern.WriteString(".*")
+		case c == '?' && !quoted:
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if inField || field.Len() == 0 {
+		return searchTerm{}, fmt.Errorf("%q is not of the form field:value", token)
+	}
+
+	value, err := regexp.Compile("^" + pattern.String() + "$")
+	if err != nil {
+		return searchTerm{}, err
+	}
+	return searchTerm{Field: field.String(), Value: value}, nil
+}
+
+func (q searchQuery) matches(fields map[string][]string) bool {
+	for _, clause := range q {
+		matched := true
+		for _, term := range clause {
+			if term.matches(fields) == term.Negate {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (t searchTerm) matches(fields map[string][]string) bool {
+	if t.Field == "*" {
+		if t.Value.String() == "^.*$" {
+			return true
+		}
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if (searchTerm{Field: name, Value: t.Value}).matches(fields) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, v := range fields[t.Field] {
+		if t.Value.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,333 @@
+// Package chefzero is an in-memory fake of the Chef Infra Server API for
+// tests, in the spirit of chef-zero. It serves a single organization with
+// nodes, roles, environments, clients and their keys, data bags, cookbooks,
+// a subset of search, ACLs and groups, and users with their keys.
+//
+// Every request must be signed with the v1.0 or v1.3 protocol by a client
+// or user known to the server. ACLs are stored and returned but not
+// enforced.
+package chefzero
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Server is a running fake Chef server.
+type Server struct {
+	*httptest.Server
+
+	// Organization is the name of the organization served.
+	Organization string
+
+	// ClientName and ClientKey are the name and PEM encoded private key of
+	// an admin client that is created with the server.
+	ClientName string
+	ClientKey  string
+
+	mu    sync.Mutex
+	users map[string]*actor
+	org   *organization
+
+	// now is replaced in tests that check timestamp validation.
+	now func() time.Time
+
+	// requests numbers the X-Request-Id of responses, as the real server
+	// identifies each request in its logs.
+	requests atomic.Uint64
+}
+
+type organization struct {
+	clients      map[string]*actor
+	nodes        map[string]map[string]interface{}
+	roles        map[string]map[string]interface{}
+	environments map[string]map[string]interface{}
+	dataBags     map[string]map[string]map[string]interface{}
+	cookbooks    map[string]map[string]map[string]interface{}
+	sandboxes    map[string]*sandbox
+	checksums    map[string][]byte
+	groups       map[string]*group
+	acls         map[string]acl
+}
+
+// NewServer starts a server for the organization with an admin client named
+// after it.
+func NewServer(org string) (*Server, error) {
+	s := &Server{
+		Organization: org,
+		ClientName:   org + "-admin",
+		users:        map[string]*actor{},
+		org: &organization{
+			clients:      map[string]*actor{},
+			nodes:        map[string]map[string]interface{}{},
+			roles:        map[string]map[string]interface{}{},
+			environments: map[string]map[string]interface{}{},
+			dataBags:     map[string]map[string]map[string]interface{}{},
+			cookbooks:    map[string]map[string]map[string]interface{}{},
+			sandboxes:    map[string]*sandbox{},
+			checksums:    map[string][]byte{},
+			groups:       map[string]*group{},
+			acls:         map[string]acl{},
+		},
+		now: time.Now,
+	}
+
+	admin, privateKey, err := newActor(s.ClientName)
+	if err != nil {
+		return nil, err
+	}
+	s.ClientKey = privateKey
+	s.org.clients[admin.Name] = admin
+
+	s.org.environments["_default"] = environmentDefaults(map[string]interface{}{
+		"name":        "_default",
+		"description": "The default Chef environment",
+	})
+	for _, name := range []string{"admins", "billing-admins", "clients", "users"} {
+		s.org.groups[name] = &group{Name: name}
+	}
+	s.org.groups["admins"].Clients = []string{admin.Name}
+	s.org.groups["clients"].Clients = []string{admin.Name}
+	s.org.acls["clients/"+admin.Name] = defaultACL(admin.Name)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s, nil
+}
+
+// OrganizationURL is the URL to configure as the Chef server URL, with the
+// trailing slash that the provider requires.
+func (s *Server) OrganizationURL() string {
+	return s.URL + "/organizations/" + s.Organization + "/"
+}
+
+// AddUser creates a user with a default key and returns its PEM encoded
+// private key.
+func (s *Server) AddUser(name string) (string, error) {
+	user, privateKey, err := newActor(name)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[name]; ok {
+		return "", fmt.Errorf("user %q already exists", name)
+	}
+	s.users[name] = user
+	s.org.groups["users"].Users = append(s.org.groups["users"].Users, name)
+	return privateKey, nil
+}
+
+// request is an authenticated request, split into path segments below the
+// organization or server root.
+type request struct {
+	*http.Request
+	Requestor string
+	Segments  []string
+	Body      []byte
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Request-Id", fmt.Sprintf("chefzero-%d", s.requests.Add(1)))
+
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	var segments []string
+	global := true
+	for _, part := range strings.Split(strings.Trim(r.URL.Path, "/"), "/") {
+		if part != "" {
+			segments = append(segments, part)
+		}
+	}
+	if len(segments) >= 2 && segments[0] == "organizations" {
+		if segments[1] != s.Organization {
+			writeError(w, http.StatusNotFound, "organization '%s' does not exist.", segments[1])
+			return
+		}
+		segments = segments[2:]
+		global = false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, fileStorePrefix) {
+		s.serveFileStore(w, r, body)
+		return
+	}
+
+	requestor, err := s.authenticate(r, body, global)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "%s", err)
+		return
+	}
+
+	req := &request{Request: r, Requestor: requestor, Segments: segments, Body: body}
+	if global {
+		s.serveGlobal(w, req)
+		return
+	}
+	s.serveOrganization(w, req)
+}
+
+func (s *Server) serveGlobal(w http.ResponseWriter, r *request) {
+	if len(r.Segments) >= 2 && r.Segments[0] == "users" {
+		user, ok := s.users[r.Segments[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Cannot load user %s", r.Segments[1])
+			return
+		}
+		switch {
+		case len(r.Segments) == 2 && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"username":     user.Name,
+				"display_name": user.Name,
+			})
+		case len(r.Segments) >= 3 && r.Segments[2] == "keys":
+			s.serveKeys(w, r, user, s.URL+"/users/"+user.Name, r.Segments[3:])
+		default:
+			writeMethodNotAllowed(w)
+		}
+		return
+	}
+	writeError(w, http.StatusNotFound, "No route for %s", r.URL.Path)
+}
+
+func (s *Server) serveOrganization(w http.ResponseWriter, r *request) {
+	if len(r.Segments) == 0 {
+		writeError(w, http.StatusNotFound, "No route for %s", r.URL.Path)
+		return
+	}
+
+	// ACLs live below every object, as /<type>/<name>/_acl.
+	if len(r.Segments) >= 3 && r.Segments[2] == "_acl" {
+		s.serveACL(w, r)
+		return
+	}
+
+	switch r.Segments[0] {
+	case "nodes":
+		s.serveNodes(w, r)
+	case "roles":
+		s.serveRoles(w, r)
+	case "environments":
+		s.serveEnvironments(w, r)
+	case "clients":
+		s.serveClients(w, r)
+	case "data":
+		s.serveDataBags(w, r)
+	case "search":
+		s.serveSearch(w, r)
+	case "groups":
+		s.serveGroups(w, r)
+	case "cookbooks", "cookbook_artifacts", "universe":
+		s.serveCookbooks(w, r)
+	case "sandboxes":
+		s.serveSandboxes(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "No route for %s", r.URL.Path)
+	}
+}
+
+// url returns the URL of an object in the organization.
+func (s *Server) url(parts ...string) string {
+	return s.OrganizationURL() + strings.Join(parts, "/")
+}
+
+// listing renders the name to URL map that the Chef server returns when
+// listing a collection.
+func (s *Server) listing(collection string, names []string) map[string]string {
+	result := make(map[string]string, len(names))
+	for _, name := range names {
+		result[name] = s.url(collection, name)
+	}
+	return result
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	defer r.Body.Close()
+	return io.ReadAll(r.Body)
+}
+
+// decodeObject decodes a request body that must be a JSON object. Numbers
+// are kept as written.
+func decodeObject(body []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v map[string]interface{}
+	if err := dec.Decode(&v); err != nil || v == nil {
+		return nil, fmt.Errorf("Request body is not a JSON object")
+	}
+	return v, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the format of the Chef server, which go-chef
+// exposes as ErrorResponse.ErrorMsg.
+func writeError(w http.ResponseWriter, status int, format string, a ...interface{}) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": []string{fmt.Sprintf(format, a...)},
+	})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// copyObject returns a deep copy of a decoded JSON object, so that stored
+// objects are never shared with a response or request.
+func copyObject(v map[string]interface{}) map[string]interface{} {
+	b, _ := json.Marshal(v)
+	result, _ := decodeObject(b)
+	return result
+}
+
+// generateKey returns a new key pair, the private key encoded the way Chef
+// writes client.pem and the public key as a PKIX PEM block.
+func generateKey() (privateKey, publicKey string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privateKey = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	publicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	return privateKey, publicKey, nil
+}
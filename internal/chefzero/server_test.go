@@ -0,0 +1,588 @@
+package chefzero
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	chefc "github.com/go-chef/chef"
+)
+
+func testServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := NewServer("test")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testClient(t *testing.T, s *Server, name, key, version string) *chefc.Client {
+	t.Helper()
+	return testClientURL(t, s.OrganizationURL(), name, key, version)
+}
+
+func testClientURL(t *testing.T, baseURL, name, key, version string) *chefc.Client {
+	t.Helper()
+	client, err := chefc.NewClient(&chefc.Config{
+		Name:                  name,
+		Key:                   key,
+		BaseURL:               baseURL,
+		AuthenticationVersion: version,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return client
+}
+
+func testStatus(err error) int {
+	var errResp *chefc.ErrorResponse
+	if errors.As(err, &errResp) {
+		return errResp.StatusCode()
+	}
+	return 0
+}
+
+func TestServer_authentication(t *testing.T) {
+	s := testServer(t)
+	otherKey, _, err := generateKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, version := range []string{"1.0", "1.3"} {
+		t.Run(version, func(t *testing.T) {
+			if _, err := testClient(t, s, s.ClientName, s.ClientKey, version).Nodes.List(); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			_, err := testClient(t, s, s.ClientName, otherKey, version).Nodes.List()
+			if status := testStatus(err); status != http.StatusUnauthorized {
+				t.Fatalf("expected 401 with the wrong key, got %d (%v)", status, err)
+			}
+
+			_, err = testClient(t, s, "nobody", s.ClientKey, version).Nodes.List()
+			if status := testStatus(err); status != http.StatusUnauthorized {
+				t.Fatalf("expected 401 for an unknown client, got %d (%v)", status, err)
+			}
+		})
+	}
+
+	s.now = func() time.Time { return time.Now().Add(time.Hour) }
+	defer func() { s.now = time.Now }()
+	_, err = testClient(t, s, s.ClientName, s.ClientKey, "1.3").Nodes.List()
+	if status := testStatus(err); status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with a stale timestamp, got %d (%v)", status, err)
+	}
+}
+
+func TestServer_unsignedRequest(t *testing.T) {
+	s := testServer(t)
+	resp, err := http.Get(s.OrganizationURL() + "nodes")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_clientKeys(t *testing.T) {
+	s := testServer(t)
+	admin := testClient(t, s, s.ClientName, s.ClientKey, "1.3")
+
+	result, err := admin.Clients.Create(chefc.ApiNewClient{Name: "web", CreateKey: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	web := testClient(t, s, "web", result.ChefKey.PrivateKey, "1.0")
+	if _, err := web.Roles.List(); err != nil {
+		t.Fatalf("the new client cannot authenticate: %s", err)
+	}
+
+	if _, err := admin.Clients.Create(chefc.ApiNewClient{Name: "web"}); testStatus(err) != http.StatusConflict {
+		t.Fatalf("expected 409 creating a duplicate client, got %v", err)
+	}
+
+	privateKey, publicKey, err := generateKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expired := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	if _, err := admin.Clients.AddKey("web", chefc.AccessKey{Name: "old", PublicKey: publicKey, ExpirationDate: expired}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	keys, err := admin.Clients.ListKeys("web")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(keys) != 2 || keys[1].Name != "old" || !keys[1].Expired {
+		t.Fatalf("unexpected keys: %#v", keys)
+	}
+	if _, err := testClient(t, s, "web", privateKey, "1.3").Roles.List(); testStatus(err) != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with an expired key, got %v", err)
+	}
+
+	if _, err := admin.Clients.UpdateKey("web", "old", chefc.AccessKey{ExpirationDate: "infinity"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := testClient(t, s, "web", privateKey, "1.3").Roles.List(); err != nil {
+		t.Fatalf("the renewed key cannot authenticate: %s", err)
+	}
+
+	if _, err := admin.Clients.DeleteKey("web", "old"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := admin.Clients.Delete("web"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := admin.Clients.Get("web"); testStatus(err) != http.StatusNotFound {
+		t.Fatalf("expected 404 after deleting the client, got %v", err)
+	}
+}
+
+func TestServer_userKeys(t *testing.T) {
+	s := testServer(t)
+	userKey, err := s.AddUser("alice")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	alice := testClientURL(t, s.URL+"/", "alice", userKey, "1.3")
+
+	_, publicKey, err := generateKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := alice.Users.AddKey("alice", chefc.AccessKey{Name: "laptop", PublicKey: publicKey, ExpirationDate: "infinity"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	key, err := alice.Users.GetKey("alice", "laptop")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if key.PublicKey != publicKey {
+		t.Fatalf("unexpected key: %#v", key)
+	}
+}
+
+func TestServer_objects(t *testing.T) {
+	s := testServer(t)
+	client := testClient(t, s, s.ClientName, s.ClientKey, "1.3")
+
+	if _, err := client.Environments.Create(&chefc.Environment{Name: "prod"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.Environments.Put(&chefc.Environment{Name: "_default"}); testStatus(err) != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 updating _default, got %v", err)
+	}
+
+	if _, err := client.Roles.Create(&chefc.Role{
+		Name:        "web",
+		RunList:     chefc.RunList{"nginx"},
+		EnvRunList:  chefc.EnvRunList{"prod": chefc.RunList{"role[base]"}},
+		Description: "Web servers",
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	role, err := client.Roles.Get("web")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(role.RunList) != 1 || role.RunList[0] != "recipe[nginx]" {
+		t.Fatalf("run list was not normalized: %#v", role.RunList)
+	}
+	envRunList, err := client.Roles.GetEnvironmentRunlist("web", "prod")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if runList := envRunList["run_list"]; len(runList) != 1 || runList[0] != "role[base]" {
+		t.Fatalf("unexpected environment run list: %#v", envRunList)
+	}
+
+	node := chefc.Node{Name: "web1", Environment: "prod", RunList: []string{"role[web]"}}
+	if _, err := client.Nodes.Post(node); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.Nodes.Post(node); testStatus(err) != http.StatusConflict {
+		t.Fatalf("expected 409 creating a duplicate node, got %v", err)
+	}
+	node.NormalAttributes = map[string]interface{}{"port": 8080}
+	if _, err := client.Nodes.Put(node); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	read, err := client.Nodes.Get("web1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if read.Environment != "prod" || read.NormalAttributes["port"] != 8080.0 {
+		t.Fatalf("unexpected node: %#v", read)
+	}
+
+	if err := client.Nodes.Delete("web1"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := client.Nodes.Head("web1"); testStatus(err) != http.StatusNotFound {
+		t.Fatalf("expected 404 after deleting the node, got %v", err)
+	}
+}
+
+func TestServer_dataBags(t *testing.T) {
+	s := testServer(t)
+	client := testClient(t, s, s.ClientName, s.ClientKey, "1.0")
+
+	if _, err := client.DataBags.Create(&chefc.DataBag{Name: "secrets"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := client.DataBags.CreateItem("secrets", map[string]interface{}{"id": "db", "password": "hunter2"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := client.DataBags.UpdateItem("secrets", "db", map[string]interface{}{"id": "other"}); testStatus(err) != http.StatusBadRequest {
+		t.Fatalf("expected 400 renaming an item, got %v", err)
+	}
+	item, err := client.DataBags.GetItem("secrets", "db")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if item.(map[string]interface{})["password"] != "hunter2" {
+		t.Fatalf("unexpected item: %#v", item)
+	}
+
+	items, err := client.DataBags.ListItems("secrets")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(*items) != 1 {
+		t.Fatalf("unexpected items: %#v", items)
+	}
+
+	if _, err := client.DataBags.Delete("secrets"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.DataBags.ListItems("secrets"); testStatus(err) != http.StatusNotFound {
+		t.Fatalf("expected 404 after deleting the data bag, got %v", err)
+	}
+}
+
+func testUploadCookbook(t *testing.T, client *chefc.Client, cookbook chefc.Cookbook, force bool) error {
+	t.Helper()
+	body, err := chefc.JSONReader(cookbook)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := "cookbooks/" + cookbook.CookbookName + "/" + cookbook.Version
+	if force {
+		path += "?force=true"
+	}
+	req, err := client.NewRequest(http.MethodPut, path, body)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	res, err := client.Do(req, nil)
+	if res != nil {
+		res.Body.Close()
+	}
+	return err
+}
+
+func testRequest(t *testing.T, method, url string, body []byte) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return req
+}
+
+func TestServer_cookbooks(t *testing.T) {
+	s := testServer(t)
+	client := testClient(t, s, s.ClientName, s.ClientKey, "1.3")
+
+	for _, cookbook := range []chefc.Cookbook{
+		{CookbookName: "web", Name: "web-1.0.0", Version: "1.0.0", Frozen: true,
+			Recipes: []chefc.CookbookItem{{Name: "default.rb", Path: "recipes/default.rb"}}},
+		{CookbookName: "web", Name: "web-1.2.0", Version: "1.2.0"},
+		{CookbookName: "base", Name: "base-2.1.0", Version: "2.1.0"},
+	} {
+		if err := testUploadCookbook(t, client, cookbook, false); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	// The all_files segment of current clients is not part of chefc.Cookbook.
+	req, err := client.NewRequest(http.MethodPut, "cookbooks/web/1.10.0", strings.NewReader(`{
+		"cookbook_name": "web", "name": "web-1.10.0", "version": "1.10.0",
+		"metadata": {"dependencies": {"base": "~> 2.0"}},
+		"all_files": [{"name": "recipes/default.rb"}, {"name": "recipes/ssl.rb"}, {"name": "recipes/files/x.rb"}, {"name": "attributes/default.rb"}]
+	}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.Do(req, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := testUploadCookbook(t, client, chefc.Cookbook{CookbookName: "web", Name: "web-1.0.0", Version: "1.0.0"}, false); testStatus(err) != http.StatusConflict {
+		t.Fatalf("expected 409 uploading a frozen version, got %v", err)
+	}
+	if err := testUploadCookbook(t, client, chefc.Cookbook{CookbookName: "web", Name: "web-1.0.0", Version: "1.0.0", Frozen: true,
+		Recipes: []chefc.CookbookItem{{Name: "default.rb"}}}, true); err != nil {
+		t.Fatalf("expected force to upload a frozen version, got %v", err)
+	}
+
+	// Files must be uploaded through a sandbox before a version refers to
+	// them.
+	content := []byte("package 'other'\n")
+	digest := md5.Sum(content)
+	sum := hex.EncodeToString(digest[:])
+	other := chefc.Cookbook{CookbookName: "other", Version: "1.0.0", Recipes: []chefc.CookbookItem{{Name: "default.rb", Checksum: sum}}}
+	if err := testUploadCookbook(t, client, other, false); testStatus(err) != http.StatusBadRequest {
+		t.Fatalf("expected 400 uploading a version whose files are missing, got %v", err)
+	}
+	box, err := client.Sandboxes.Post([]string{sum})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !box.Checksums[sum].Upload {
+		t.Fatalf("expected the file to need uploading: %#v", box)
+	}
+	if _, err := client.Sandboxes.Put(box.ID); testStatus(err) != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 committing a sandbox before uploading, got %v", err)
+	}
+	res, err := http.DefaultClient.Do(testRequest(t, http.MethodPut, box.Checksums[sum].Url, content))
+	if err != nil || res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected the file to be stored, got %v %v", res, err)
+	}
+	if _, err := client.Sandboxes.Put(box.ID); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := testUploadCookbook(t, client, other, false); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	stored, err := client.Cookbooks.GetVersion("other", "1.0.0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	res, err = http.DefaultClient.Do(testRequest(t, http.MethodGet, stored.Recipes[0].Url, nil))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer res.Body.Close()
+	if downloaded, _ := io.ReadAll(res.Body); !bytes.Equal(downloaded, content) {
+		t.Fatalf("expected to download the recipe, got %q", downloaded)
+	}
+	if err := client.Cookbooks.Delete("other", "1.0.0"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	available, err := client.Cookbooks.GetAvailableVersions("web", "all")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var versions []string
+	for _, v := range available["web"].Versions {
+		versions = append(versions, v.Version)
+	}
+	if expected := []string{"1.10.0", "1.2.0", "1.0.0"}; !reflect.DeepEqual(versions, expected) {
+		t.Fatalf("expected versions %v newest first, got %v", expected, versions)
+	}
+
+	latest, err := client.Cookbooks.ListAvailableVersions("")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(latest) != 2 || len(latest["web"].Versions) != 1 || latest["web"].Versions[0].Version != "1.10.0" {
+		t.Fatalf("expected only the latest version of each cookbook by default, got %v", latest)
+	}
+
+	cookbook, err := client.Cookbooks.GetVersion("web", "1.0.0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !cookbook.Frozen {
+		t.Fatalf("expected version 1.0.0 to be frozen: %#v", cookbook)
+	}
+	if _, err := client.Cookbooks.GetVersion("web", "3.0.0"); testStatus(err) != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing version, got %v", err)
+	}
+
+	recipes, err := client.Cookbooks.ListAllRecipes()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if expected := (chefc.CookbookRecipesResult{"web", "web::ssl"}); !reflect.DeepEqual(recipes, expected) {
+		t.Fatalf("expected recipes %v, got %v", expected, recipes)
+	}
+
+	universe, err := client.Universe.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if deps := universe.Books["web"].Versions["1.10.0"].Dependencies; !reflect.DeepEqual(deps, map[string]string{"base": "~> 2.0"}) {
+		t.Fatalf("unexpected universe dependencies: %#v", deps)
+	}
+
+	if _, err := client.Environments.Create(&chefc.Environment{Name: "pinned", CookbookVersions: map[string]string{"web": "~> 1.0.0"}}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	envCookbooks, err := client.Environments.ListCookbooks("pinned", "all")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := envCookbooks["web"].Versions; len(v) != 1 || v[0].Version != "1.0.0" {
+		t.Fatalf("expected the environment to only allow web 1.0.0, got %v", v)
+	}
+	envRecipes, err := client.Environments.ListRecipes("pinned")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if expected := (chefc.EnvironmentRecipesResult{"web"}); !reflect.DeepEqual(envRecipes, expected) {
+		t.Fatalf("expected environment recipes %v, got %v", expected, envRecipes)
+	}
+}
+
+func TestServer_search(t *testing.T) {
+	s := testServer(t)
+	client := testClient(t, s, s.ClientName, s.ClientKey, "1.3")
+
+	nodes := []chefc.Node{
+		{Name: "web1", Environment: "prod", RunList: []string{"role[web]", "recipe[nginx::ssl]"},
+			NormalAttributes: map[string]interface{}{"app": map[string]interface{}{"port": 80}}},
+		{Name: "web2", Environment: "staging", RunList: []string{"role[web]"},
+			NormalAttributes: map[string]interface{}{"app": map[string]interface{}{"port": 8080}}},
+		{Name: "db1", Environment: "prod", RunList: []string{"role[db]"}},
+	}
+	for _, node := range nodes {
+		if _, err := client.Nodes.Post(node); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	cases := []struct {
+		Query    string
+		Expected []string
+	}{
+		{"*:*", []string{"db1", "web1", "web2"}},
+		{"name:web*", []string{"web1", "web2"}},
+		{"role:web+AND+chef_environment:prod", []string{"web1"}},
+		{"chef_environment:staging+OR+name:db1", []string{"db1", "web2"}},
+		{"role:web+AND+NOT+chef_environment:prod", []string{"web2"}},
+		{"app_port:8080", []string{"web2"}},
+		{"port:80", []string{"web1"}},
+		{"recipe:nginx\\:\\:ssl", []string{"web1"}},
+		{"name:web?", []string{"web1", "web2"}},
+		{"name:nothing", nil},
+	}
+	for _, tc := range cases {
+		result, err := client.Search.Exec("node", tc.Query)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Query, err)
+		}
+		var names []string
+		for _, row := range result.Rows {
+			names = append(names, row.(map[string]interface{})["name"].(string))
+		}
+		sort.Strings(names)
+		if len(names) != len(tc.Expected) || result.Total != len(tc.Expected) {
+			t.Fatalf("%s: expected %v, got %v", tc.Query, tc.Expected, names)
+		}
+		for i := range names {
+			if names[i] != tc.Expected[i] {
+				t.Fatalf("%s: expected %v, got %v", tc.Query, tc.Expected, names)
+			}
+		}
+	}
+
+	partial, err := client.Search.PartialExec("node", "name:web1", map[string]interface{}{
+		"port": []string{"app", "port"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	row := partial.Rows[0].(map[string]interface{})
+	if data := row["data"].(map[string]interface{}); data["port"] != 80.0 {
+		t.Fatalf("unexpected partial search row: %#v", row)
+	}
+
+	if _, err := client.Search.Exec("nothing", "*:*"); testStatus(err) != http.StatusNotFound {
+		t.Fatalf("expected 404 searching a missing index, got %v", err)
+	}
+}
+
+func TestParseQuery_invalid(t *testing.T) {
+	for _, q := range []string{"name", "name:a AND", "OR name:a", "(name:a)", "name:[a TO b]", `name:"a`} {
+		if _, err := parseQuery(q); err == nil {
+			t.Errorf("%q: expected an error", q)
+		}
+	}
+}
+
+func TestServer_aclsAndGroups(t *testing.T) {
+	s := testServer(t)
+	if _, err := s.AddUser("alice"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client := testClient(t, s, s.ClientName, s.ClientKey, "1.3")
+
+	if _, err := client.Groups.Create(chefc.Group{GroupName: "ops"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	update := chefc.GroupUpdate{Name: "ops", GroupName: "ops"}
+	update.Actors.Users = []string{"alice"}
+	update.Actors.Clients = []string{s.ClientName}
+	if _, err := client.Groups.Update(update); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	group, err := client.Groups.Get("ops")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(group.Users) != 1 || len(group.Clients) != 1 || len(group.Actors) != 2 {
+		t.Fatalf("unexpected group: %#v", group)
+	}
+	update.Actors.Users = []string{"bob"}
+	if _, err := client.Groups.Update(update); testStatus(err) != http.StatusBadRequest {
+		t.Fatalf("expected 400 adding an unknown user, got %v", err)
+	}
+
+	if _, err := client.Nodes.Post(chefc.Node{Name: "web1"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	acl, err := client.ACLs.Get("nodes", "web1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actors := acl["update"].Actors; len(actors) != 1 || actors[0] != s.ClientName {
+		t.Fatalf("unexpected default ACL: %#v", acl)
+	}
+
+	if err := client.ACLs.Put("nodes", "web1", "update", chefc.NewACL("update", chefc.ACLitem{"alice"}, chefc.ACLitem{"ops"})); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	acl, err = client.ACLs.Get("nodes", "web1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if items := acl["update"]; len(items.Actors) != 1 || items.Actors[0] != "alice" || len(items.Groups) != 1 || items.Groups[0] != "ops" {
+		t.Fatalf("ACL was not updated: %#v", acl)
+	}
+
+	if _, err := client.ACLs.Get("nodes", "missing"); testStatus(err) != http.StatusNotFound {
+		t.Fatalf("expected 404 for the ACL of a missing node, got %v", err)
+	}
+	if err := client.Groups.Delete("admins"); testStatus(err) != http.StatusForbidden {
+		t.Fatalf("expected 403 deleting a system group, got %v", err)
+	}
+	if err := client.Groups.Delete("ops"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// adoptExistingSchema is the adopt_existing argument of every resource.
+func adoptExistingSchema(kind string) schema.BoolAttribute {
+	article := "a"
+	if strings.ContainsAny(kind[:1], "aeiou") {
+		article = "an"
+	}
+	return schema.BoolAttribute{
+		Optional: true,
+		Computed: true,
+		Default:  booldefault.StaticBool(false),
+		Description: fmt.Sprintf("If set, %s %s that already exists on the Chef server is taken over and updated to match "+
+			"the configuration instead of failing the creation. Only affects creation.", article, kind),
+	}
+}
+
+// adoption describes how a resource takes over an object that was created
+// outside of Terraform, for example with knife.
+type adoption struct {
+	// Kind is the kind of object, such as "node", and Name identifies it
+	// in messages. Name is empty while it is unknown.
+	Kind string
+	Name string
+
+	// Adopt is the adopt_existing argument, and Attribute is the argument
+	// that names the object, to which a conflict is attributed.
+	Adopt     types.Bool
+	Attribute path.Path
+
+	// Get reads the existing object and Update converges it with the
+	// configuration. Update is nil for objects without any content.
+	Get    func() (interface{}, error)
+	Update func() error
+}
+
+// plan warns when a resource that is about to be created takes over an
+// existing object, so that the plan shows it. Failures to read the object
+// are left to the apply.
+func (a adoption) plan(ctx context.Context, req resource.ModifyPlanRequest, diags *diag.Diagnostics) {
+	if !req.State.Raw.IsNull() || !a.Adopt.ValueBool() || a.Name == "" {
+		return
+	}
+
+	if _, err := a.Get(); err != nil {
+		if !isNotFound(err) {
+			tflog.Debug(ctx, "Unable to check for an existing Chef "+a.Kind, map[string]interface{}{"name": a.Name, "error": err.Error()})
+		}
+		return
+	}
+	diags.AddAttributeWarning(a.Attribute, fmt.Sprintf("Existing %s will be adopted", a.Kind),
+		fmt.Sprintf("%s %q already exists on the Chef server. Applying this plan takes it over and updates it to match the configuration.",
+			capitalizeKind(a.Kind), a.Name))
+}
+
+// create creates the object, or adopts it when it already exists and
+// adopt_existing is set: the existing object is read, logged at debug level
+// with sensitive values redacted, and converged with the configuration.
+func (a adoption) create(ctx context.Context, create func() error) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	err := create()
+	if chefErrorStatus(err) == http.StatusConflict {
+		if !a.Adopt.ValueBool() {
+			diags.AddAttributeError(a.Attribute, fmt.Sprintf("%s already exists", capitalizeKind(a.Kind)),
+				chefErrorDetail(err)+"\n\nImport it into the state, or set adopt_existing to take it over.")
+			return diags
+		}
+		err = a.adopt(ctx)
+	}
+	if err != nil {
+		diags.Append(chefDiagnostic("Error creating "+a.Kind, err, a.Attribute))
+	}
+	return diags
+}
+
+func (a adoption) adopt(ctx context.Context) error {
+	existing, err := a.Get()
+	if err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Adopting existing Chef "+a.Kind, map[string]interface{}{"name": a.Name})
+	if previous, err := jsonObjectValue(existing); err == nil {
+		// Data bag items hold secrets under any key.
+		previous = redactSensitive(previous, a.Kind == "data bag item")
+		if b, err := json.Marshal(previous); err == nil {
+			tflog.Debug(ctx, "Previous content of the adopted Chef "+a.Kind, map[string]interface{}{"name": a.Name, "previous": string(b)})
+		}
+	}
+
+	if a.Update == nil {
+		return nil
+	}
+	return a.Update()
+}
+
+func capitalizeKind(kind string) string {
+	return strings.ToUpper(kind[:1]) + kind[1:]
+}
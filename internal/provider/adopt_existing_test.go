@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+
+	chefc "github.com/go-chef/chef"
+
+	"github.com/bdwyertech/terraform-provider-chef/internal/chefzero"
+)
+
+func TestAdoption_create(t *testing.T) {
+	server, err := chefzero.NewServer("terraform")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer server.Close()
+
+	client, err := newChefClient(chefClientConfig{
+		ServerURL:  server.OrganizationURL(),
+		ClientName: server.ClientName,
+		Key:        server.ClientKey,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	existing := chefc.Node{
+		Name:             "web1",
+		Environment:      "_default",
+		RunList:          []string{"recipe[knife]"},
+		NormalAttributes: map[string]interface{}{"db_password": "hunter2"},
+	}
+	if _, err := client.Nodes.Post(existing); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	node := chefc.Node{Name: "web1", Environment: "_default", RunList: []string{"recipe[terraform]"}}
+	adoptionFor := func(adopt bool) adoption {
+		return adoption{
+			Kind:      "node",
+			Name:      node.Name,
+			Adopt:     types.BoolValue(adopt),
+			Attribute: path.Root("name"),
+			Get: func() (interface{}, error) {
+				return client.Nodes.Get(node.Name)
+			},
+			Update: func() error {
+				_, err := client.Nodes.Put(node)
+				return err
+			},
+		}
+	}
+	create := func() error {
+		_, err := client.Nodes.Post(node)
+		return err
+	}
+
+	diags := adoptionFor(false).create(context.Background(), create)
+	if !diags.HasError() {
+		t.Fatalf("expected an error without adopt_existing")
+	}
+	if summary, detail := diags[0].Summary(), diags[0].Detail(); summary != "Node already exists" || !strings.Contains(detail, "adopt_existing") {
+		t.Errorf("unexpected diagnostic: %s: %s", summary, detail)
+	}
+
+	var logs bytes.Buffer
+	if diags := adoptionFor(true).create(tflogtest.RootLogger(context.Background(), &logs), create); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	entries, err := tflogtest.MultilineJSONDecode(&logs)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var logged bool
+	for _, entry := range entries {
+		previous, _ := entry["previous"].(string)
+		if previous == "" {
+			continue
+		}
+		logged = true
+		if entry["@level"] != "debug" {
+			t.Errorf("the previous node was logged at %s level", entry["@level"])
+		}
+		if strings.Contains(previous, "hunter2") || !strings.Contains(previous, redactedJsonValue) {
+			t.Errorf("the previous node was logged without redacting its password: %s", previous)
+		}
+	}
+	if !logged {
+		t.Errorf("the previous node was not logged: %v", entries)
+	}
+	got, err := client.Nodes.Get(node.Name)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(got.RunList) != 1 || got.RunList[0] != "recipe[terraform]" {
+		t.Errorf("the node was not converged: %v", got.RunList)
+	}
+}
@@ -0,0 +1,282 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	chefc "github.com/go-chef/chef"
+)
+
+// auditLogDescription and auditLogLabelDescription describe the audit log
+// provider arguments.
+const (
+	auditLogDescription = "Path of a file to which JSON lines are appended for every request that changes the Chef server: " +
+		"a `request` line with the time, client, organization, method, path, object and the changes to be made, written before the request is sent, " +
+		"and a `response` line with the status or error. A request is not sent if its line cannot be written. " +
+		"Terraform does not tell providers the address of the resource being applied, so entries identify the Chef object instead, along with `audit_log_label`. " +
+		"Values of keys that look like passwords, secrets, tokens or private keys, and all values in data bag items, are redacted."
+	auditLogLabelDescription = "Label recorded in every line of the audit log, such as the workspace or the pipeline run applying the configuration."
+)
+
+// auditLogMu serializes writes to audit logs, which the SDKv2 and framework
+// providers may share.
+var auditLogMu sync.Mutex
+
+// redactedJsonValue replaces the values that are not written to audit logs.
+const redactedJsonValue = "(redacted)"
+
+// sensitiveJsonKey matches the keys whose values are redacted.
+var sensitiveJsonKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|private_key|key_material|credential)`)
+
+// auditEntry is a line of an audit log. A request line records the changes
+// a request is about to make, and a response line its outcome.
+type auditEntry struct {
+	Time         string        `json:"time"`
+	Event        string        `json:"event"`
+	Label        string        `json:"label,omitempty"`
+	Client       string        `json:"client"`
+	Organization string        `json:"organization,omitempty"`
+	Method       string        `json:"method"`
+	Path         string        `json:"path"`
+	Object       string        `json:"object"`
+	Changes      []auditChange `json:"changes,omitempty"`
+	Status       int           `json:"status,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+const (
+	auditRequestEvent  = "request"
+	auditResponseEvent = "response"
+)
+
+// auditChange is a change made by a request, in the form of a JSON Patch
+// operation with the value replaced or removed as old.
+type auditChange struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// openAuditLog checks that an audit log can be written.
+func openAuditLog(name string) error {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// auditTransport records the requests that change the Chef server. Objects
+// are fetched before they are updated or deleted so that the changes can be
+// logged, with requests signed by signer, which is set once the client using
+// the transport is created.
+type auditTransport struct {
+	base       http.RoundTripper
+	path       string
+	label      string
+	clientName string
+	signer     *chefc.Client
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !changesChefServer(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	var before interface{} = absentJsonValue
+	if req.Method != http.MethodPost {
+		before = t.current(req)
+	}
+	var after interface{} = absentJsonValue
+	if req.Method != http.MethodDelete {
+		after = jsonBodyValue(body)
+	}
+
+	container, object := chefErrorTarget(req.URL.Path)
+	entry := auditEntry{
+		Time:         time.Now().UTC().Format(time.RFC3339Nano),
+		Event:        auditRequestEvent,
+		Label:        t.label,
+		Client:       t.clientName,
+		Organization: chefOrganization(req.URL.Path),
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		Object:       strings.TrimSuffix(container+"/"+object, "/"),
+		Changes:      auditChanges(jsonChanges("", before, after), container == "data" && object != ""),
+	}
+	// The change is only made once it is logged.
+	if err := t.write(entry); err != nil {
+		return nil, fmt.Errorf("writing audit log %s: %w", t.path, err)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	entry.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	entry.Event = auditResponseEvent
+	entry.Changes = nil
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+	}
+	// The change has been made by now, and its request line records it
+	// even if its outcome cannot be logged.
+	t.write(entry)
+	return resp, err
+}
+
+// current fetches the object a request is about to change, which is absent
+// if it cannot be read.
+func (t *auditTransport) current(req *http.Request) interface{} {
+	if t.signer == nil {
+		return absentJsonValue
+	}
+	getReq, err := t.signer.NewRequest(http.MethodGet, req.URL.String(), nil)
+	if err != nil {
+		return absentJsonValue
+	}
+	resp, err := t.base.RoundTrip(getReq.WithContext(req.Context()))
+	if err != nil {
+		return absentJsonValue
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return absentJsonValue
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return absentJsonValue
+	}
+	return jsonBodyValue(body)
+}
+
+func (t *auditTransport) write(entry auditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	auditLogMu.Lock()
+	defer auditLogMu.Unlock()
+	f, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// jsonBodyValue decodes a request or response body, which is absent if it is
+// empty or not JSON.
+func jsonBodyValue(body []byte) interface{} {
+	var v interface{}
+	if len(bytes.TrimSpace(body)) == 0 || json.Unmarshal(body, &v) != nil {
+		return absentJsonValue
+	}
+	return v
+}
+
+// chefOrganization returns the organization a request path is scoped to.
+func chefOrganization(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	if len(segments) >= 2 && segments[0] == "organizations" {
+		return segments[1]
+	}
+	return ""
+}
+
+// auditChanges converts changes for an audit log, redacting sensitive values,
+// or every value but the keys of objects if redactAll is set. go-chef leaves
+// empty objects and lists out of requests, so adding or removing an empty
+// value is not recorded.
+func auditChanges(changes []jsonChange, redactAll bool) []auditChange {
+	entries := []auditChange{}
+	for _, change := range changes {
+		if (change.From == absentJsonValue && emptyJsonValue(change.To)) || (change.To == absentJsonValue && emptyJsonValue(change.From)) {
+			continue
+		}
+		entry := auditChange{Op: "replace", Path: change.Pointer}
+		switch {
+		case change.From == absentJsonValue:
+			entry.Op = "add"
+		case change.To == absentJsonValue:
+			entry.Op = "remove"
+		}
+		sensitive := sensitiveJsonKey.MatchString(change.Pointer)
+		if change.From != absentJsonValue {
+			entry.Old = redactedJson(change.From, sensitive, redactAll)
+		}
+		if change.To != absentJsonValue {
+			entry.Value = redactedJson(change.To, sensitive, redactAll)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func emptyJsonValue(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func redactedJson(v interface{}, sensitive, redactAll bool) json.RawMessage {
+	if sensitive {
+		v = redactedJsonValue
+	} else {
+		v = redactSensitive(v, redactAll)
+	}
+	rendered, _ := json.Marshal(v)
+	return rendered
+}
+
+// redactSensitive returns a copy of a JSON value with the values of sensitive
+// keys redacted, or all values but the keys of objects if redactAll is set.
+func redactSensitive(v interface{}, redactAll bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for k, member := range v {
+			if sensitiveJsonKey.MatchString(k) {
+				redacted[k] = redactedJsonValue
+			} else {
+				redacted[k] = redactSensitive(member, redactAll)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, element := range v {
+			redacted[i] = redactSensitive(element, redactAll)
+		}
+		return redacted
+	}
+	if redactAll {
+		return redactedJsonValue
+	}
+	return v
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAuditTransport(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	transport := &auditTransport{
+		path:       logPath,
+		label:      "production",
+		clientName: "terraform",
+		base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodDelete {
+				return nil, errors.New("connection refused")
+			}
+			return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody, Request: req}, nil
+		}),
+	}
+
+	requests := []struct{ method, url, body string }{
+		{http.MethodGet, "https://chef.example.com/organizations/test/nodes/web-1", ""},
+		{http.MethodPost, "https://chef.example.com/organizations/test/search/node?q=*:*", `{"name": ["name"]}`},
+		{http.MethodPost, "https://chef.example.com/organizations/test/nodes", `{"name": "web-1", "normal": {"port": 80, "db_password": "hunter2", "app": {"api_token": "abc"}}, "default": {}}`},
+		{http.MethodPost, "https://chef.example.com/organizations/test/data/secrets", `{"id": "db", "password": "hunter2"}`},
+		{http.MethodDelete, "https://chef.example.com/organizations/test/nodes/web-1", ""},
+	}
+	for _, r := range requests {
+		req, _ := http.NewRequest(r.method, r.url, strings.NewReader(r.body))
+		transport.RoundTrip(req)
+	}
+
+	logJson, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(logJson)), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected a request and a response line for each of the 3 requests that change the Chef server, got %d:\n%s", len(lines), logJson)
+	}
+	entries := make([]auditEntry, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &entries[i]); err != nil {
+			t.Fatalf("line %d: %s", i, err)
+		}
+	}
+
+	created := entries[0]
+	if created.Event != auditRequestEvent || created.Label != "production" || created.Client != "terraform" || created.Organization != "test" ||
+		created.Method != http.MethodPost || created.Path != "/organizations/test/nodes" || created.Object != "nodes" || created.Status != 0 {
+		t.Errorf("unexpected entry: %#v", created)
+	}
+	if response := entries[1]; response.Event != auditResponseEvent || response.Label != "production" ||
+		response.Object != "nodes" || response.Status != http.StatusCreated || response.Changes != nil {
+		t.Errorf("unexpected response entry: %#v", response)
+	}
+	expected := `[{"op":"add","path":"","value":{"default":{},"name":"web-1","normal":{"app":{"api_token":"(redacted)"},"db_password":"(redacted)","port":80}}}]`
+	if changes, _ := json.Marshal(created.Changes); string(changes) != expected {
+		t.Errorf("expected changes %s, got %s", expected, changes)
+	}
+
+	expected = `[{"op":"add","path":"","value":{"id":"(redacted)","password":"(redacted)"}}]`
+	if changes, _ := json.Marshal(entries[2].Changes); string(changes) != expected {
+		t.Errorf("expected the data bag item to be redacted as %s, got %s", expected, changes)
+	}
+
+	deleted := entries[5]
+	if deleted.Event != auditResponseEvent || deleted.Object != "nodes/web-1" || deleted.Status != 0 || deleted.Error != "connection refused" {
+		t.Errorf("unexpected entry for a failed request: %#v", deleted)
+	}
+}
+
+func TestAuditTransport_unwritable(t *testing.T) {
+	var sent bool
+	transport := &auditTransport{
+		path:       filepath.Join(t.TempDir(), "missing", "audit.jsonl"),
+		clientName: "terraform",
+		base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			sent = true
+			return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody, Request: req}, nil
+		}),
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://chef.example.com/organizations/test/nodes", strings.NewReader(`{"name": "web-1"}`))
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Error("expected an error when the audit log cannot be written")
+	}
+	if sent {
+		t.Error("expected the request not to be sent when the audit log cannot be written")
+	}
+}
+
+func TestAccProvider_auditLog(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testSuffixRender(testAccProviderConfig_auditLog), logPath),
+				Check: func(s *terraform.State) error {
+					logJson, err := os.ReadFile(logPath)
+					if err != nil {
+						return err
+					}
+					path := "/nodes/terraform-acc-test-audit-log-" + testSuffix
+					for _, line := range strings.Split(strings.TrimSpace(string(logJson)), "\n") {
+						var entry auditEntry
+						if err := json.Unmarshal([]byte(line), &entry); err != nil {
+							return err
+						}
+						if entry.Method == http.MethodPost && strings.HasSuffix(entry.Path, "/nodes") && entry.Status == http.StatusCreated && entry.Label == "acceptance" {
+							return nil
+						}
+					}
+					return fmt.Errorf("no entry for the creation of %s in the audit log:\n%s", path, logJson)
+				},
+			},
+		},
+	})
+}
+
+const testAccProviderConfig_auditLog = `
+provider "chef" {
+  audit_log_path  = %q
+  audit_log_label = "acceptance"
+}
+
+resource "chef_node" "test" {
+  name = "terraform-acc-test-audit-log-{{.}}"
+}
+`
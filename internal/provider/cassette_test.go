@@ -0,0 +1,644 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bdwyertech/terraform-provider-chef/internal/chefzero"
+	chefc "github.com/go-chef/chef"
+)
+
+// Cassettes record the Chef server traffic of each acceptance test, so that
+// the suite can be replayed without a Chef server. Set TF_ACC_CASSETTES to
+// "record" to write one cassette per test into testdata/cassettes while
+// running against a server, or to "replay" to answer every request from the
+// cassettes instead. A test without a cassette fails when replaying.
+//
+// Requests are matched on method, path, query, body and headers, ignoring
+// the X-Ops-* authentication headers, which are not recorded: they hold the
+// signature, timestamp and content hash. Matching interactions are replayed
+// in the order they were recorded, so reads see the same sequence of changes
+// as they did against the server. The scheme and host of the server are
+// replaced with cassetteOrigin, so cassettes do not depend on where they
+// were recorded.
+//
+// Secrets are scrubbed from recorded requests and responses: every value of
+// data bag items, and the values of keys that look sensitive elsewhere, are
+// replaced with a digest. When replaying, a digest is restored to the value
+// the provider sent in its place in the matching request, so that items are
+// read back as written in the same run; values only the server knew stay
+// scrubbed.
+
+const (
+	cassetteDir      = "testdata/cassettes"
+	cassetteSettings = "settings.json"
+	cassetteOrigin   = "https://chef.cassette.test"
+)
+
+// cassetteSuiteSettings is shared by all cassettes, which must be recorded
+// with the same object name suffix and organization to replay together.
+type cassetteSuiteSettings struct {
+	Suffix    string `json:"suffix"`
+	ServerURL string `json:"server_url"`
+}
+
+type cassetteInteraction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+type cassetteRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type cassetteResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+type cassette struct {
+	mu           sync.Mutex
+	Interactions []*cassetteInteraction `json:"interactions"`
+	replayed     []bool
+
+	// restored maps the digests of scrubbed values to the values sent in
+	// their place while replaying.
+	restored map[string]interface{}
+}
+
+var (
+	activeCassetteMu sync.Mutex
+	activeCassette   *cassette
+)
+
+func setActiveCassette(c *cassette) {
+	activeCassetteMu.Lock()
+	defer activeCassetteMu.Unlock()
+	activeCassette = c
+}
+
+func getActiveCassette() *cassette {
+	activeCassetteMu.Lock()
+	defer activeCassetteMu.Unlock()
+	return activeCassette
+}
+
+// testAccCassettes prepares the suite for recording or replaying, before
+// any test runs. When replaying, the provider is pointed at the recorded
+// organization and given a throwaway key, since requests are never sent.
+func testAccCassettes(mode string) error {
+	if mode != "record" && mode != "replay" {
+		return fmt.Errorf("TF_ACC_CASSETTES must be \"record\" or \"replay\", got %q", mode)
+	}
+	replay := mode == "replay"
+	chefTransport = func(base http.RoundTripper) http.RoundTripper {
+		return &cassetteTransport{base: base, replay: replay}
+	}
+
+	settingsPath := filepath.Join(cassetteDir, cassetteSettings)
+	var settings cassetteSuiteSettings
+	contents, err := os.ReadFile(settingsPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(contents, &settings); err != nil {
+			return fmt.Errorf("%s: %s", settingsPath, err)
+		}
+		testSuffix = settings.Suffix
+	case errors.Is(err, os.ErrNotExist):
+		if replay {
+			return nil
+		}
+		settings.Suffix = testSuffix
+	default:
+		return err
+	}
+
+	if replay {
+		privateKey, _, err := generateClientKeyPair()
+		if err != nil {
+			return err
+		}
+		os.Setenv("CHEF_SERVER_URL", settings.ServerURL)
+		os.Setenv("CHEF_CLIENT_NAME", "terraform-cassette")
+		os.Setenv("CHEF_KEY_MATERIAL", privateKey)
+		return nil
+	}
+
+	serverURL, err := url.Parse(os.Getenv("CHEF_SERVER_URL"))
+	if err != nil {
+		return err
+	}
+	settings.ServerURL = cassetteOrigin + serverURL.Path
+	contents, err = json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cassetteDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(settingsPath, append(contents, '\n'), 0o644)
+}
+
+// testAccCassette loads or starts the cassette of a test, when cassettes are
+// in use. It is called from testAccPreCheck, so every acceptance test gets
+// its own cassette.
+func testAccCassette(t *testing.T) {
+	mode := os.Getenv("TF_ACC_CASSETTES")
+	if mode == "" {
+		return
+	}
+
+	path := filepath.Join(cassetteDir, strings.ReplaceAll(t.Name(), "/", "_")+".json")
+	c := &cassette{}
+	if mode == "replay" {
+		contents, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("no cassette recorded at %s; record it with TF_ACC_CASSETTES=record", path)
+		}
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := json.Unmarshal(contents, c); err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		c.replayed = make([]bool, len(c.Interactions))
+	}
+
+	setActiveCassette(c)
+	t.Cleanup(func() {
+		setActiveCassette(nil)
+		if mode != "record" || t.Failed() {
+			return
+		}
+		contents, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := os.WriteFile(path, append(contents, '\n'), 0o644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	})
+}
+
+// replay returns the response of the first matching interaction that has
+// not been replayed yet. Once all of them have been, the last one is
+// repeated, so that extra reads by another Terraform version see the final
+// recorded state. sent is the request body before it was scrubbed.
+func (c *cassette) replay(req cassetteRequest, sent string) (cassetteResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	match := -1
+	for i, interaction := range c.Interactions {
+		if !interaction.Request.matches(req) {
+			continue
+		}
+		match = i
+		if !c.replayed[i] {
+			c.replayed[i] = true
+			break
+		}
+	}
+	if match < 0 {
+		return cassetteResponse{}, false
+	}
+
+	interaction := c.Interactions[match]
+	if c.restored == nil {
+		c.restored = map[string]interface{}{}
+	}
+	learnScrubbedValues(decodeCassetteJson(interaction.Request.Body), decodeCassetteJson(sent), c.restored)
+	resp := interaction.Response
+	resp.Body = restoreScrubbedValues(resp.Body, c.restored)
+	return resp, true
+}
+
+func (c *cassette) record(req cassetteRequest, resp cassetteResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, &cassetteInteraction{Request: req, Response: resp})
+}
+
+func (r cassetteRequest) matches(other cassetteRequest) bool {
+	if r.Method != other.Method || r.Path != other.Path {
+		return false
+	}
+	if (len(r.Header) > 0 || len(other.Header) > 0) && !reflect.DeepEqual(r.Header, other.Header) {
+		return false
+	}
+	return r.Body == other.Body || jsonEquivalent(scrubCassetteBody(r.Body), scrubCassetteBody(other.Body))
+}
+
+// cassetteGeneratedKeys are the request body keys whose values are generated
+// anew on every run, such as the public half of a key pair made by the
+// provider, the expiration date of short-lived keys and the ciphertext of
+// encrypted data bag items. They are ignored when matching requests.
+var cassetteGeneratedKeys = []string{"public_key", "expiration_date", "encrypted_data", "iv", "hmac", "auth_tag"}
+
+// cassetteStructuralKeys are the keys of data bag items and search results
+// whose values are kept when every other value is scrubbed.
+var cassetteStructuralKeys = []string{"id", "name", "chef_type", "data_bag", "json_class", "url", "total", "start"}
+
+// cassetteBuiltinIndexes are the search indexes that are not data bags.
+var cassetteBuiltinIndexes = []string{"node", "role", "client", "environment"}
+
+// cassetteScrubbedPrefix starts the digest a scrubbed value is replaced with.
+const cassetteScrubbedPrefix = "(scrubbed "
+
+// scrubsAllValues reports whether every value in the bodies of requests to a
+// path is scrubbed, as they hold data bag items.
+func scrubsAllValues(p string) bool {
+	p, _, _ = strings.Cut(p, "?")
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	if len(segments) >= 2 && segments[0] == "organizations" {
+		segments = segments[2:]
+	}
+	switch {
+	case len(segments) >= 2 && segments[0] == "data":
+		return true
+	case len(segments) == 2 && segments[0] == "search":
+		return !slices.Contains(cassetteBuiltinIndexes, segments[1])
+	}
+	return false
+}
+
+// scrubCassetteSecrets replaces the secret values of a JSON body recorded for
+// a path with their digests. Bodies that are not JSON are returned as is.
+func scrubCassetteSecrets(p, body string) string {
+	v := decodeCassetteJson(body)
+	if v == nil {
+		return body
+	}
+	scrubbed, changed := scrubCassetteValue(v, scrubsAllValues(p))
+	if !changed {
+		return body
+	}
+	b, _ := json.Marshal(scrubbed)
+	return string(b)
+}
+
+func scrubCassetteValue(v interface{}, all bool) (interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		changed := false
+		for k, member := range v {
+			var c bool
+			switch {
+			case all && slices.Contains(cassetteStructuralKeys, k):
+				continue
+			case sensitiveJsonKey.MatchString(k):
+				v[k], c = scrubCassetteValue(member, true)
+			default:
+				v[k], c = scrubCassetteValue(member, all)
+			}
+			changed = changed || c
+		}
+		return v, changed
+	case []interface{}:
+		changed := false
+		for i, element := range v {
+			var c bool
+			v[i], c = scrubCassetteValue(element, all)
+			changed = changed || c
+		}
+		return v, changed
+	case string:
+		if strings.HasPrefix(v, cassetteScrubbedPrefix) {
+			return v, false
+		}
+	}
+	if !all || v == nil {
+		return v, false
+	}
+	b, _ := json.Marshal(v)
+	digest := sha256.Sum256(b)
+	return cassetteScrubbedPrefix + hex.EncodeToString(digest[:8]) + ")", true
+}
+
+// learnScrubbedValues pairs the digests and generated values in a recorded
+// request body with the values sent in their place, so that responses that
+// echo them, such as a key read back, match what was sent.
+func learnScrubbedValues(recorded, sent interface{}, restored map[string]interface{}) {
+	switch recorded := recorded.(type) {
+	case string:
+		if strings.HasPrefix(recorded, cassetteScrubbedPrefix) && sent != nil {
+			restored[recorded] = sent
+		}
+	case map[string]interface{}:
+		if sent, ok := sent.(map[string]interface{}); ok {
+			for k, member := range recorded {
+				if generated, ok := member.(string); ok && generated != "" && slices.Contains(cassetteGeneratedKeys, k) && sent[k] != nil {
+					restored[generated] = sent[k]
+					continue
+				}
+				learnScrubbedValues(member, sent[k], restored)
+			}
+		}
+	case []interface{}:
+		if sent, ok := sent.([]interface{}); ok && len(sent) == len(recorded) {
+			for i, element := range recorded {
+				learnScrubbedValues(element, sent[i], restored)
+			}
+		}
+	}
+}
+
+// restoreScrubbedValues replaces the digests and generated values in a
+// recorded response body with the values learned from the requests replayed
+// so far.
+func restoreScrubbedValues(body string, restored map[string]interface{}) string {
+	if len(restored) == 0 {
+		return body
+	}
+	v := decodeCassetteJson(body)
+	if v == nil {
+		return body
+	}
+	var restore func(v interface{}) interface{}
+	restore = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case string:
+			if original, ok := restored[v]; ok {
+				return original
+			}
+		case map[string]interface{}:
+			for k, member := range v {
+				v[k] = restore(member)
+			}
+		case []interface{}:
+			for i, element := range v {
+				v[i] = restore(element)
+			}
+		}
+		return v
+	}
+	b, _ := json.Marshal(restore(v))
+	return string(b)
+}
+
+// decodeCassetteJson decodes a body, keeping numbers as they were written,
+// or returns nil if it is not JSON.
+func decodeCassetteJson(body string) interface{} {
+	d := json.NewDecoder(strings.NewReader(body))
+	d.UseNumber()
+	var v interface{}
+	if d.Decode(&v) != nil {
+		return nil
+	}
+	return v
+}
+
+// scrubCassetteBody blanks the generated values of a JSON request body.
+// Bodies that are not JSON are returned as is.
+func scrubCassetteBody(body string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+	var scrub func(v interface{})
+	scrub = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, member := range v {
+				if slices.Contains(cassetteGeneratedKeys, k) {
+					v[k] = ""
+				} else {
+					scrub(member)
+				}
+			}
+		case []interface{}:
+			for _, element := range v {
+				scrub(element)
+			}
+		}
+	}
+	scrub(v)
+	scrubbed, _ := json.Marshal(v)
+	return string(scrubbed)
+}
+
+type cassetteTransport struct {
+	base   http.RoundTripper
+	replay bool
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := getActiveCassette()
+	if c == nil {
+		if t.replay {
+			return nil, fmt.Errorf("no cassette is loaded to replay %s %s", req.Method, req.URL.Path)
+		}
+		return t.base.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := newCassetteRequest(req, body)
+	recorded.Body = scrubCassetteSecrets(recorded.Path, recorded.Body)
+
+	if t.replay {
+		resp, ok := c.replay(recorded, string(body))
+		if !ok {
+			return nil, fmt.Errorf("no recorded interaction matches %s %s", req.Method, recorded.Path)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+			StatusCode:    resp.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{resp.ContentType}},
+			Body:          io.NopCloser(strings.NewReader(resp.Body)),
+			ContentLength: int64(len(resp.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	origin := req.URL.Scheme + "://" + req.URL.Host
+	c.record(recorded, cassetteResponse{
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        scrubCassetteSecrets(recorded.Path, strings.ReplaceAll(string(respBody), origin, cassetteOrigin)),
+	})
+	return resp, nil
+}
+
+// newCassetteRequest returns the parts of a request that are recorded and
+// matched, leaving out the X-Ops-* authentication headers.
+func newCassetteRequest(req *http.Request, body []byte) cassetteRequest {
+	header := http.Header{}
+	for name, values := range req.Header {
+		if !strings.HasPrefix(http.CanonicalHeaderKey(name), "X-Ops-") {
+			header[name] = values
+		}
+	}
+
+	path := req.URL.EscapedPath()
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+	return cassetteRequest{
+		Method: req.Method,
+		Path:   path,
+		Header: header,
+		Body:   string(body),
+	}
+}
+
+// TestCassette_recordReplay records traffic against the fake server and
+// checks that the same requests are answered identically from the cassette,
+// with the server gone and a different key.
+func TestCassette_recordReplay(t *testing.T) {
+	server, err := chefzero.NewServer("terraform")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer func(transport func(http.RoundTripper) http.RoundTripper) { chefTransport = transport }(chefTransport)
+	defer setActiveCassette(nil)
+
+	exercise := func(cfg chefClientConfig) []interface{} {
+		client, err := newChefClient(cfg)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		var results []interface{}
+		for _, runList := range [][]string{{"recipe[a]"}, {"recipe[b]"}} {
+			_, err := client.Nodes.Put(chefc.Node{Name: "n1", RunList: runList})
+			if isNotFound(err) {
+				_, err = client.Nodes.Post(chefc.Node{Name: "n1", RunList: runList})
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			node, err := client.Nodes.Get("n1")
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			results = append(results, node)
+		}
+		if _, err := client.DataBags.Create(&chefc.DataBag{Name: "secrets"}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		item := map[string]interface{}{"id": "db", "password": "hunter2", "port": 5432}
+		if err := client.DataBags.CreateItem("secrets", item); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		stored, err := client.DataBags.GetItem("secrets", "db")
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		results = append(results, stored)
+		_, err = client.Nodes.Get("missing")
+		return append(results, isNotFound(err))
+	}
+
+	recording := &cassette{}
+	chefTransport = func(base http.RoundTripper) http.RoundTripper {
+		return &cassetteTransport{base: base}
+	}
+	setActiveCassette(recording)
+	recorded := exercise(chefClientConfig{
+		ServerURL:  server.OrganizationURL(),
+		ClientName: server.ClientName,
+		Key:        server.ClientKey,
+	})
+	server.Close()
+
+	for _, interaction := range recording.Interactions {
+		for name := range interaction.Request.Header {
+			if strings.HasPrefix(name, "X-Ops-") {
+				t.Fatalf("%s was recorded", name)
+			}
+		}
+		if strings.Contains(interaction.Response.Body, server.URL) {
+			t.Fatalf("the server URL was recorded: %s", interaction.Response.Body)
+		}
+		for _, body := range []string{interaction.Request.Body, interaction.Response.Body} {
+			if strings.Contains(body, "hunter2") || strings.Contains(body, "5432") {
+				t.Fatalf("the data bag item was recorded: %s", body)
+			}
+		}
+	}
+
+	contents, err := json.Marshal(recording)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	replaying := &cassette{}
+	if err := json.Unmarshal(contents, replaying); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	replaying.replayed = make([]bool, len(replaying.Interactions))
+	chefTransport = func(base http.RoundTripper) http.RoundTripper {
+		return &cassetteTransport{base: base, replay: true}
+	}
+	setActiveCassette(replaying)
+	key, _, err := generateClientKeyPair()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	replayed := exercise(chefClientConfig{
+		ServerURL:  cassetteOrigin + "/organizations/terraform/",
+		ClientName: "terraform-cassette",
+		Key:        key,
+	})
+
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Fatalf("replay differs; recorded %#v, replayed %#v", recorded, replayed)
+	}
+}
+
+func TestCassetteRequest_matchesGeneratedValues(t *testing.T) {
+	recorded := cassetteRequest{
+		Method: http.MethodPost,
+		Path:   "/organizations/terraform/clients/test/keys",
+		Body:   `{"name":"default","public_key":"-----BEGIN PUBLIC KEY-----\nrecorded","expiration_date":"2024-01-01T00:00:00Z"}`,
+	}
+	replayed := recorded
+	replayed.Body = `{"expiration_date":"2026-10-19T18:00:00Z","name":"default","public_key":"-----BEGIN PUBLIC KEY-----\nreplayed"}`
+	if !recorded.matches(replayed) {
+		t.Errorf("expected requests that only differ in generated values to match")
+	}
+
+	replayed.Body = `{"name":"other","public_key":"","expiration_date":""}`
+	if recorded.matches(replayed) {
+		t.Errorf("expected requests for different keys not to match")
+	}
+}
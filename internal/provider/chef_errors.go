@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	sdkdiag "github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+
+	chefc "github.com/go-chef/chef"
+)
+
+// chefErrorResponse returns the Chef server response behind err, if err
+// came from the server.
+func chefErrorResponse(err error) *chefc.ErrorResponse {
+	cerr, _ := chefc.ChefError(err)
+	return cerr
+}
+
+// chefErrorStatus returns the HTTP status of a Chef server error, or 0 if
+// err did not come from the server.
+func chefErrorStatus(err error) int {
+	if cerr := chefErrorResponse(err); cerr != nil && cerr.Response != nil {
+		return cerr.Response.StatusCode
+	}
+	return 0
+}
+
+// isNotFound reports whether err is a 404 response from the Chef server.
+func isNotFound(err error) bool {
+	return chefErrorStatus(err) == http.StatusNotFound
+}
+
+// chefErrorDetail renders an error for a diagnostic. Errors from the Chef
+// server include the request, the status, the messages returned by the
+// server and the request ID to look up in its logs, and authentication and
+// authorization failures come with a hint on how to fix them.
+func chefErrorDetail(err error) string {
+	cerr := chefErrorResponse(err)
+	if cerr == nil || cerr.Response == nil || cerr.Response.Request == nil {
+		return err.Error()
+	}
+	resp := cerr.Response
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s: %d %s", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, http.StatusText(resp.StatusCode))
+	if messages := chefErrorMessages(cerr); len(messages) > 0 {
+		b.WriteString("\n\nThe Chef server returned:")
+		for _, msg := range messages {
+			b.WriteString("\n  - " + msg)
+		}
+	}
+	if id := resp.Header.Get("X-Request-Id"); id != "" {
+		b.WriteString("\n\nRequest ID: " + id)
+	}
+	if hint := chefErrorHint(resp); hint != "" {
+		b.WriteString("\n\n" + hint)
+	}
+	return b.String()
+}
+
+// chefErrorMessages returns the "error" array of a Chef server error body.
+// Some endpoints return a single string instead, and bodies that are not
+// JSON are returned as is.
+func chefErrorMessages(cerr *chefc.ErrorResponse) []string {
+	text := strings.TrimSpace(string(cerr.ErrorText))
+	if text == "" {
+		return nil
+	}
+
+	var body struct {
+		Error interface{} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(text), &body); err != nil {
+		return []string{text}
+	}
+	switch e := body.Error.(type) {
+	case string:
+		return []string{e}
+	case []interface{}:
+		messages := make([]string, 0, len(e))
+		for _, msg := range e {
+			messages = append(messages, fmt.Sprint(msg))
+		}
+		return messages
+	case nil:
+		return nil
+	default:
+		return []string{fmt.Sprint(e)}
+	}
+}
+
+// chefErrorHint explains how to fix authentication and authorization
+// failures, which are caused by the provider configuration or the server's
+// permissions rather than by the resource.
+func chefErrorHint(resp *http.Response) string {
+	actor := resp.Request.Header.Get("X-Ops-UserId")
+	if actor == "" {
+		actor = "(unknown)"
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return fmt.Sprintf("The Chef server could not authenticate client %q. Check that client_name and the configured key belong together, "+
+			"that the key has not expired, and that the clock of this machine is within 15 minutes of the server's.", actor)
+	case http.StatusForbidden:
+		permission := map[string]string{
+			http.MethodPost:   "create",
+			http.MethodPut:    "update",
+			http.MethodDelete: "delete",
+		}[resp.Request.Method]
+		if permission == "" {
+			permission = "read"
+		}
+
+		container, object := chefErrorTarget(resp.Request.URL.Path)
+		if object == "" || resp.Request.Method == http.MethodPost {
+			return fmt.Sprintf("Client %q lacks %s on the %s container. Grant it in the permissions of the container, "+
+				"or add the client to a group that has it.", actor, permission, container)
+		}
+		return fmt.Sprintf("Client %q lacks %s on %s/%s. Grant it in the permissions of the object, "+
+			"or add the client to a group that has it.", actor, permission, container, object)
+	}
+	return ""
+}
+
+// chefErrorTarget returns the container and object that a request path
+// refers to, with the organization prefix removed. Nested paths such as
+// data bag items refer to their parent object, which holds the ACL.
+func chefErrorTarget(p string) (container, object string) {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	if len(segments) >= 2 && segments[0] == "organizations" {
+		segments = segments[2:]
+	}
+	if len(segments) == 0 || segments[0] == "" {
+		return "organization", ""
+	}
+	if len(segments) == 1 {
+		return segments[0], ""
+	}
+	return segments[0], segments[1]
+}
+
+// chefDiagnostic returns the diagnostic for a failed Chef server request.
+// Conflicts and missing objects are attributed to the argument that names
+// the object, when given; other failures are not caused by any one argument.
+func chefDiagnostic(summary string, err error, attribute path.Path) diag.Diagnostic {
+	detail := chefErrorDetail(err)
+	switch chefErrorStatus(err) {
+	case http.StatusNotFound, http.StatusConflict:
+		if len(attribute.Steps()) > 0 {
+			return diag.NewAttributeErrorDiagnostic(attribute, summary, detail)
+		}
+	}
+	return diag.NewErrorDiagnostic(summary, detail)
+}
+
+// chefSDKDiagnostics is chefDiagnostic for the SDKv2 data sources.
+func chefSDKDiagnostics(summary string, err error, attribute cty.Path) sdkdiag.Diagnostics {
+	d := sdkdiag.Diagnostic{
+		Severity: sdkdiag.Error,
+		Summary:  summary,
+		Detail:   chefErrorDetail(err),
+	}
+	switch chefErrorStatus(err) {
+	case http.StatusNotFound, http.StatusConflict:
+		d.AttributePath = attribute
+	}
+	return sdkdiag.Diagnostics{d}
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+
+	chefc "github.com/go-chef/chef"
+
+	"github.com/bdwyertech/terraform-provider-chef/internal/chefzero"
+)
+
+func testChefError(method, rawPath string, status int, body string) error {
+	u, _ := url.Parse("https://chef.example.com" + rawPath)
+	req := &http.Request{Method: method, URL: u, Header: http.Header{"X-Ops-Userid": []string{"terraform"}}}
+	return &chefc.ErrorResponse{
+		Response: &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"X-Request-Id": []string{"req-1"}},
+			Request:    req,
+		},
+		ErrorText: []byte(body),
+	}
+}
+
+func TestChefErrorDetail(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{
+			name: "forbidden update",
+			err:  testChefError("PUT", "/organizations/test/nodes/web1", 403, `{"error":["missing update permission"]}`),
+			want: []string{
+				"PUT /organizations/test/nodes/web1: 403 Forbidden",
+				"  - missing update permission",
+				"Request ID: req-1",
+				`Client "terraform" lacks update on nodes/web1.`,
+			},
+		},
+		{
+			name: "forbidden create",
+			err:  testChefError("POST", "/organizations/test/nodes", 403, `{"error":["missing create permission"]}`),
+			want: []string{`Client "terraform" lacks create on the nodes container.`},
+		},
+		{
+			name: "forbidden data bag item",
+			err:  testChefError("DELETE", "/organizations/test/data/secrets/db", 403, ``),
+			want: []string{`Client "terraform" lacks delete on data/secrets.`},
+		},
+		{
+			name: "unauthorized",
+			err:  testChefError("GET", "/organizations/test/roles/web", 401, `{"error":"Failed to authenticate as 'terraform'."}`),
+			want: []string{
+				"  - Failed to authenticate as 'terraform'.",
+				`could not authenticate client "terraform"`,
+			},
+		},
+		{
+			name: "error array",
+			err:  testChefError("POST", "/organizations/test/roles", 400, `{"error":["Field 'name' invalid","Field 'run_list' invalid"]}`),
+			want: []string{"  - Field 'name' invalid\n  - Field 'run_list' invalid"},
+		},
+		{
+			name: "not JSON",
+			err:  testChefError("GET", "/organizations/test/roles/web", 502, `<html>Bad Gateway</html>`),
+			want: []string{"GET /organizations/test/roles/web: 502 Bad Gateway", "  - <html>Bad Gateway</html>"},
+		},
+		{
+			name: "not from the server",
+			err:  errors.New("dial tcp: connection refused"),
+			want: []string{"dial tcp: connection refused"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			detail := chefErrorDetail(tc.err)
+			for _, want := range tc.want {
+				if !strings.Contains(detail, want) {
+					t.Errorf("expected %q in:\n%s", want, detail)
+				}
+			}
+		})
+	}
+}
+
+func TestChefDiagnostic_attribute(t *testing.T) {
+	attribute := path.Root("name")
+	cases := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusConflict, true},
+		{http.StatusForbidden, false},
+		{http.StatusInternalServerError, false},
+	}
+	for _, tc := range cases {
+		d := chefDiagnostic("Error", testChefError("GET", "/organizations/test/nodes/web1", tc.status, ""), attribute)
+		_, attributed := d.(interface{ Path() path.Path })
+		if attributed != tc.want {
+			t.Errorf("%d: expected attributed to be %v", tc.status, tc.want)
+		}
+
+		sdk := chefSDKDiagnostics("Error", testChefError("GET", "/organizations/test/nodes/web1", tc.status, ""), nil)
+		if len(sdk) != 1 || sdk[0].Summary != "Error" {
+			t.Errorf("%d: unexpected diagnostics %#v", tc.status, sdk)
+		}
+	}
+}
+
+func TestChefErrorDetail_server(t *testing.T) {
+	server, err := chefzero.NewServer("terraform")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer server.Close()
+
+	client, err := newChefClient(chefClientConfig{
+		ServerURL:  server.OrganizationURL(),
+		ClientName: server.ClientName,
+		Key:        server.ClientKey,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = client.Nodes.Get("missing")
+	if !isNotFound(err) {
+		t.Fatalf("expected a 404, got %v", err)
+	}
+	detail := chefErrorDetail(err)
+	for _, want := range []string{"GET /organizations/terraform/nodes/missing: 404 Not Found", "Request ID: chefzero-"} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("expected %q in:\n%s", want, detail)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// conflictDetectionDescription describes the conflict_detection provider
+// argument.
+const conflictDetectionDescription = "If set, nodes, roles and environments are fetched again before they are updated and compared with the snapshot taken when they were last read. " +
+	"With `error`, an update fails if the object was changed outside of Terraform, and the changes are shown. " +
+	"With `merge`, changes made outside of Terraform are kept if they do not overlap with the update, and show up as drift in the next plan."
+
+// conflictDetectionModes are the values of the conflict_detection argument.
+var conflictDetectionModes = []string{"error", "merge"}
+
+// snapshotKey is the private state key holding the hash of an object as it
+// was last read from the Chef server.
+const snapshotKey = "snapshot"
+
+// privateStateGetter and privateStateSetter are implemented by the private
+// state of framework requests and responses.
+type privateStateGetter interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+type privateStateSetter interface {
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// setSnapshot records the hash of an object read from the Chef server.
+func setSnapshot(ctx context.Context, private privateStateSetter, object interface{}) diag.Diagnostics {
+	hash, err := snapshotHash(object)
+	if err != nil {
+		var diags diag.Diagnostics
+		diags.AddError("Error recording the snapshot of the object", err.Error())
+		return diags
+	}
+	value, _ := json.Marshal(hash)
+	return private.SetKey(ctx, snapshotKey, value)
+}
+
+func snapshotHash(object interface{}) (string, error) {
+	objectJson, err := json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(objectJson)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkConflicts is called before an object is updated with planned. get
+// fetches the object as it is now on the Chef server, and base is the object
+// as Terraform last saw it, built from the prior state. If the object was
+// changed since it was last read, the update is refused or, in merge mode,
+// the changes are merged into the update. The JSON of the merged object is
+// returned, or nil if planned can be written as is.
+func (c *chefClient) checkConflicts(ctx context.Context, private privateStateGetter, kind string, base, planned interface{}, get func() (interface{}, error)) ([]byte, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if c.ConflictDetection == "" {
+		return nil, nil
+	}
+
+	// Objects that were not read since conflict detection was added have
+	// no snapshot to compare with.
+	stored, getDiags := private.GetKey(ctx, snapshotKey)
+	diags.Append(getDiags...)
+	var snapshot string
+	if len(stored) == 0 || json.Unmarshal(stored, &snapshot) != nil {
+		return nil, diags
+	}
+	current, err := get()
+	if err != nil {
+		diags.Append(chefDiagnostic(fmt.Sprintf("Error reading the %s to check for conflicts", kind), err, path.Root("name")))
+		return nil, diags
+	}
+	currentHash, err := snapshotHash(current)
+	if err != nil {
+		diags.AddError("Error checking for conflicts", err.Error())
+		return nil, diags
+	}
+	if currentHash == snapshot {
+		return nil, diags
+	}
+
+	values := make([]interface{}, 3)
+	for i, object := range []interface{}{base, planned, current} {
+		if values[i], err = jsonObjectValue(object); err != nil {
+			diags.AddError("Error checking for conflicts", err.Error())
+			return nil, diags
+		}
+	}
+	var lines []string
+	for _, change := range jsonChanges("", values[0], values[2]) {
+		lines = append(lines, change.String())
+	}
+	changes := strings.Join(lines, "\n")
+
+	if c.ConflictDetection != "merge" {
+		diags.AddError(fmt.Sprintf("The %s was changed outside of Terraform", kind),
+			fmt.Sprintf("The %s changed on the Chef server since it was last read, and updating it would discard these changes:\n\n%s\n\n"+
+				"Run terraform plan again to review them.", kind, changes))
+		return nil, diags
+	}
+
+	var conflicts []string
+	merged := mergeJsonValues("", values[0], values[1], values[2], &conflicts)
+	if len(conflicts) > 0 {
+		diags.AddError(fmt.Sprintf("Conflicting changes to the %s outside of Terraform", kind),
+			fmt.Sprintf("The %s changed on the Chef server since it was last read, and these changes overlap with the update: %s\n\n"+
+				"Changes made outside of Terraform:\n\n%s", kind, strings.Join(conflicts, ", "), changes))
+		return nil, diags
+	}
+	mergedJson, err := json.Marshal(merged)
+	if err != nil {
+		diags.AddError("Error merging changes", err.Error())
+		return nil, diags
+	}
+	diags.AddWarning(fmt.Sprintf("Merged changes to the %s made outside of Terraform", kind),
+		fmt.Sprintf("The %s changed on the Chef server since it was last read. These changes were kept, and show up in the next plan:\n\n%s", kind, changes))
+	return mergedJson, diags
+}
+
+func jsonObjectValue(object interface{}) (interface{}, error) {
+	objectJson, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(objectJson, &v)
+	return v, err
+}
+
+// absentJsonValue stands for a key that is missing from an object.
+var absentJsonValue = &struct{}{}
+
+func jsonMember(m map[string]interface{}, key string) interface{} {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return absentJsonValue
+}
+
+func sameJsonValue(a, b interface{}) bool {
+	if a == absentJsonValue || b == absentJsonValue {
+		return a == b
+	}
+	return jsonValuesEqual(a, b)
+}
+
+// unionKeys returns the keys of the objects among values, sorted.
+func unionKeys(values ...interface{}) []string {
+	seen := map[string]bool{}
+	for _, v := range values {
+		if m, ok := v.(map[string]interface{}); ok {
+			for k := range m {
+				seen[k] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonChange is a difference between two JSON values at a JSON pointer.
+// From or To is absentJsonValue for a key that was added or removed.
+type jsonChange struct {
+	Pointer string
+	From    interface{}
+	To      interface{}
+}
+
+func (c jsonChange) String() string {
+	switch {
+	case c.From == absentJsonValue:
+		return fmt.Sprintf("+ %s: %s", c.Pointer, renderJsonValue(c.To))
+	case c.To == absentJsonValue:
+		return fmt.Sprintf("- %s: %s", c.Pointer, renderJsonValue(c.From))
+	}
+	return fmt.Sprintf("~ %s: %s => %s", c.Pointer, renderJsonValue(c.From), renderJsonValue(c.To))
+}
+
+// jsonChanges lists the differences between two JSON values by JSON
+// pointer, descending into objects.
+func jsonChanges(pointer string, from, to interface{}) []jsonChange {
+	if sameJsonValue(from, to) {
+		return nil
+	}
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if !fromIsMap || !toIsMap {
+		return []jsonChange{{Pointer: pointer, From: from, To: to}}
+	}
+
+	var changes []jsonChange
+	for _, k := range unionKeys(fromMap, toMap) {
+		changes = append(changes, jsonChanges(pointer+jsonPointer([]string{k}), jsonMember(fromMap, k), jsonMember(toMap, k))...)
+	}
+	return changes
+}
+
+func renderJsonValue(v interface{}) string {
+	rendered, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(rendered)
+}
+
+// mergeJsonValues is a three-way merge of the changes from base to ours and
+// from base to theirs. Objects are merged key by key, and any other value
+// that both sides changed differently is a conflict, for which ours is kept.
+func mergeJsonValues(pointer string, base, ours, theirs interface{}, conflicts *[]string) interface{} {
+	switch {
+	case sameJsonValue(ours, theirs), sameJsonValue(base, theirs):
+		return ours
+	case sameJsonValue(base, ours):
+		return theirs
+	}
+
+	baseMap, baseIsMap := base.(map[string]interface{})
+	oursMap, oursIsMap := ours.(map[string]interface{})
+	theirsMap, theirsIsMap := theirs.(map[string]interface{})
+	if !oursIsMap || !theirsIsMap {
+		*conflicts = append(*conflicts, pointer)
+		return ours
+	}
+	if !baseIsMap {
+		baseMap = map[string]interface{}{}
+	}
+
+	merged := map[string]interface{}{}
+	for _, k := range unionKeys(baseMap, oursMap, theirsMap) {
+		v := mergeJsonValues(pointer+jsonPointer([]string{k}), jsonMember(baseMap, k), jsonMember(oursMap, k), jsonMember(theirsMap, k), conflicts)
+		if v != absentJsonValue {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// fillUnknown copies the values of a resource model that are unknown in dst
+// from src. It is used when the object written differs from the plan, to
+// record the plan while filling in what was computed.
+func fillUnknown(dst, src interface{}) {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < d.NumField(); i++ {
+		if v, ok := d.Field(i).Interface().(attr.Value); ok && v.IsUnknown() {
+			d.Field(i).Set(s.Field(i))
+		}
+	}
+}
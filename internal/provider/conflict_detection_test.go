@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+type testPrivateState map[string][]byte
+
+func (p testPrivateState) GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics) {
+	return p[key], nil
+}
+
+func (p testPrivateState) SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics {
+	p[key] = value
+	return nil
+}
+
+func TestMergeJsonValues(t *testing.T) {
+	base := map[string]interface{}{
+		"run_list": []interface{}{"recipe[a]"},
+		"normal":   map[string]interface{}{"app": "web", "port": float64(80)},
+	}
+	ours := map[string]interface{}{
+		"run_list": []interface{}{"recipe[a]", "recipe[b]"},
+		"normal":   map[string]interface{}{"app": "web", "port": float64(80)},
+	}
+	theirs := map[string]interface{}{
+		"run_list": []interface{}{"recipe[a]"},
+		"normal":   map[string]interface{}{"app": "web", "port": float64(8080), "extra": true},
+	}
+
+	var conflicts []string
+	merged := mergeJsonValues("", base, ours, theirs, &conflicts)
+	expected := map[string]interface{}{
+		"run_list": []interface{}{"recipe[a]", "recipe[b]"},
+		"normal":   map[string]interface{}{"app": "web", "port": float64(8080), "extra": true},
+	}
+	if len(conflicts) > 0 || !reflect.DeepEqual(merged, expected) {
+		t.Errorf("wrong merge; expected %#v, got %#v with conflicts %v", expected, merged, conflicts)
+	}
+
+	ours["normal"] = map[string]interface{}{"app": "api", "port": float64(443)}
+	conflicts = nil
+	mergeJsonValues("", base, ours, theirs, &conflicts)
+	if !reflect.DeepEqual(conflicts, []string{"/normal/port"}) {
+		t.Errorf("expected a conflict on /normal/port, got %v", conflicts)
+	}
+
+	var changes []string
+	for _, change := range jsonChanges("", base, theirs) {
+		changes = append(changes, change.String())
+	}
+	expectedChanges := []string{
+		"+ /normal/extra: true",
+		"~ /normal/port: 80 => 8080",
+	}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("wrong changes; expected %#v, got %#v", expectedChanges, changes)
+	}
+}
+
+func TestCheckConflicts(t *testing.T) {
+	ctx := context.Background()
+	base := map[string]interface{}{"description": "a", "default_attributes": map[string]interface{}{}}
+	planned := map[string]interface{}{"description": "b", "default_attributes": map[string]interface{}{}}
+	current := map[string]interface{}{"description": "a", "default_attributes": map[string]interface{}{"owner": "ops"}}
+	get := func() (interface{}, error) { return current, nil }
+
+	private := testPrivateState{}
+	setSnapshot(ctx, private, base)
+
+	client := &chefClient{}
+	if merged, diags := client.checkConflicts(ctx, private, "role", base, planned, get); merged != nil || diags.HasError() {
+		t.Errorf("expected no check without conflict_detection, got %s %v", merged, diags)
+	}
+
+	client.ConflictDetection = "error"
+	if _, diags := client.checkConflicts(ctx, private, "role", base, planned, func() (interface{}, error) { return base, nil }); diags.HasError() {
+		t.Errorf("expected no conflict for an unchanged role, got %v", diags)
+	}
+	_, diags := client.checkConflicts(ctx, private, "role", base, planned, get)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail(), `+ /default_attributes/owner: "ops"`) {
+		t.Errorf("expected an error showing the change, got %v", diags)
+	}
+	if merged, diags := client.checkConflicts(ctx, testPrivateState{}, "role", base, planned, get); merged != nil || diags.HasError() {
+		t.Errorf("expected no check without a snapshot, got %s %v", merged, diags)
+	}
+
+	client.ConflictDetection = "merge"
+	merged, diags := client.checkConflicts(ctx, private, "role", base, planned, get)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	var mergedValue map[string]interface{}
+	if err := json.Unmarshal(merged, &mergedValue); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"description": "b", "default_attributes": map[string]interface{}{"owner": "ops"}}
+	if !reflect.DeepEqual(mergedValue, expected) {
+		t.Errorf("wrong merge; expected %#v, got %#v", expected, mergedValue)
+	}
+
+	current["description"] = "c"
+	if _, diags := client.checkConflicts(ctx, private, "role", base, planned, get); !diags.HasError() {
+		t.Errorf("expected a conflict on the description")
+	}
+}
@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	chefc "github.com/go-chef/chef"
+)
+
+func dataChefCookbook() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataChefCookbookRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"version": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"versions": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"latest": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"dependencies": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"frozen": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"artifact_identifiers": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataChefCookbookRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*chefClient)
+
+	name := d.Get("name").(string)
+
+	available, err := client.Cookbooks.GetAvailableVersions(name, "all")
+	if err != nil {
+		return chefSDKDiagnostics("Error reading cookbook versions", err, cty.GetAttrPath("name"))
+	}
+
+	versions := sortedCookbookVersions(available[name])
+	if len(versions) == 0 {
+		return diag.Diagnostics{
+			{
+				Severity:      diag.Error,
+				Summary:       "Cookbook has no versions",
+				Detail:        fmt.Sprintf("No versions of cookbook %q are available on the server", name),
+				AttributePath: cty.GetAttrPath("name"),
+			},
+		}
+	}
+
+	version := versions[0]
+	if v, ok := d.GetOk("version"); ok {
+		version = v.(string)
+	}
+
+	cookbook, err := client.Cookbooks.GetVersion(name, version)
+	if err != nil {
+		return chefSDKDiagnostics("Error reading cookbook version", err, cty.GetAttrPath("version"))
+	}
+
+	identifiers := []string{}
+	artifacts, err := client.CookbookArtifacts.Get(name)
+	if err != nil {
+		if !isNotFound(err) {
+			return chefSDKDiagnostics("Error reading cookbook artifacts", err, cty.GetAttrPath("name"))
+		}
+	}
+	for _, v := range artifacts[name].CBAVersions {
+		identifiers = append(identifiers, v.Identifier)
+	}
+
+	d.SetId(name + "@" + cookbook.Version)
+	d.Set("version", cookbook.Version)
+	d.Set("versions", versions)
+	d.Set("latest", versions[0])
+	d.Set("description", cookbook.Metadata.Description)
+	d.Set("dependencies", cookbook.Metadata.Depends)
+	d.Set("frozen", cookbook.Frozen)
+	d.Set("artifact_identifiers", identifiers)
+
+	return nil
+}
+
+// sortedCookbookVersions returns the versions of a cookbook newest first.
+// Versions that do not parse are kept, after all the valid ones.
+func sortedCookbookVersions(cookbook chefc.CookbookVersions) []string {
+	versions := make([]string, len(cookbook.Versions))
+	for i, v := range cookbook.Versions {
+		versions[i] = v.Version
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, erri := parseChefVersion(versions[i])
+		vj, errj := parseChefVersion(versions[j])
+		if erri != nil || errj != nil {
+			return erri == nil && errj != nil
+		}
+		return vi.compare(vj) > 0
+	})
+	return versions
+}
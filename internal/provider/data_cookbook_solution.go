@@ -0,0 +1,337 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	chefc "github.com/go-chef/chef"
+)
+
+func dataChefCookbookSolution() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataChefCookbookSolutionRead,
+
+		Schema: map[string]*schema.Schema{
+			"environment_name": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "_default",
+			},
+			"run_list": {
+				Type:     schema.TypeList,
+				Required: true,
+				Elem: &schema.Schema{
+					Type:      schema.TypeString,
+					StateFunc: runListEntryStateFunc,
+				},
+			},
+			"constraints": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"solution": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataChefCookbookSolutionRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*chefClient)
+
+	envName := d.Get("environment_name").(string)
+
+	runListI := d.Get("run_list").([]interface{})
+	runList := make([]string, len(runListI))
+	for i, vI := range runListI {
+		runList[i] = vI.(string)
+	}
+
+	env, err := client.Environments.Get(envName)
+	if err != nil {
+		return chefSDKDiagnostics("Error reading environment", err, cty.GetAttrPath("environment_name"))
+	}
+
+	var constraints []cookbookConstraint
+	for cookbook, constraint := range env.CookbookVersions {
+		c, err := parseChefVersionConstraint(constraint)
+		if err != nil {
+			return diag.Diagnostics{
+				{
+					Severity:      diag.Error,
+					Summary:       "Invalid cookbook constraint in environment",
+					Detail:        fmt.Sprintf("%s: %s", cookbook, err),
+					AttributePath: cty.GetAttrPath("environment_name"),
+				},
+			}
+		}
+		constraints = append(constraints, cookbookConstraint{
+			Cookbook:   cookbook,
+			Constraint: c,
+			Source:     fmt.Sprintf("environment %s", envName),
+		})
+	}
+
+	for cookbook, constraintI := range d.Get("constraints").(map[string]interface{}) {
+		c, err := parseChefVersionConstraint(constraintI.(string))
+		if err != nil {
+			return diag.Diagnostics{
+				{
+					Severity:      diag.Error,
+					Summary:       "Invalid cookbook constraint",
+					Detail:        fmt.Sprint(err),
+					AttributePath: cty.GetAttrPath("constraints").IndexString(cookbook),
+				},
+			}
+		}
+		constraints = append(constraints, cookbookConstraint{
+			Cookbook:   cookbook,
+			Constraint: c,
+			Source:     "constraints",
+		})
+	}
+
+	expansion := newRunListExpansion(envName, func(name string) (*chefc.Role, error) {
+		return client.Roles.Get(name)
+	})
+	if err := expansion.expand(runList); err != nil {
+		resp := diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Error expanding run list",
+			Detail:   fmt.Sprint(err),
+		}
+		if itemErr, ok := err.(*runListExpansionError); ok {
+			resp.AttributePath = cty.GetAttrPath("run_list").IndexInt(itemErr.Index)
+		}
+		return diag.Diagnostics{resp}
+	}
+
+	var required []string
+	for _, recipe := range expansion.Recipes {
+		cookbook := strings.SplitN(recipe, "::", 2)[0]
+		required = append(required, cookbook)
+		if version, ok := expansion.Versions[recipe]; ok {
+			c, err := parseChefVersionConstraint(version)
+			if err != nil {
+				return diag.Diagnostics{
+					{
+						Severity:      diag.Error,
+						Summary:       "Invalid recipe version in run list",
+						Detail:        fmt.Sprintf("recipe[%s@%s]: %s", recipe, version, err),
+						AttributePath: cty.GetAttrPath("run_list"),
+					},
+				}
+			}
+			constraints = append(constraints, cookbookConstraint{
+				Cookbook:   cookbook,
+				Constraint: c,
+				Source:     fmt.Sprintf("run list entry recipe[%s@%s]", recipe, version),
+			})
+		}
+	}
+
+	universe, err := client.Universe.Get()
+	if err != nil {
+		return chefSDKDiagnostics("Error reading cookbook universe", err, nil)
+	}
+
+	solution, err := newCookbookSolver(universe).solve(required, constraints)
+	if err != nil {
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "Unable to resolve cookbook dependencies",
+				Detail:   fmt.Sprint(err),
+			},
+		}
+	}
+
+	d.SetId(envName + ":" + strings.Join(runList, ","))
+	d.Set("solution", solution)
+
+	return nil
+}
+
+// cookbookConstraint is a version constraint on a cookbook together with a
+// human readable description of where it came from, so that an unsolvable
+// set of constraints can be explained.
+type cookbookConstraint struct {
+	Cookbook   string
+	Constraint chefVersionConstraint
+	Source     string
+}
+
+type cookbookCandidate struct {
+	Raw          string
+	Version      chefVersion
+	Dependencies map[string]string
+}
+
+// cookbookSolver performs a depth first search over the cookbook universe,
+// preferring the newest version of each cookbook, in the same spirit as the
+// depsolver used by the Chef server.
+type cookbookSolver struct {
+	candidates map[string][]cookbookCandidate
+	steps      int
+	failure    *cookbookSolverFailure
+}
+
+type cookbookSolverFailure struct {
+	depth int
+	err   error
+}
+
+const cookbookSolverMaxSteps = 100000
+
+func newCookbookSolver(universe chefc.Universe) *cookbookSolver {
+	s := &cookbookSolver{candidates: map[string][]cookbookCandidate{}}
+	for name, book := range universe.Books {
+		for raw, uv := range book.Versions {
+			v, err := parseChefVersion(raw)
+			if err != nil {
+				continue
+			}
+			s.candidates[name] = append(s.candidates[name], cookbookCandidate{
+				Raw:          raw,
+				Version:      v,
+				Dependencies: uv.Dependencies,
+			})
+		}
+		sort.Slice(s.candidates[name], func(i, j int) bool {
+			return s.candidates[name][i].Version.compare(s.candidates[name][j].Version) > 0
+		})
+	}
+	return s
+}
+
+func (s *cookbookSolver) solve(required []string, constraints []cookbookConstraint) (map[string]string, error) {
+	byCookbook := map[string][]cookbookConstraint{}
+	for _, c := range constraints {
+		byCookbook[c.Cookbook] = append(byCookbook[c.Cookbook], c)
+	}
+
+	assigned, ok := s.search(map[string]cookbookCandidate{}, required, byCookbook)
+	if !ok {
+		if s.failure == nil {
+			return nil, fmt.Errorf("no solution found")
+		}
+		return nil, s.failure.err
+	}
+
+	solution := make(map[string]string, len(assigned))
+	for name, candidate := range assigned {
+		solution[name] = candidate.Raw
+	}
+	return solution, nil
+}
+
+func (s *cookbookSolver) search(assigned map[string]cookbookCandidate, pending []string, constraints map[string][]cookbookConstraint) (map[string]cookbookCandidate, bool) {
+	if len(pending) == 0 {
+		return assigned, true
+	}
+
+	s.steps++
+	if s.steps > cookbookSolverMaxSteps {
+		s.fail(len(assigned), fmt.Errorf("gave up after trying %d combinations of cookbook versions", cookbookSolverMaxSteps))
+		return nil, false
+	}
+
+	name, rest := pending[0], pending[1:]
+	if _, ok := assigned[name]; ok {
+		return s.search(assigned, rest, constraints)
+	}
+
+	candidates, ok := s.candidates[name]
+	if !ok {
+		s.fail(len(assigned), fmt.Errorf("cookbook %q is not available on the server%s", name, describeConstraints(constraints[name])))
+		return nil, false
+	}
+
+Candidates:
+	for _, candidate := range candidates {
+		for _, c := range constraints[name] {
+			if !c.Constraint.satisfiedBy(candidate.Version) {
+				continue Candidates
+			}
+		}
+
+		nextConstraints := make(map[string][]cookbookConstraint, len(constraints))
+		for k, v := range constraints {
+			nextConstraints[k] = v
+		}
+		nextPending := append([]string{}, rest...)
+
+		deps := make([]string, 0, len(candidate.Dependencies))
+		for dep := range candidate.Dependencies {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+
+		for _, dep := range deps {
+			c, err := parseChefVersionConstraint(candidate.Dependencies[dep])
+			if err != nil {
+				s.fail(len(assigned), fmt.Errorf("cookbook %q %s has an invalid dependency on %q: %s", name, candidate.Raw, dep, err))
+				continue Candidates
+			}
+			depConstraint := cookbookConstraint{
+				Cookbook:   dep,
+				Constraint: c,
+				Source:     fmt.Sprintf("dependency of %s %s", name, candidate.Raw),
+			}
+			if existing, ok := assigned[dep]; ok && !c.satisfiedBy(existing.Version) {
+				s.fail(len(assigned), fmt.Errorf("cookbook %q %s is already selected%s", dep, existing.Raw,
+					describeConstraints(append(append([]cookbookConstraint{}, constraints[dep]...), depConstraint))))
+				continue Candidates
+			}
+			nextConstraints[dep] = append(append([]cookbookConstraint{}, constraints[dep]...), depConstraint)
+			nextPending = append(nextPending, dep)
+		}
+
+		nextAssigned := make(map[string]cookbookCandidate, len(assigned)+1)
+		for k, v := range assigned {
+			nextAssigned[k] = v
+		}
+		nextAssigned[name] = candidate
+
+		if result, ok := s.search(nextAssigned, nextPending, nextConstraints); ok {
+			return result, true
+		}
+	}
+
+	available := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		available[i] = candidate.Raw
+	}
+	s.fail(len(assigned), fmt.Errorf("no version of cookbook %q satisfies all constraints%s\navailable versions: %s",
+		name, describeConstraints(constraints[name]), strings.Join(available, ", ")))
+	return nil, false
+}
+
+// fail remembers the failure that happened deepest in the search, which is
+// usually the most useful one to report.
+func (s *cookbookSolver) fail(depth int, err error) {
+	if s.failure == nil || depth > s.failure.depth {
+		s.failure = &cookbookSolverFailure{depth: depth, err: err}
+	}
+}
+
+func describeConstraints(constraints []cookbookConstraint) string {
+	if len(constraints) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(":")
+	for _, c := range constraints {
+		fmt.Fprintf(&b, "\n  %s (%s)", c.Constraint, c.Source)
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	chefc "github.com/go-chef/chef"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestCookbookSolver(t *testing.T) {
+	universe := chefc.Universe{
+		Books: map[string]chefc.UniverseBook{
+			"apache2": {Versions: map[string]chefc.UniverseVersion{
+				"3.0.0": {Dependencies: map[string]string{"openssl": ">= 2.0.0"}},
+				"2.0.0": {Dependencies: map[string]string{"openssl": "~> 1.0"}},
+			}},
+			"openssl": {Versions: map[string]chefc.UniverseVersion{
+				"1.1.0": {},
+				"1.0.0": {},
+				"2.1.0": {},
+			}},
+			"app": {Versions: map[string]chefc.UniverseVersion{
+				"1.0.0": {Dependencies: map[string]string{"apache2": ">= 0.0.0", "openssl": "< 2.0"}},
+			}},
+			"legacy": {Versions: map[string]chefc.UniverseVersion{
+				"1.0.0": {Dependencies: map[string]string{"openssl": "~> one"}},
+			}},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		required    []string
+		constraints []cookbookConstraint
+		solution    map[string]string
+		err         string
+	}{
+		{
+			name:     "newest versions",
+			required: []string{"apache2"},
+			solution: map[string]string{"apache2": "3.0.0", "openssl": "2.1.0"},
+		},
+		{
+			name:     "backtracks through dependencies",
+			required: []string{"app"},
+			solution: map[string]string{"app": "1.0.0", "apache2": "2.0.0", "openssl": "1.1.0"},
+		},
+		{
+			name:     "environment constraint",
+			required: []string{"apache2"},
+			constraints: []cookbookConstraint{
+				{Cookbook: "openssl", Constraint: chefVersionConstraint{Op: "=", Version: chefVersion{1, 0, 0, 3}}, Source: "environment production"},
+			},
+			solution: map[string]string{"apache2": "2.0.0", "openssl": "1.0.0"},
+		},
+		{
+			name:     "conflict",
+			required: []string{"apache2"},
+			constraints: []cookbookConstraint{
+				{Cookbook: "apache2", Constraint: chefVersionConstraint{Op: ">=", Version: chefVersion{3, 0, 0, 3}}, Source: "constraints"},
+				{Cookbook: "openssl", Constraint: chefVersionConstraint{Op: "<", Version: chefVersion{2, 0, 0, 3}}, Source: "environment production"},
+			},
+			err: "no version of cookbook \"openssl\" satisfies all constraints:\n  < 2.0.0 (environment production)\n  >= 2.0.0 (dependency of apache2 3.0.0)",
+		},
+		{
+			name:     "invalid dependency constraint",
+			required: []string{"legacy"},
+			err:      "cookbook \"legacy\" 1.0.0 has an invalid dependency on \"openssl\": \"~> one\" is not a valid version constraint",
+		},
+		{
+			name:     "missing cookbook",
+			required: []string{"nginx"},
+			err:      "cookbook \"nginx\" is not available on the server",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			solution, err := newCookbookSolver(universe).solve(tc.required, tc.constraints)
+			if tc.err != "" {
+				if err == nil {
+					t.Fatalf("expected error, got solution %#v", solution)
+				}
+				if !strings.HasPrefix(err.Error(), tc.err) {
+					t.Fatalf("wrong error; expected prefix %q, got %q", tc.err, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(solution, tc.solution) {
+				t.Fatalf("wrong solution; expected %#v, got %#v", tc.solution, solution)
+			}
+		})
+	}
+}
+
+func TestChefVersionConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		satisfied  bool
+	}{
+		{"= 1.0.0", "1.0.0", true},
+		{"1.0", "1.0.0", true},
+		{"= 1.0.0", "1.0.1", false},
+		{">= 1.2", "1.10.0", true},
+		{"> 1.2.3", "1.2.3", false},
+		{"< 2.0", "1.99.99", true},
+		{"<= 2.0", "2.0.1", false},
+		{"~> 1.2", "1.9.0", true},
+		{"~> 1.2", "2.0.0", false},
+		{"~> 1.2.3", "1.2.9", true},
+		{"~> 1.2.3", "1.3.0", false},
+		{"~>1.2.3", "1.2.2", false},
+	}
+
+	for _, tc := range cases {
+		c, err := parseChefVersionConstraint(tc.constraint)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tc.constraint, err)
+		}
+		v, err := parseChefVersion(tc.version)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tc.version, err)
+		}
+		if got := c.satisfiedBy(v); got != tc.satisfied {
+			t.Errorf("%q satisfied by %q: expected %v, got %v", tc.constraint, tc.version, tc.satisfied, got)
+		}
+	}
+
+	for _, invalid := range []string{"", "~> 1", "=> 1.0", "1.0.0.0", "latest"} {
+		if _, err := parseChefVersionConstraint(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestAccDataCookbookSolution_missing(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testSuffixRender(testAccDataCookbookSolutionConfig_missing),
+				ExpectError: regexp.MustCompile(`cookbook "terraform-acc-test-missing-[0-9a-f]+" is not available on the server`),
+			},
+		},
+	})
+}
+
+const testAccDataCookbookSolutionConfig_missing = `
+resource "chef_environment" "test" {
+  name = "terraform-acc-test-solution-{{.}}"
+}
+
+data "chef_cookbook_solution" "test" {
+  environment_name = chef_environment.test.name
+  run_list         = ["recipe[terraform-acc-test-missing-{{.}}]"]
+  constraints = {
+    "terraform-acc-test-missing-{{.}}" = "~> 1.0"
+  }
+}
+`
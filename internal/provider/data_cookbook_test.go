@@ -0,0 +1,264 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	chefc "github.com/go-chef/chef"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestSortedCookbookVersions(t *testing.T) {
+	cookbook := chefc.CookbookVersions{
+		Versions: []chefc.CookbookVersion{
+			{Version: "1.2.0"},
+			{Version: "1.10.0"},
+			{Version: "bogus"},
+			{Version: "1.9"},
+			{Version: "0.1.0"},
+		},
+	}
+
+	expected := []string{"1.10.0", "1.9", "1.2.0", "0.1.0", "bogus"}
+	if got := sortedCookbookVersions(cookbook); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("wrong order; expected %#v, got %#v", expected, got)
+	}
+}
+
+// testAccUploadCookbook uploads every version of a cookbook in
+// testdata/cookbooks under the given name, the way knife does: the files are
+// uploaded through a sandbox before the manifests that refer to them. The
+// versions listed in frozen are frozen. The versions are deleted when the
+// test ends.
+func testAccUploadCookbook(t *testing.T, client *chefClient, fixture, name string, frozen ...string) {
+	t.Helper()
+
+	dir := filepath.Join("testdata", "cookbooks", fixture)
+	versions, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range versions {
+		root := filepath.Join(dir, version.Name())
+		files := map[string][]byte{}
+		var manifest []map[string]interface{}
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			digest := md5.Sum(content)
+			sum := hex.EncodeToString(digest[:])
+			files[sum] = content
+			manifest = append(manifest, map[string]interface{}{
+				"name":        filepath.ToSlash(rel),
+				"path":        filepath.ToSlash(rel),
+				"checksum":    sum,
+				"specificity": "default",
+			})
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(files[testAccManifestChecksum(manifest, "metadata.json")], &metadata); err != nil {
+			t.Fatal(err)
+		}
+		metadata["name"] = name
+
+		sums := make([]string, 0, len(files))
+		for sum := range files {
+			sums = append(sums, sum)
+		}
+		box, err := client.Sandboxes.Post(sums)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for sum, item := range box.Checksums {
+			if !item.Upload {
+				continue
+			}
+			req, err := client.NewRequest(http.MethodPut, item.Url, bytes.NewReader(files[sum]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			digest := md5.Sum(files[sum])
+			req.Header.Set("Content-Type", "application/x-binary")
+			req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(digest[:]))
+			if _, err := client.Do(req, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := client.Sandboxes.Put(box.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := chefc.JSONReader(map[string]interface{}{
+			"cookbook_name": name,
+			"name":          name + "-" + version.Name(),
+			"version":       version.Name(),
+			"json_class":    "Chef::CookbookVersion",
+			"chef_type":     "cookbook_version",
+			"frozen?":       slices.Contains(frozen, version.Name()),
+			"metadata":      metadata,
+			"all_files":     manifest,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := client.NewRequest(http.MethodPut, "cookbooks/"+name+"/"+version.Name(), body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Do(req, nil); err != nil {
+			t.Fatal(err)
+		}
+		v := version.Name()
+		t.Cleanup(func() { client.Cookbooks.Delete(name, v) })
+	}
+}
+
+func testAccManifestChecksum(manifest []map[string]interface{}, name string) string {
+	for _, file := range manifest {
+		if file["name"] == name {
+			return file["checksum"].(string)
+		}
+	}
+	return ""
+}
+
+func TestAccDataCookbook_basic(t *testing.T) {
+	name := "terraform-acc-test-cookbook-" + testSuffix
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+
+			client, err := testAccClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			testAccUploadCookbook(t, client, "fixture", name, "1.0.0")
+		},
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccDataCookbookConfig_basic),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "version", "1.2.0"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "latest", "1.2.0"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "versions.#", "2"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "versions.0", "1.2.0"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "versions.1", "1.0.0"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "description", "Installs and configures the fixture service"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "dependencies.%", "2"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "dependencies.apt", "~> 7.4"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "dependencies.yum", ">= 0.0.0"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.latest", "frozen", "false"),
+
+					resource.TestCheckResourceAttr("data.chef_cookbook.pinned", "version", "1.0.0"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.pinned", "latest", "1.2.0"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.pinned", "description", "Installs the fixture service"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.pinned", "dependencies.%", "1"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.pinned", "dependencies.apt", ">= 7.0.0"),
+					resource.TestCheckResourceAttr("data.chef_cookbook.pinned", "frozen", "true"),
+
+					resource.TestCheckTypeSetElemNestedAttrs("data.chef_cookbook_versions.all", "cookbooks.*", map[string]string{
+						"name":       name,
+						"latest":     "1.2.0",
+						"versions.#": "2",
+						"versions.0": "1.2.0",
+						"versions.1": "1.0.0",
+					}),
+					resource.TestCheckTypeSetElemNestedAttrs("data.chef_cookbook_versions.environment", "cookbooks.*", map[string]string{
+						"name":       name,
+						"latest":     "1.0.0",
+						"versions.#": "1",
+						"versions.0": "1.0.0",
+					}),
+
+					resource.TestCheckTypeSetElemAttr("data.chef_recipes.all", "recipes.*", name),
+					resource.TestCheckTypeSetElemAttr("data.chef_recipes.all", "recipes.*", name+"::server"),
+					resource.TestCheckTypeSetElemAttr("data.chef_recipes.environment", "recipes.*", name),
+					func(s *terraform.State) error {
+						// The constraint pins the version without the server
+						// recipe.
+						attrs := s.RootModule().Resources["data.chef_recipes.environment"].Primary.Attributes
+						for key, value := range attrs {
+							if strings.HasPrefix(key, "recipes.") && value == name+"::server" {
+								return fmt.Errorf("expected %s::server not to be available in the environment", name)
+							}
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config:      testSuffixRender(testAccDataCookbookConfig_missing),
+				ExpectError: regexp.MustCompile("Error reading cookbook versions"),
+			},
+		},
+	})
+}
+
+const testAccDataCookbookConfig_basic = `
+resource "chef_environment" "test" {
+  name = "terraform-acc-test-cookbook-{{.}}"
+  cookbook_constraints = {
+    "terraform-acc-test-cookbook-{{.}}" = "~> 1.0.0"
+  }
+}
+
+data "chef_cookbook" "latest" {
+  name = "terraform-acc-test-cookbook-{{.}}"
+}
+
+data "chef_cookbook" "pinned" {
+  name    = "terraform-acc-test-cookbook-{{.}}"
+  version = "1.0.0"
+}
+
+data "chef_cookbook_versions" "all" {
+}
+
+data "chef_cookbook_versions" "environment" {
+  environment_name = chef_environment.test.name
+}
+
+data "chef_recipes" "all" {
+}
+
+data "chef_recipes" "environment" {
+  environment_name = chef_environment.test.name
+}
+`
+
+const testAccDataCookbookConfig_missing = `
+data "chef_cookbook" "test" {
+  name = "terraform-acc-test-missing-{{.}}"
+}
+`
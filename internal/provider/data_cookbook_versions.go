@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	chefc "github.com/go-chef/chef"
+)
+
+func dataChefCookbookVersions() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataChefCookbookVersionsRead,
+
+		Schema: map[string]*schema.Schema{
+			"environment_name": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"cookbooks": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"latest": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"versions": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataChefCookbookVersionsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*chefClient)
+
+	var result chefc.CookbookListResult
+	var err error
+	envName, scoped := d.GetOk("environment_name")
+	if scoped {
+		var envResult chefc.EnvironmentCookbookResult
+		envResult, err = client.Environments.ListCookbooks(envName.(string), "all")
+		result = chefc.CookbookListResult(envResult)
+	} else {
+		result, err = client.Cookbooks.ListAvailableVersions("all")
+	}
+	if err != nil {
+		var attribute cty.Path
+		if scoped {
+			attribute = cty.GetAttrPath("environment_name")
+		}
+		return chefSDKDiagnostics("Error listing cookbook versions", err, attribute)
+	}
+
+	names := make([]string, 0, len(result))
+	for name := range result {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cookbooks := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		versions := sortedCookbookVersions(result[name])
+		latest := ""
+		if len(versions) > 0 {
+			latest = versions[0]
+		}
+		cookbooks = append(cookbooks, map[string]interface{}{
+			"name":     name,
+			"latest":   latest,
+			"versions": versions,
+		})
+	}
+
+	if scoped {
+		d.SetId(envName.(string))
+	} else {
+		d.SetId("_all")
+	}
+	d.Set("cookbooks", cookbooks)
+
+	return nil
+}
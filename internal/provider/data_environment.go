@@ -1,38 +1,97 @@
 package provider
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataChefEnvironment() *schema.Resource {
-	return &schema.Resource{
-		ReadContext: ReadEnvironment,
+var _ datasource.DataSource = &environmentDataSource{}
 
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:     schema.TypeString,
-				Required: true,
-			},
-			"description": {
-				Type:     schema.TypeString,
-				Computed: true,
-			},
-			"default_attributes_json": {
-				Type:     schema.TypeString,
-				Computed: true,
-			},
-			"override_attributes_json": {
-				Type:     schema.TypeString,
+func NewEnvironmentDataSource() datasource.DataSource {
+	return &environmentDataSource{}
+}
+
+type environmentDataSource struct {
+	frameworkDataSource
+}
+
+type environmentDataSourceModel struct {
+	ID                     types.String  `tfsdk:"id"`
+	Name                   types.String  `tfsdk:"name"`
+	Description            types.String  `tfsdk:"description"`
+	DefaultAttributesJson  types.String  `tfsdk:"default_attributes_json"`
+	DefaultAttributes      types.Dynamic `tfsdk:"default_attributes"`
+	OverrideAttributesJson types.String  `tfsdk:"override_attributes_json"`
+	OverrideAttributes     types.Dynamic `tfsdk:"override_attributes"`
+	CookbookConstraints    types.Map     `tfsdk:"cookbook_constraints"`
+	Json                   types.String  `tfsdk:"json"`
+}
+
+func (d *environmentDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_environment"
+}
+
+func (d *environmentDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
 				Computed: true,
 			},
-			"cookbook_constraints": {
-				Type:     schema.TypeMap,
-				Computed: true,
+			"name": schema.StringAttribute{
+				Required: true,
 			},
-			"json": {
-				Type:     schema.TypeString,
-				Computed: true,
+			"description":              schema.StringAttribute{Computed: true},
+			"default_attributes_json":  schema.StringAttribute{Computed: true},
+			"default_attributes":       schema.DynamicAttribute{Computed: true},
+			"override_attributes_json": schema.StringAttribute{Computed: true},
+			"override_attributes":      schema.DynamicAttribute{Computed: true},
+			"cookbook_constraints": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
 			},
+			"json": schema.StringAttribute{Computed: true},
 		},
 	}
 }
+
+func (d *environmentDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if !requireClient(d.client, &resp.Diagnostics) {
+		return
+	}
+
+	var data environmentDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	env, err := d.client.Environments.Get(data.Name.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error reading environment", err, path.Root("name")))
+		return
+	}
+
+	m := environmentResourceModel{
+		DefaultAttributesJson:  types.StringNull(),
+		OverrideAttributesJson: types.StringNull(),
+		CookbookConstraints:    types.MapUnknown(types.StringType),
+	}
+	resp.Diagnostics.Append(environmentToModel(ctx, env, &m, defaultAttributes{})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.ID = m.ID
+	data.Description = m.Description
+	data.DefaultAttributesJson = m.DefaultAttributesJson
+	data.DefaultAttributes = m.DefaultAttributes
+	data.OverrideAttributesJson = m.OverrideAttributesJson
+	data.OverrideAttributes = m.OverrideAttributes
+	data.CookbookConstraints = m.CookbookConstraints
+	data.Json = m.Json
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -15,9 +15,9 @@ func TestAccDataEnvironment_basic(t *testing.T) {
 	var env chefc.Environment
 
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccEnvironmentCheckDestroy(&env),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccEnvironmentCheckDestroy(&env),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccDataEnvironmentConfig_basic),
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	chefc "github.com/go-chef/chef"
+)
+
+func dataChefExpandedRunList() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataChefExpandedRunListRead,
+
+		Schema: map[string]*schema.Schema{
+			"run_list": {
+				Type:     schema.TypeList,
+				Required: true,
+				Elem: &schema.Schema{
+					Type:      schema.TypeString,
+					StateFunc: runListEntryStateFunc,
+				},
+			},
+			"environment_name": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "_default",
+			},
+			"recipes": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"recipe_versions": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"roles": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataChefExpandedRunListRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*chefClient)
+
+	environment := d.Get("environment_name").(string)
+
+	runListI := d.Get("run_list").([]interface{})
+	runList := make([]string, len(runListI))
+	for i, vI := range runListI {
+		runList[i] = vI.(string)
+	}
+
+	expansion := newRunListExpansion(environment, func(name string) (*chefc.Role, error) {
+		return client.Roles.Get(name)
+	})
+	if err := expansion.expand(runList); err != nil {
+		resp := diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Error expanding run list",
+			Detail:   fmt.Sprint(err),
+		}
+		if itemErr, ok := err.(*runListExpansionError); ok {
+			resp.AttributePath = cty.GetAttrPath("run_list").IndexInt(itemErr.Index)
+		}
+		return diag.Diagnostics{resp}
+	}
+
+	d.SetId(environment + ":" + strings.Join(runList, ","))
+	d.Set("recipes", expansion.Recipes)
+	d.Set("recipe_versions", expansion.Versions)
+	d.Set("roles", expansion.Roles)
+
+	return nil
+}
+
+// runListExpansion resolves a run list into the flat list of recipes that
+// chef-client would converge, following the same rules as
+// Chef::RunList::RunListExpansion: roles are expanded depth first using
+// their environment specific run list when one exists, a role is only
+// applied once, and a recipe keeps the position of its first occurrence.
+type runListExpansion struct {
+	Environment string
+	Recipes     []string
+	Versions    map[string]string
+	Roles       []string
+
+	getRole func(name string) (*chefc.Role, error)
+	applied map[string]bool
+	stack   []string
+}
+
+// runListExpansionError records which entry of the top-level run list led
+// to a failure so that diagnostics can point at it.
+type runListExpansionError struct {
+	Index int
+	Err   error
+}
+
+func (e *runListExpansionError) Error() string {
+	return e.Err.Error()
+}
+
+func newRunListExpansion(environment string, getRole func(name string) (*chefc.Role, error)) *runListExpansion {
+	return &runListExpansion{
+		Environment: environment,
+		Recipes:     []string{},
+		Versions:    map[string]string{},
+		Roles:       []string{},
+		getRole:     getRole,
+		applied:     map[string]bool{},
+	}
+}
+
+func (e *runListExpansion) expand(runList []string) error {
+	for i, entry := range runList {
+		if err := e.expandEntry(entry); err != nil {
+			return &runListExpansionError{Index: i, Err: err}
+		}
+	}
+	return nil
+}
+
+func (e *runListExpansion) expandEntry(entry string) error {
+	item, err := chefc.NewRunListItem(entry)
+	if err != nil {
+		return err
+	}
+
+	if item.IsRecipe() {
+		return e.addRecipe(item.Name, item.Version)
+	}
+
+	for _, name := range e.stack {
+		if name == item.Name {
+			return fmt.Errorf("role cycle detected: %s -> %s", strings.Join(e.stack, " -> "), item.Name)
+		}
+	}
+	if e.applied[item.Name] {
+		return nil
+	}
+
+	role, err := e.getRole(item.Name)
+	if err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("role[%s] does not exist", item.Name)
+		}
+		return fmt.Errorf("reading role[%s]: %s", item.Name, chefErrorDetail(err))
+	}
+
+	e.applied[item.Name] = true
+	e.Roles = append(e.Roles, item.Name)
+
+	runList := role.RunList
+	if envRunList, ok := role.EnvRunList[e.Environment]; ok {
+		runList = envRunList
+	}
+
+	e.stack = append(e.stack, item.Name)
+	for _, roleEntry := range runList {
+		if err := e.expandEntry(roleEntry); err != nil {
+			return err
+		}
+	}
+	e.stack = e.stack[:len(e.stack)-1]
+
+	return nil
+}
+
+func (e *runListExpansion) addRecipe(name, version string) error {
+	if version != "" {
+		if existing, ok := e.Versions[name]; ok && existing != version {
+			return fmt.Errorf("recipe[%s] is pinned to both %s and %s", name, existing, version)
+		}
+		e.Versions[name] = version
+	}
+
+	for _, recipe := range e.Recipes {
+		if recipe == name {
+			return nil
+		}
+	}
+	e.Recipes = append(e.Recipes, name)
+
+	return nil
+}
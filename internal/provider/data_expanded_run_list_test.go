@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"testing"
+
+	chefc "github.com/go-chef/chef"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestRunListExpansion(t *testing.T) {
+	roles := map[string]*chefc.Role{
+		"base": {
+			Name:    "base",
+			RunList: chefc.RunList{"recipe[ntp]", "recipe[users::sysadmins]"},
+		},
+		"web": {
+			Name:    "web",
+			RunList: chefc.RunList{"role[base]", "recipe[nginx]"},
+			EnvRunList: chefc.EnvRunList{
+				"production": chefc.RunList{"role[base]", "recipe[nginx@1.2.0]", "recipe[monitoring]"},
+			},
+		},
+		"app": {
+			Name:    "app",
+			RunList: chefc.RunList{"role[base]", "role[web]", "recipe[app]", "recipe[ntp]"},
+		},
+		"loop-a": {
+			Name:    "loop-a",
+			RunList: chefc.RunList{"role[loop-b]"},
+		},
+		"loop-b": {
+			Name:    "loop-b",
+			RunList: chefc.RunList{"recipe[ntp]", "role[loop-a]"},
+		},
+	}
+	getRole := func(name string) (*chefc.Role, error) {
+		if role, ok := roles[name]; ok {
+			return role, nil
+		}
+		return nil, &chefc.ErrorResponse{Response: &http.Response{StatusCode: 404}}
+	}
+
+	cases := []struct {
+		name        string
+		environment string
+		runList     []string
+		recipes     []string
+		versions    map[string]string
+		roles       []string
+		err         string
+		errIndex    int
+	}{
+		{
+			name:        "default environment",
+			environment: "_default",
+			runList:     []string{"role[app]", "recipe[app]", "extra"},
+			recipes:     []string{"ntp", "users::sysadmins", "nginx", "app", "extra"},
+			versions:    map[string]string{},
+			roles:       []string{"app", "base", "web"},
+		},
+		{
+			name:        "environment run list",
+			environment: "production",
+			runList:     []string{"role[web]", "recipe[nginx@1.2.0]"},
+			recipes:     []string{"ntp", "users::sysadmins", "nginx", "monitoring"},
+			versions:    map[string]string{"nginx": "1.2.0"},
+			roles:       []string{"web", "base"},
+		},
+		{
+			name:        "conflicting versions",
+			environment: "production",
+			runList:     []string{"recipe[ntp]", "role[web]", "recipe[nginx@2.0.0]"},
+			err:         "recipe[nginx] is pinned to both 1.2.0 and 2.0.0",
+			errIndex:    2,
+		},
+		{
+			name:        "role cycle",
+			environment: "_default",
+			runList:     []string{"role[loop-a]"},
+			err:         "role cycle detected: loop-a -> loop-b -> loop-a",
+		},
+		{
+			name:        "missing role",
+			environment: "_default",
+			runList:     []string{"recipe[ntp]", "role[missing]"},
+			err:         "role[missing] does not exist",
+			errIndex:    1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			expansion := newRunListExpansion(tc.environment, getRole)
+			err := expansion.expand(tc.runList)
+			if tc.err != "" {
+				itemErr, ok := err.(*runListExpansionError)
+				if !ok {
+					t.Fatalf("expected a run list expansion error, got %#v", err)
+				}
+				if itemErr.Error() != tc.err {
+					t.Fatalf("wrong error; expected %q, got %q", tc.err, itemErr.Error())
+				}
+				if itemErr.Index != tc.errIndex {
+					t.Fatalf("wrong error index; expected %d, got %d", tc.errIndex, itemErr.Index)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(expansion.Recipes, tc.recipes) {
+				t.Errorf("wrong recipes; expected %#v, got %#v", tc.recipes, expansion.Recipes)
+			}
+			if !reflect.DeepEqual(expansion.Versions, tc.versions) {
+				t.Errorf("wrong versions; expected %#v, got %#v", tc.versions, expansion.Versions)
+			}
+			if !reflect.DeepEqual(expansion.Roles, tc.roles) {
+				t.Errorf("wrong roles; expected %#v, got %#v", tc.roles, expansion.Roles)
+			}
+		})
+	}
+}
+
+func TestAccDataExpandedRunList_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccDataExpandedRunListConfig_basic),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.chef_expanded_run_list.test", "recipes.#", "3"),
+					resource.TestCheckResourceAttr("data.chef_expanded_run_list.test", "recipes.0", "base"),
+					resource.TestCheckResourceAttr("data.chef_expanded_run_list.test", "recipes.1", "web"),
+					resource.TestCheckResourceAttr("data.chef_expanded_run_list.test", "recipes.2", "extra"),
+					resource.TestCheckResourceAttr("data.chef_expanded_run_list.test", "recipe_versions.web", "1.0.0"),
+					resource.TestCheckResourceAttr("data.chef_expanded_run_list.test", "roles.#", "2"),
+					resource.TestCheckResourceAttr("data.chef_expanded_run_list.test", "roles.0", "terraform-acc-test-web-"+testSuffix),
+					resource.TestCheckResourceAttr("data.chef_expanded_run_list.test", "roles.1", "terraform-acc-test-base-"+testSuffix),
+				),
+			},
+			{
+				Config:      testSuffixRender(testAccDataExpandedRunListConfig_missing),
+				ExpectError: regexp.MustCompile(fmt.Sprintf("role\\[terraform-acc-test-missing-%s\\] does not exist", testSuffix)),
+			},
+		},
+	})
+}
+
+const testAccDataExpandedRunListConfig_basic = `
+resource "chef_role" "base" {
+  name     = "terraform-acc-test-base-{{.}}"
+  run_list = ["recipe[base]"]
+}
+
+resource "chef_role" "web" {
+  name     = "terraform-acc-test-web-{{.}}"
+  run_list = ["role[${chef_role.base.name}]", "recipe[web]"]
+  env_run_list_json = jsonencode({
+    "terraform-acc-test-{{.}}" = ["role[${chef_role.base.name}]", "recipe[web@1.0.0]"]
+  })
+}
+
+data "chef_expanded_run_list" "test" {
+  environment_name = "terraform-acc-test-{{.}}"
+  run_list         = ["role[${chef_role.web.name}]", "extra", "recipe[base]"]
+}
+`
+
+const testAccDataExpandedRunListConfig_missing = `
+data "chef_expanded_run_list" "test" {
+  run_list = ["role[terraform-acc-test-missing-{{.}}]"]
+}
+`
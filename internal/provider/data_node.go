@@ -1,46 +1,122 @@
 package provider
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataChefNode() *schema.Resource {
-	return &schema.Resource{
-		ReadContext: ReadNode,
+var _ datasource.DataSource = &nodeDataSource{}
 
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:     schema.TypeString,
-				Required: true,
-			},
-			"environment_name": {
-				Type:     schema.TypeString,
-				Computed: true,
-			},
-			"automatic_attributes_json": {
-				Type:     schema.TypeString,
-				Computed: true,
-			},
-			"normal_attributes_json": {
-				Type:     schema.TypeString,
+func NewNodeDataSource() datasource.DataSource {
+	return &nodeDataSource{}
+}
+
+type nodeDataSource struct {
+	frameworkDataSource
+}
+
+type nodeDataSourceModel struct {
+	ID                      types.String  `tfsdk:"id"`
+	Name                    types.String  `tfsdk:"name"`
+	EnvironmentName         types.String  `tfsdk:"environment_name"`
+	AutomaticAttributesJson types.String  `tfsdk:"automatic_attributes_json"`
+	AutomaticAttributes     types.Dynamic `tfsdk:"automatic_attributes"`
+	NormalAttributesJson    types.String  `tfsdk:"normal_attributes_json"`
+	NormalAttributes        types.Dynamic `tfsdk:"normal_attributes"`
+	DefaultAttributesJson   types.String  `tfsdk:"default_attributes_json"`
+	DefaultAttributes       types.Dynamic `tfsdk:"default_attributes"`
+	OverrideAttributesJson  types.String  `tfsdk:"override_attributes_json"`
+	OverrideAttributes      types.Dynamic `tfsdk:"override_attributes"`
+	RunList                 types.List    `tfsdk:"run_list"`
+	Tags                    types.Set     `tfsdk:"tags"`
+	PolicyName              types.String  `tfsdk:"policy_name"`
+	PolicyGroup             types.String  `tfsdk:"policy_group"`
+}
+
+func (d *nodeDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_node"
+}
+
+func (d *nodeDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
 				Computed: true,
 			},
-			"default_attributes_json": {
-				Type:     schema.TypeString,
-				Computed: true,
+			"name": schema.StringAttribute{
+				Required: true,
 			},
-			"override_attributes_json": {
-				Type:     schema.TypeString,
-				Computed: true,
+			"environment_name":          schema.StringAttribute{Computed: true},
+			"automatic_attributes_json": schema.StringAttribute{Computed: true},
+			"automatic_attributes":      schema.DynamicAttribute{Computed: true},
+			"normal_attributes_json":    schema.StringAttribute{Computed: true},
+			"normal_attributes":         schema.DynamicAttribute{Computed: true},
+			"default_attributes_json":   schema.StringAttribute{Computed: true},
+			"default_attributes":        schema.DynamicAttribute{Computed: true},
+			"override_attributes_json":  schema.StringAttribute{Computed: true},
+			"override_attributes":       schema.DynamicAttribute{Computed: true},
+			"run_list": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
 			},
-			"run_list": {
-				Type:     schema.TypeList,
-				Computed: true,
-				Elem: &schema.Schema{
-					Type:      schema.TypeString,
-					StateFunc: runListEntryStateFunc,
-				},
+			"tags": schema.SetAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
 			},
+			"policy_name":  schema.StringAttribute{Computed: true},
+			"policy_group": schema.StringAttribute{Computed: true},
 		},
 	}
 }
+
+func (d *nodeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if !requireClient(d.client, &resp.Diagnostics) {
+		return
+	}
+
+	var data nodeDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	node, err := d.client.Nodes.Get(data.Name.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error reading node", err, path.Root("name")))
+		return
+	}
+
+	m := nodeResourceModel{
+		AutomaticAttributesJson: types.StringNull(),
+		NormalAttributesJson:    types.StringNull(),
+		DefaultAttributesJson:   types.StringNull(),
+		OverrideAttributesJson:  types.StringNull(),
+		RunList:                 types.ListUnknown(types.StringType),
+	}
+	resp.Diagnostics.Append(nodeToModel(ctx, &node, &m, defaultAttributes{})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.ID = m.ID
+	data.EnvironmentName = m.EnvironmentName
+	data.AutomaticAttributesJson = m.AutomaticAttributesJson
+	data.AutomaticAttributes = m.AutomaticAttributes
+	data.NormalAttributesJson = m.NormalAttributesJson
+	data.NormalAttributes = m.NormalAttributes
+	data.DefaultAttributesJson = m.DefaultAttributesJson
+	data.DefaultAttributes = m.DefaultAttributes
+	data.OverrideAttributesJson = m.OverrideAttributesJson
+	data.OverrideAttributes = m.OverrideAttributes
+	data.RunList = m.RunList
+	tags, diags := types.SetValueFrom(ctx, types.StringType, nodeTags(node.NormalAttributes))
+	resp.Diagnostics.Append(diags...)
+	data.Tags = tags
+	data.PolicyName = m.PolicyName
+	data.PolicyGroup = m.PolicyGroup
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -15,9 +15,9 @@ func TestAccDataNode_basic(t *testing.T) {
 	var node chefc.Node
 
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccNodeCheckDestroy(&node),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccNodeCheckDestroy(&node),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccDataNodeConfig_basic),
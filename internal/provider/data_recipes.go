@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataChefRecipes() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataChefRecipesRead,
+
+		Schema: map[string]*schema.Schema{
+			"environment_name": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"recipes": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataChefRecipesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*chefClient)
+
+	// Without an environment, list the recipes of the latest version of
+	// every cookbook, which is what the Chef server considers available
+	// when no cookbook constraints apply.
+	var recipes []string
+	if envName, ok := d.GetOk("environment_name"); ok {
+		result, err := client.Environments.ListRecipes(envName.(string))
+		if err != nil {
+			return chefSDKDiagnostics("Error listing environment recipes", err, cty.GetAttrPath("environment_name"))
+		}
+		recipes = result
+		d.SetId(envName.(string))
+	} else {
+		result, err := client.Cookbooks.ListAllRecipes()
+		if err != nil {
+			return chefSDKDiagnostics("Error listing recipes", err, nil)
+		}
+		recipes = result
+		d.SetId("_all")
+	}
+
+	if recipes == nil {
+		recipes = []string{}
+	}
+	d.Set("recipes", recipes)
+
+	return nil
+}
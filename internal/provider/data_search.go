@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 
@@ -54,6 +55,12 @@ func dataChefSearch() *schema.Resource {
 					Type: schema.TypeString,
 				},
 			},
+			"tags": &schema.Schema{
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Tags of the node found, for searches of the `node` index.",
+			},
 			"total_num": &schema.Schema{
 				Type:     schema.TypeInt,
 				Computed: true,
@@ -63,7 +70,7 @@ func dataChefSearch() *schema.Resource {
 }
 
 func dataChefSearchRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefc.Client)
+	client := meta.(*chefClient).Client
 
 	query, err := client.Search.NewQuery(d.Get("index").(string), d.Get("query").(string))
 	if err != nil {
@@ -77,6 +84,10 @@ func dataChefSearchRead(ctx context.Context, d *schema.ResourceData, meta interf
 	}
 	query.Rows = 1
 
+	// The tags of nodes are fetched along with the filter, unless the filter
+	// already has a result named tags.
+	fetchTags := d.Get("index").(string) == "node"
+
 	filter, ok := d.Get("filter").(*schema.Set)
 	var res chefc.SearchResult
 	if ok {
@@ -85,18 +96,18 @@ func dataChefSearchRead(ctx context.Context, d *schema.ResourceData, meta interf
 			m := v.(map[string]interface{})
 			params[m["name"].(string)] = m["value"].([]interface{})
 		}
+		if _, ok := params["tags"]; ok {
+			fetchTags = false
+		}
+		if fetchTags {
+			params["tags"] = []interface{}{"tags"}
+		}
 		res, err = query.DoPartial(client, params)
 	} else {
 		res, err = query.Do(client)
 	}
 	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error executing search",
-				Detail:   fmt.Sprint(err),
-			},
-		}
+		return chefSDKDiagnostics("Error executing search", err, cty.GetAttrPath("index"))
 	}
 
 	log.Printf("Chef search result: %+v\n", res)
@@ -119,7 +130,12 @@ func dataChefSearchRead(ctx context.Context, d *schema.ResourceData, meta interf
 		if !ok {
 			data = row["raw_data"]
 		}
-		for k, v := range data.(map[string]interface{}) {
+		fields := data.(map[string]interface{})
+		if fetchTags {
+			d.Set("tags", nodeTags(fields))
+			delete(fields, "tags")
+		}
+		for k, v := range fields {
 			switch t := v.(type) {
 			case string:
 				result[k] = t
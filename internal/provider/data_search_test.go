@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSearch_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccDataSearchConfig_basic),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.chef_search.test", "total_num", "1"),
+					resource.TestCheckResourceAttr("data.chef_search.test", "result.environment", "terraform-acc-test-search-"+testSuffix),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSearchConfig_basic = `
+resource "chef_environment" "test" {
+  name = "terraform-acc-test-search-{{.}}"
+}
+
+resource "chef_node" "test" {
+  name             = "terraform-acc-test-search-{{.}}"
+  environment_name = chef_environment.test.name
+}
+
+data "chef_search" "test" {
+  index  = "node"
+  query  = "name:${chef_node.test.name}"
+  unique = true
+
+  filter {
+    name  = "environment"
+    value = ["chef_environment"]
+  }
+}
+`
+
+func TestAccDataSearch_nodeTags(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccDataSearchConfig_nodeTags(`
+  filter {
+    name  = "environment"
+    value = ["chef_environment"]
+  }
+`)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.chef_search.test", "tags.#", "2"),
+					resource.TestCheckTypeSetElemAttr("data.chef_search.test", "tags.*", "web"),
+					resource.TestCheckTypeSetElemAttr("data.chef_search.test", "tags.*", "db"),
+					resource.TestCheckResourceAttr("data.chef_search.test", "result.%", "1"),
+					resource.TestCheckResourceAttr("data.chef_search.test", "result.environment", "terraform-acc-test-search-tags-"+testSuffix),
+				),
+			},
+			{
+				// A filter result named tags replaces the one fetched for
+				// the tags attribute.
+				Config: testSuffixRender(testAccDataSearchConfig_nodeTags(`
+  filter {
+    name  = "environment"
+    value = ["chef_environment"]
+  }
+
+  filter {
+    name  = "tags"
+    value = ["chef_environment"]
+  }
+`)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.chef_search.test", "tags.#", "0"),
+					resource.TestCheckResourceAttr("data.chef_search.test", "result.%", "2"),
+					resource.TestCheckResourceAttr("data.chef_search.test", "result.environment", "terraform-acc-test-search-tags-"+testSuffix),
+					resource.TestCheckResourceAttr("data.chef_search.test", "result.tags", "terraform-acc-test-search-tags-"+testSuffix),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSearchConfig_nodeTags(filters string) string {
+	return `
+resource "chef_environment" "test" {
+  name = "terraform-acc-test-search-tags-{{.}}"
+}
+
+resource "chef_node" "test" {
+  name             = "terraform-acc-test-search-tags-{{.}}"
+  environment_name = chef_environment.test.name
+  tags             = ["web", "db"]
+}
+
+data "chef_search" "test" {
+  index  = "node"
+  query  = "name:${chef_node.test.name}"
+  unique = true
+` + filters + `}
+`
+}
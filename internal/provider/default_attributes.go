@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Descriptions of the default_attributes provider block.
+const (
+	defaultAttributesDescription = "Attributes that are deep-merged into every node, role and environment the provider writes. " +
+		"The attributes of the resource itself take precedence. Attributes that only the provider sets are not recorded in the state, " +
+		"so they do not show up in plans."
+	defaultAttributesNormalDescription   = "Normal attributes as a JSON object, merged into nodes."
+	defaultAttributesDefaultDescription  = "Default attributes as a JSON object, merged into nodes, roles and environments."
+	defaultAttributesOverrideDescription = "Override attributes as a JSON object, merged into nodes, roles and environments."
+)
+
+// defaultAttributes are the attributes of each precedence level that the
+// provider merges into the objects it writes.
+type defaultAttributes struct {
+	Normal   map[string]interface{}
+	Default  map[string]interface{}
+	Override map[string]interface{}
+}
+
+// parseDefaultAttributes decodes the arguments of the default_attributes
+// provider block. Empty arguments are not set.
+func parseDefaultAttributes(normalJson, defaultJson, overrideJson string) (defaultAttributes, error) {
+	var d defaultAttributes
+	targets := []struct {
+		name  string
+		value string
+		m     *map[string]interface{}
+	}{
+		{"normal_json", normalJson, &d.Normal},
+		{"default_json", defaultJson, &d.Default},
+		{"override_json", overrideJson, &d.Override},
+	}
+	for _, t := range targets {
+		if t.value == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(t.value), t.m); err != nil {
+			return defaultAttributes{}, &chefClientConfigError{
+				Attribute: "default_attributes",
+				Err:       fmt.Errorf("default_attributes.%s must be a JSON object: %s", t.name, err),
+			}
+		}
+	}
+	return d, nil
+}
+
+// ignoreProviderDefaultAttributesSchema is the opt-out of the resources that
+// the provider merges default attributes into.
+func ignoreProviderDefaultAttributesSchema(kind string) schema.BoolAttribute {
+	return schema.BoolAttribute{
+		Optional: true,
+		Computed: true,
+		Default:  booldefault.StaticBool(false),
+		Description: fmt.Sprintf("If set, the `default_attributes` of the provider are not merged into the %s. "+
+			"Attributes that were merged before are removed on the next apply.", kind),
+	}
+}
+
+// defaultAttributesFor returns the attributes to merge into an object,
+// which are none if it opts out.
+func (c *chefClient) defaultAttributesFor(ignore types.Bool) defaultAttributes {
+	if c == nil || ignore.ValueBool() {
+		return defaultAttributes{}
+	}
+	return c.DefaultAttributes
+}
+
+// mergeDefaultAttributes deep-merges attributes onto the defaults of their
+// precedence level, the way Chef merges attributes from different sources.
+func mergeDefaultAttributes(defaults map[string]interface{}, attributes interface{}) interface{} {
+	if len(defaults) == 0 {
+		return attributes
+	}
+	return hashOnlyMerge(defaults, attributes)
+}
+
+// withoutDefaultAttributes removes the attributes that the provider merged
+// into a value read from the Chef server: keys that still hold the provider
+// default and that the resource does not set itself. A default that was
+// changed on the server is kept, so that the next apply restores it.
+func withoutDefaultAttributes(value interface{}, defaults map[string]interface{}, p attributesPair) interface{} {
+	if len(defaults) == 0 {
+		return value
+	}
+	// Invalid JSON in the state is reported when it is written; until
+	// then the resource is treated as not setting any attributes.
+	own, _ := p.value()
+	return stripDefaultAttributes(value, defaults, own)
+}
+
+func stripDefaultAttributes(value interface{}, defaults, own map[string]interface{}) interface{} {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		d, isDefault := defaults[k]
+		_, isOwn := own[k]
+		if !isDefault {
+			result[k] = v
+			continue
+		}
+
+		dMap, dIsMap := d.(map[string]interface{})
+		if _, vIsMap := v.(map[string]interface{}); vIsMap && dIsMap {
+			ownMap, _ := own[k].(map[string]interface{})
+			stripped := stripDefaultAttributes(v, dMap, ownMap).(map[string]interface{})
+			if len(stripped) > 0 || isOwn {
+				result[k] = stripped
+			}
+			continue
+		}
+
+		if isOwn || !jsonValuesEqual(v, d) {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// jsonValuesEqual compares two decoded JSON values, regardless of how their
+// numbers were decoded.
+func jsonValuesEqual(a, b interface{}) bool {
+	aJson, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bJson, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return jsonEquivalent(string(aJson), string(bJson))
+}
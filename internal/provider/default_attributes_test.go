@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	chefc "github.com/go-chef/chef"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestDefaultAttributesRoundTrip(t *testing.T) {
+	defaults := map[string]interface{}{
+		"owner": "platform",
+		"tags":  map[string]interface{}{"team": "platform", "cost_center": float64(42)},
+	}
+	own := map[string]interface{}{
+		"app":  "web",
+		"tags": map[string]interface{}{"team": "web"},
+	}
+
+	merged := mergeDefaultAttributes(defaults, own)
+	expected := map[string]interface{}{
+		"app":   "web",
+		"owner": "platform",
+		"tags":  map[string]interface{}{"team": "web", "cost_center": float64(42)},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("wrong merge; expected %#v, got %#v", expected, merged)
+	}
+
+	jsonValue := types.StringValue(`{"app":"web","tags":{"team":"web"}}`)
+	dynamicValue := types.DynamicNull()
+	p := newAttributesPair("normal_attributes", &jsonValue, &dynamicValue)
+	if got := withoutDefaultAttributes(merged, defaults, p); !reflect.DeepEqual(got, own) {
+		t.Errorf("expected the defaults to be removed; expected %#v, got %#v", own, got)
+	}
+
+	// A default that was changed on the server, or that the resource sets
+	// to the same value, is kept.
+	changed := mergeDefaultAttributes(defaults, own).(map[string]interface{})
+	changed["owner"] = "someone-else"
+	jsonValue = types.StringValue(`{"app":"web","tags":{"team":"web","cost_center":42}}`)
+	expected = map[string]interface{}{
+		"app":   "web",
+		"owner": "someone-else",
+		"tags":  map[string]interface{}{"team": "web", "cost_center": float64(42)},
+	}
+	if got := withoutDefaultAttributes(changed, defaults, p); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected changed and own attributes to be kept; expected %#v, got %#v", expected, got)
+	}
+}
+
+func TestParseDefaultAttributes(t *testing.T) {
+	d, err := parseDefaultAttributes(`{"owner":"platform"}`, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.Normal["owner"] != "platform" || d.Default != nil || d.Override != nil {
+		t.Errorf("wrong default attributes: %#v", d)
+	}
+
+	if _, err := parseDefaultAttributes("", `["owner"]`, ""); err == nil {
+		t.Errorf("expected an error for a JSON array")
+	}
+}
+
+func TestAccNode_defaultAttributes(t *testing.T) {
+	var node chefc.Node
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccNodeCheckDestroy(&node),
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccNodeConfig_defaultAttributes(false)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccNodeCheckExists("chef_node.test", &node),
+					resource.TestCheckResourceAttr("chef_node.test", "normal_attributes_json", `{"app":"web"}`),
+					func(s *terraform.State) error {
+						expected := map[string]interface{}{"app": "web", "tags": []interface{}{"managed"}}
+						if !reflect.DeepEqual(node.NormalAttributes, expected) {
+							return fmt.Errorf("wrong normal attributes; expected %#v, got %#v", expected, node.NormalAttributes)
+						}
+						expected = map[string]interface{}{"owner": "platform"}
+						if !reflect.DeepEqual(node.DefaultAttributes, expected) {
+							return fmt.Errorf("wrong default attributes; expected %#v, got %#v", expected, node.DefaultAttributes)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config:   testSuffixRender(testAccNodeConfig_defaultAttributes(false)),
+				PlanOnly: true,
+			},
+			{
+				Config: testSuffixRender(testAccNodeConfig_defaultAttributes(true)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccNodeCheckExists("chef_node.test", &node),
+					func(s *terraform.State) error {
+						expected := map[string]interface{}{"app": "web"}
+						if !reflect.DeepEqual(node.NormalAttributes, expected) {
+							return fmt.Errorf("wrong normal attributes; expected %#v, got %#v", expected, node.NormalAttributes)
+						}
+						if len(node.DefaultAttributes) != 0 {
+							return fmt.Errorf("expected no default attributes, got %#v", node.DefaultAttributes)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func testAccNodeConfig_defaultAttributes(ignore bool) string {
+	return fmt.Sprintf(`
+provider "chef" {
+  default_attributes {
+    normal_json  = jsonencode({ tags = ["managed"] })
+    default_json = jsonencode({ owner = "platform" })
+  }
+}
+
+resource "chef_node" "test" {
+  name                   = "terraform-acc-test-default-attributes-{{.}}"
+  normal_attributes_json = jsonencode({ app = "web" })
+
+  ignore_provider_default_attributes = %t
+}
+`, ignore)
+}
@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// deletionProtectionSchema is the deletion_protection argument of the
+// resources whose objects other objects depend on.
+func deletionProtectionSchema(kind string) schema.BoolAttribute {
+	return schema.BoolAttribute{
+		Optional: true,
+		Computed: true,
+		Default:  booldefault.StaticBool(false),
+		Description: fmt.Sprintf("If set, Terraform refuses to delete the %s, including when it would be replaced. "+
+			"Set it to false and apply before destroying.", kind),
+	}
+}
+
+// checkDeletionProtection adds an error and returns false if the object is
+// protected from deletion. The protection is read from the state, so that
+// it has to be turned off in an apply of its own.
+func checkDeletionProtection(protection types.Bool, kind, name string, diags *diag.Diagnostics) bool {
+	if !protection.ValueBool() {
+		return true
+	}
+	diags.AddAttributeError(path.Root("deletion_protection"), fmt.Sprintf("Cannot delete protected %s", kind),
+		fmt.Sprintf("The %s %q has deletion_protection set. Set it to false and apply before deleting the %s.", kind, name, kind))
+	return false
+}
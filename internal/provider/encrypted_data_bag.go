@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// encryptedDataBagValue is the format in which Chef stores each encrypted
+// value of a data bag item. Version 1 and 2 use AES-256-CBC, with version 2
+// adding an HMAC of the cipher text; version 3 uses AES-256-GCM.
+type encryptedDataBagValue struct {
+	EncryptedData string `json:"encrypted_data"`
+	IV            string `json:"iv"`
+	Version       int    `json:"version"`
+	Cipher        string `json:"cipher"`
+	HMAC          string `json:"hmac"`
+	AuthTag       string `json:"auth_tag"`
+}
+
+// decryptDataBagItem decrypts the values of an encrypted data bag item with
+// the shared secret. The id of the item is never encrypted and is returned
+// as is, as are any values that are not in the encrypted format.
+func decryptDataBagItem(item map[string]interface{}, secret string) (map[string]interface{}, error) {
+	// Like knife, ignore surrounding whitespace in secrets read from files.
+	secret = strings.TrimSpace(secret)
+
+	result := make(map[string]interface{}, len(item))
+	for k, v := range item {
+		if k == "id" {
+			result[k] = v
+			continue
+		}
+
+		encrypted, ok := encryptedValue(v)
+		if !ok {
+			result[k] = v
+			continue
+		}
+
+		value, err := encrypted.decrypt(secret)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting %q: %s", k, err)
+		}
+		result[k] = value
+	}
+
+	return result, nil
+}
+
+func encryptedValue(v interface{}) (encryptedDataBagValue, bool) {
+	var encrypted encryptedDataBagValue
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return encrypted, false
+	}
+	if _, ok := m["encrypted_data"]; !ok {
+		return encrypted, false
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return encrypted, false
+	}
+	if err := json.Unmarshal(b, &encrypted); err != nil {
+		return encrypted, false
+	}
+	return encrypted, true
+}
+
+func (e encryptedDataBagValue) decrypt(secret string) (interface{}, error) {
+	key := sha256.Sum256([]byte(secret))
+
+	cipherText, err := base64.StdEncoding.DecodeString(e.EncryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted_data: %s", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(e.IV)
+	if err != nil {
+		return nil, fmt.Errorf("invalid iv: %s", err)
+	}
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var plainText []byte
+	switch e.Version {
+	case 1, 2:
+		if e.Version == 2 {
+			if err := e.verifyHMAC(secret); err != nil {
+				return nil, err
+			}
+		}
+		if len(iv) != aes.BlockSize {
+			return nil, fmt.Errorf("invalid iv length %d", len(iv))
+		}
+		if len(cipherText) == 0 || len(cipherText)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("invalid encrypted_data length %d", len(cipherText))
+		}
+		plainText = make([]byte, len(cipherText))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plainText, cipherText)
+		plainText, err = unpadPKCS7(plainText)
+		if err != nil {
+			return nil, fmt.Errorf("wrong secret or corrupt data")
+		}
+	case 3:
+		tag, err := base64.StdEncoding.DecodeString(e.AuthTag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth_tag: %s", err)
+		}
+		gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+		if err != nil {
+			return nil, err
+		}
+		plainText, err = gcm.Open(nil, iv, append(cipherText, tag...), nil)
+		if err != nil {
+			return nil, fmt.Errorf("wrong secret or corrupt data")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encrypted data bag version %d", e.Version)
+	}
+
+	// Chef wraps every value so that scalars can be serialized as JSON.
+	var wrapper struct {
+		Value interface{} `json:"json_wrapper"`
+	}
+	if err := json.Unmarshal(plainText, &wrapper); err != nil {
+		return nil, fmt.Errorf("wrong secret or corrupt data")
+	}
+	return wrapper.Value, nil
+}
+
+// verifyHMAC checks the HMAC that version 2 items carry over the base64
+// encoded cipher text, keyed with the secret itself.
+func (e encryptedDataBagValue) verifyHMAC(secret string) error {
+	expected, err := base64.StdEncoding.DecodeString(e.HMAC)
+	if err != nil || len(expected) == 0 {
+		return fmt.Errorf("missing or invalid hmac")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(e.EncryptedData))
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return fmt.Errorf("hmac does not match, the secret is wrong or the data was tampered with")
+	}
+	return nil
+}
+
+func unpadPKCS7(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty plain text")
+	}
+	n := int(b[len(b)-1])
+	if n == 0 || n > aes.BlockSize || n > len(b) {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	for _, p := range b[len(b)-n:] {
+		if int(p) != n {
+			return nil, fmt.Errorf("invalid padding")
+		}
+	}
+	return b[:len(b)-n], nil
+}
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// testEncryptDataBagValue encrypts a value the way knife does for the given
+// format version.
+func testEncryptDataBagValue(t *testing.T, value interface{}, secret string, version int) map[string]interface{} {
+	t.Helper()
+
+	plainText, err := json.Marshal(map[string]interface{}{"json_wrapper": value})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	encrypted := map[string]interface{}{"version": version}
+	switch version {
+	case 1, 2:
+		iv := bytes.Repeat([]byte{7}, aes.BlockSize)
+		n := aes.BlockSize - len(plainText)%aes.BlockSize
+		plainText = append(plainText, bytes.Repeat([]byte{byte(n)}, n)...)
+		cipherText := make([]byte, len(plainText))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(cipherText, plainText)
+
+		// knife wraps base64 output at 60 characters.
+		data := base64.StdEncoding.EncodeToString(cipherText)
+		if len(data) > 60 {
+			data = data[:60] + "\n" + data[60:]
+		}
+		encrypted["encrypted_data"] = data
+		encrypted["iv"] = base64.StdEncoding.EncodeToString(iv)
+		encrypted["cipher"] = "aes-256-cbc"
+		if version == 2 {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(data))
+			encrypted["hmac"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		}
+	case 3:
+		iv := bytes.Repeat([]byte{9}, 12)
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		sealed := gcm.Seal(nil, iv, plainText, nil)
+		tagStart := len(sealed) - gcm.Overhead()
+		encrypted["encrypted_data"] = base64.StdEncoding.EncodeToString(sealed[:tagStart])
+		encrypted["auth_tag"] = base64.StdEncoding.EncodeToString(sealed[tagStart:])
+		encrypted["iv"] = base64.StdEncoding.EncodeToString(iv)
+		encrypted["cipher"] = "aes-256-gcm"
+	}
+
+	// Round trip through JSON so that the item looks like one read from
+	// the Chef server.
+	b, _ := json.Marshal(encrypted)
+	var result map[string]interface{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return result
+}
+
+func TestDecryptDataBagItem(t *testing.T) {
+	value := map[string]interface{}{"user": "admin", "ports": []interface{}{float64(80), float64(443)}}
+
+	for _, version := range []int{1, 2, 3} {
+		item := map[string]interface{}{
+			"id":       "secrets",
+			"password": testEncryptDataBagValue(t, "hunter2", "s3cret", version),
+			"settings": testEncryptDataBagValue(t, value, "s3cret", version),
+		}
+
+		got, err := decryptDataBagItem(item, "s3cret\n")
+		if err != nil {
+			t.Fatalf("version %d: err: %s", version, err)
+		}
+		want := map[string]interface{}{"id": "secrets", "password": "hunter2", "settings": value}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d: got %#v, want %#v", version, got, want)
+		}
+
+		if _, err := decryptDataBagItem(item, "wrong"); err == nil {
+			t.Errorf("version %d: expected an error with the wrong secret", version)
+		}
+	}
+}
+
+func TestDecryptDataBagItem_tampered(t *testing.T) {
+	encrypted := testEncryptDataBagValue(t, "hunter2", "s3cret", 2)
+	encrypted["hmac"] = base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	item := map[string]interface{}{"id": "secrets", "password": encrypted}
+	if _, err := decryptDataBagItem(item, "s3cret"); err == nil {
+		t.Errorf("expected an error for a mismatched hmac")
+	}
+}
+
+func TestDecryptDataBagItem_plain(t *testing.T) {
+	item := map[string]interface{}{"id": "plain", "nested": map[string]interface{}{"a": "b"}}
+
+	got, err := decryptDataBagItem(item, "s3cret")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(got, item) {
+		t.Errorf("expected values that are not encrypted to be kept, got %#v", got)
+	}
+}
@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	chefc "github.com/go-chef/chef"
+)
+
+var (
+	_ ephemeral.EphemeralResourceWithClose     = &clientCredentialsEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithConfigure = &clientCredentialsEphemeralResource{}
+)
+
+// clientCredentialsPrivateKey is the key in private data under which the
+// key to delete on close is recorded.
+const clientCredentialsPrivateKey = "client_key"
+
+func NewClientCredentialsEphemeralResource() ephemeral.EphemeralResource {
+	return &clientCredentialsEphemeralResource{}
+}
+
+// clientCredentialsEphemeralResource adds a short-lived key to a client and
+// deletes it again once Terraform no longer needs it. The private key is
+// generated locally and never sent to the Chef server.
+type clientCredentialsEphemeralResource struct {
+	frameworkEphemeralResource
+}
+
+type clientCredentialsEphemeralResourceModel struct {
+	Client         types.String `tfsdk:"client"`
+	KeyName        types.String `tfsdk:"key_name"`
+	TTL            types.String `tfsdk:"ttl"`
+	PrivateKeyPem  types.String `tfsdk:"private_key_pem"`
+	PublicKeyPem   types.String `tfsdk:"public_key_pem"`
+	ExpirationDate types.String `tfsdk:"expiration_date"`
+}
+
+// clientCredentialsPrivate identifies the key added by Open.
+type clientCredentialsPrivate struct {
+	Client  string `json:"client"`
+	KeyName string `json:"key_name"`
+}
+
+func (e *clientCredentialsEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_client_credentials"
+}
+
+func (e *clientCredentialsEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"client": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the client to add the key to.",
+			},
+			"key_name": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Name of the key. Defaults to a random name prefixed with `terraform-`.",
+			},
+			"ttl": schema.StringAttribute{
+				Optional:    true,
+				Description: "How long the key is valid for, as a Go duration such as `30m`. Defaults to `1h`.",
+			},
+			"private_key_pem": schema.StringAttribute{
+				Computed:    true,
+				Sensitive:   true,
+				Description: "PEM-formatted private key for authenticating as the client.",
+			},
+			"public_key_pem": schema.StringAttribute{
+				Computed: true,
+			},
+			"expiration_date": schema.StringAttribute{
+				Computed:    true,
+				Description: "Time at which the Chef server stops accepting the key.",
+			},
+		},
+	}
+}
+
+func (e *clientCredentialsEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	if !requireClient(e.client, &resp.Diagnostics) {
+		return
+	}
+
+	var data clientCredentialsEphemeralResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ttl := time.Hour
+	if !data.TTL.IsNull() {
+		var err error
+		ttl, err = time.ParseDuration(data.TTL.ValueString())
+		if err != nil || ttl <= 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("ttl"), "Invalid ttl",
+				fmt.Sprintf("%q is not a positive duration such as 30m or 2h.", data.TTL.ValueString()))
+			return
+		}
+	}
+
+	if data.KeyName.IsNull() || data.KeyName.IsUnknown() {
+		suffix := make([]byte, 4)
+		if _, err := rand.Read(suffix); err != nil {
+			resp.Diagnostics.AddError("Error generating key name", err.Error())
+			return
+		}
+		data.KeyName = types.StringValue("terraform-" + hex.EncodeToString(suffix))
+	}
+
+	privateKey, publicKey, err := generateClientKeyPair()
+	if err != nil {
+		resp.Diagnostics.AddError("Error generating client key", err.Error())
+		return
+	}
+
+	// The Chef server only accepts whole seconds in UTC.
+	expiration := time.Now().Add(ttl).UTC().Truncate(time.Second).Format(time.RFC3339)
+	key := chefc.AccessKey{
+		Name:           data.KeyName.ValueString(),
+		PublicKey:      publicKey,
+		ExpirationDate: expiration,
+	}
+	if _, err := e.client.Clients.AddKey(data.Client.ValueString(), key); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error creating client key", err, path.Root("client")))
+		return
+	}
+
+	private, err := json.Marshal(clientCredentialsPrivate{
+		Client:  data.Client.ValueString(),
+		KeyName: key.Name,
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Error creating client key", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, clientCredentialsPrivateKey, private)...)
+
+	data.PrivateKeyPem = types.StringValue(privateKey)
+	data.PublicKeyPem = types.StringValue(publicKey)
+	data.ExpirationDate = types.StringValue(expiration)
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
+
+func (e *clientCredentialsEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if !requireClient(e.client, &resp.Diagnostics) {
+		return
+	}
+
+	b, diags := req.Private.GetKey(ctx, clientCredentialsPrivateKey)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || b == nil {
+		return
+	}
+
+	var private clientCredentialsPrivate
+	if err := json.Unmarshal(b, &private); err != nil {
+		resp.Diagnostics.AddError("Error deleting client key", err.Error())
+		return
+	}
+
+	if _, err := e.client.Clients.DeleteKey(private.Client, private.KeyName); err != nil && !isNotFound(err) {
+		resp.Diagnostics.AddError("Error deleting client key",
+			fmt.Sprintf("Key %q of client %q could not be deleted and remains valid until it expires: %s",
+				private.KeyName, private.Client, chefErrorDetail(err)))
+	}
+}
+
+// generateClientKeyPair returns a new RSA key pair in the PEM formats that
+// Chef uses for client.pem and for public keys.
+func generateClientKeyPair() (privateKey string, publicKey string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	privateKey = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	publicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	return privateKey, publicKey, nil
+}
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ ephemeral.EphemeralResourceWithConfigure = &dataBagItemEphemeralResource{}
+
+func NewDataBagItemEphemeralResource() ephemeral.EphemeralResource {
+	return &dataBagItemEphemeralResource{}
+}
+
+// dataBagItemEphemeralResource reads a data bag item, decrypting it when a
+// secret is given, without the content ever being written to state.
+type dataBagItemEphemeralResource struct {
+	frameworkEphemeralResource
+}
+
+type dataBagItemEphemeralResourceModel struct {
+	DataBagName types.String  `tfsdk:"data_bag_name"`
+	ItemName    types.String  `tfsdk:"item_name"`
+	Secret      types.String  `tfsdk:"secret"`
+	ContentJson types.String  `tfsdk:"content_json"`
+	Content     types.Dynamic `tfsdk:"content"`
+}
+
+func (e *dataBagItemEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_data_bag_item"
+}
+
+func (e *dataBagItemEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"data_bag_name": schema.StringAttribute{
+				Required: true,
+			},
+			"item_name": schema.StringAttribute{
+				Required: true,
+			},
+			"secret": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Shared secret the item was encrypted with. If not set, the item is returned as stored.",
+			},
+			"content_json": schema.StringAttribute{
+				Computed:    true,
+				Sensitive:   true,
+				Description: "JSON encoded content of the item.",
+			},
+			"content": schema.DynamicAttribute{
+				Computed:    true,
+				Sensitive:   true,
+				Description: "Object holding the content of the item.",
+			},
+		},
+	}
+}
+
+func (e *dataBagItemEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	if !requireClient(e.client, &resp.Diagnostics) {
+		return
+	}
+
+	var data dataBagItemEphemeralResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	value, err := e.client.DataBags.GetItem(data.DataBagName.ValueString(), data.ItemName.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error reading data bag item", err, path.Root("item_name")))
+		return
+	}
+
+	content, ok := value.(map[string]interface{})
+	if !ok {
+		resp.Diagnostics.AddAttributeError(path.Root("item_name"), "Error reading data bag item", "The Chef server returned an item that is not an object.")
+		return
+	}
+
+	if !data.Secret.IsNull() {
+		content, err = decryptDataBagItem(content, data.Secret.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("secret"), "Error decrypting data bag item", err.Error())
+			return
+		}
+	}
+
+	data.ContentJson = types.StringNull()
+	data.Content = types.DynamicNull()
+	if err := setAttributesPair(newAttributesPair("content", &data.ContentJson, &data.Content), content); err != nil {
+		resp.Diagnostics.AddError("Error reading data bag item", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
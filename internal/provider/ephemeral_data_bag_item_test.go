@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	chefc "github.com/go-chef/chef"
+)
+
+func TestAccDataBagItemEphemeral(t *testing.T) {
+	if os.Getenv("TF_ACC") == "" {
+		t.Skip("acceptance tests are skipped unless TF_ACC is set")
+	}
+	testAccPreCheck(t)
+
+	client, err := testAccClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bag := "terraform-acc-test-ephemeral-" + testSuffix
+	if _, err := client.DataBags.Create(&chefc.DataBag{Name: bag}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.DataBags.Delete(bag)
+
+	const secret = "correct horse battery staple"
+	password := map[string]interface{}{"user": "app", "password": "hunter2"}
+	for version := 1; version <= 3; version++ {
+		if err := client.DataBags.CreateItem(bag, map[string]interface{}{
+			"id":       fmt.Sprintf("v%d", version),
+			"database": testEncryptDataBagValue(t, password, secret, version),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server, schemas := testAccProtocolServer(t)
+
+	for version := 1; version <= 3; version++ {
+		item := fmt.Sprintf("v%d", version)
+		resp, result := testAccOpenEphemeral(t, server, schemas, "chef_data_bag_item", map[string]string{
+			"data_bag_name": bag,
+			"item_name":     item,
+			"secret":        secret,
+		})
+		checkProtocolDiagnostics(t, "open "+item, resp.Diagnostics)
+
+		expected := `{"database":{"password":"hunter2","user":"app"},"id":"` + item + `"}`
+		if got := testAccStringValue(t, result["content_json"]); !jsonEquivalent(got, expected) {
+			t.Errorf("%s: expected content %s, got %s", item, expected, got)
+		}
+		if len(resp.Private) != 0 {
+			t.Errorf("%s: expected no private data, which Terraform keeps until close, got %s", item, resp.Private)
+		}
+	}
+
+	resp, _ := testAccOpenEphemeral(t, server, schemas, "chef_data_bag_item", map[string]string{
+		"data_bag_name": bag,
+		"item_name":     "v2",
+		"secret":        "wrong",
+	})
+	if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Summary != "Error decrypting data bag item" ||
+		resp.Diagnostics[0].Attribute.String() != tftypes.NewAttributePath().WithAttributeName("secret").String() {
+		t.Errorf("expected a wrong secret to fail decryption, got %v", resp.Diagnostics)
+	}
+}
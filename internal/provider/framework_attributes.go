@@ -0,0 +1,452 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// attrValueFromJson converts a decoded JSON value into a value for a dynamic
+// attribute. Objects become object values and arrays become tuples, which is
+// how Terraform types the equivalent HCL literals.
+func attrValueFromJson(v interface{}) attr.Value {
+	switch t := v.(type) {
+	case nil:
+		return types.DynamicNull()
+	case bool:
+		return types.BoolValue(t)
+	case json.Number:
+		f, _, err := big.ParseFloat(string(t), 10, 512, big.ToNearestEven)
+		if err != nil {
+			return types.StringValue(string(t))
+		}
+		return types.NumberValue(f)
+	case float64:
+		return types.NumberValue(big.NewFloat(t))
+	case string:
+		return types.StringValue(t)
+	case []interface{}:
+		elemTypes := make([]attr.Type, len(t))
+		elems := make([]attr.Value, len(t))
+		for i, e := range t {
+			elems[i] = attrValueFromJson(e)
+			elemTypes[i] = elems[i].Type(context.Background())
+		}
+		return types.TupleValueMust(elemTypes, elems)
+	case map[string]interface{}:
+		attrTypes := make(map[string]attr.Type, len(t))
+		attrs := make(map[string]attr.Value, len(t))
+		for k, e := range t {
+			attrs[k] = attrValueFromJson(e)
+			attrTypes[k] = attrs[k].Type(context.Background())
+		}
+		return types.ObjectValueMust(attrTypes, attrs)
+	}
+	return types.StringValue(fmt.Sprint(v))
+}
+
+// jsonFromAttrValue converts a framework value back into plain Go values
+// that encoding/json understands. The boolean result is false if the value
+// or anything nested in it is unknown.
+func jsonFromAttrValue(v attr.Value) (interface{}, bool, error) {
+	if v == nil || v.IsNull() {
+		return nil, true, nil
+	}
+	if v.IsUnknown() {
+		return nil, false, nil
+	}
+
+	switch t := v.(type) {
+	case types.Dynamic:
+		if t.IsUnderlyingValueUnknown() {
+			return nil, false, nil
+		}
+		if t.IsUnderlyingValueNull() {
+			return nil, true, nil
+		}
+		return jsonFromAttrValue(t.UnderlyingValue())
+	case types.String:
+		return t.ValueString(), true, nil
+	case types.Bool:
+		return t.ValueBool(), true, nil
+	case types.Number:
+		f := t.ValueBigFloat()
+		if f.IsInt() {
+			return json.Number(f.Text('f', 0)), true, nil
+		}
+		return json.Number(f.Text('g', -1)), true, nil
+	case types.Int64:
+		return t.ValueInt64(), true, nil
+	case types.Float64:
+		return t.ValueFloat64(), true, nil
+	case types.Object:
+		return jsonFromAttrValueMap(t.Attributes())
+	case types.Map:
+		return jsonFromAttrValueMap(t.Elements())
+	case types.Tuple:
+		return jsonFromAttrValueSlice(t.Elements())
+	case types.List:
+		return jsonFromAttrValueSlice(t.Elements())
+	case types.Set:
+		return jsonFromAttrValueSlice(t.Elements())
+	}
+	return nil, true, fmt.Errorf("unsupported value type %s", v.Type(context.Background()))
+}
+
+func jsonFromAttrValueMap(attrs map[string]attr.Value) (interface{}, bool, error) {
+	result := make(map[string]interface{}, len(attrs))
+	for k, e := range attrs {
+		v, known, err := jsonFromAttrValue(e)
+		if err != nil || !known {
+			return nil, known, err
+		}
+		result[k] = v
+	}
+	return result, true, nil
+}
+
+func jsonFromAttrValueSlice(elems []attr.Value) (interface{}, bool, error) {
+	result := make([]interface{}, len(elems))
+	for i, e := range elems {
+		v, known, err := jsonFromAttrValue(e)
+		if err != nil || !known {
+			return nil, known, err
+		}
+		result[i] = v
+	}
+	return result, true, nil
+}
+
+func decodeJson(s string) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// jsonEquivalent reports whether two JSON documents hold the same value,
+// ignoring formatting, key order and how numbers are written.
+func jsonEquivalent(a, b string) bool {
+	var va, vb interface{}
+	if err := json.Unmarshal([]byte(a), &va); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// attributesPair ties one of the *_json string arguments to its dynamically
+// typed equivalent. Either one may be configured and the other is derived
+// from it, so both always describe the same attributes.
+type attributesPair struct {
+	JsonPath    path.Path
+	DynamicPath path.Path
+	Json        *types.String
+	Dynamic     *types.Dynamic
+}
+
+func newAttributesPair(name string, jsonValue *types.String, dynamicValue *types.Dynamic) attributesPair {
+	return attributesPair{
+		JsonPath:    path.Root(name + "_json"),
+		DynamicPath: path.Root(name),
+		Json:        jsonValue,
+		Dynamic:     dynamicValue,
+	}
+}
+
+// value returns the attributes as plain Go values, ready to be sent to the
+// Chef server. Errors are reported against the argument that was set.
+func (p attributesPair) value() (map[string]interface{}, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	var v interface{}
+	var err error
+	switch {
+	case !p.Json.IsUnknown() && !p.Json.IsNull():
+		v, err = decodeJson(p.Json.ValueString())
+		if err != nil {
+			diags.AddAttributeError(p.JsonPath, "Invalid JSON", err.Error())
+			return nil, diags
+		}
+	case !p.Dynamic.IsUnknown() && !p.Dynamic.IsNull():
+		var known bool
+		v, known, err = jsonFromAttrValue(*p.Dynamic)
+		if err != nil {
+			diags.AddAttributeError(p.DynamicPath, "Invalid attributes", err.Error())
+			return nil, diags
+		}
+		if !known {
+			diags.AddAttributeError(p.DynamicPath, "Invalid attributes", "The value is not known.")
+			return nil, diags
+		}
+	}
+
+	if v == nil {
+		return map[string]interface{}{}, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		diags.AddAttributeError(p.DynamicPath, "Invalid attributes", "Attributes must be an object.")
+		return nil, diags
+	}
+	return m, nil
+}
+
+// validateConfig checks that only one side of the pair is configured and
+// that a configured dynamic value is an object.
+func (p attributesPair) validateConfig(diags *diag.Diagnostics) {
+	if !p.Json.IsNull() && !p.Dynamic.IsNull() {
+		diags.AddAttributeError(p.DynamicPath, "Conflicting configuration arguments",
+			fmt.Sprintf("%q cannot be specified when %q is specified", p.DynamicPath, p.JsonPath))
+		return
+	}
+	if p.Dynamic.IsNull() || p.Dynamic.IsUnknown() || p.Dynamic.IsUnderlyingValueUnknown() {
+		return
+	}
+	switch p.Dynamic.UnderlyingValue().(type) {
+	case types.Object, types.Map:
+	default:
+		diags.AddAttributeError(p.DynamicPath, "Invalid attributes", "Attributes must be an object.")
+	}
+}
+
+// planAttributesPair fills in the side of the pair that was not configured.
+// When the result is equivalent to the prior state the prior state is kept,
+// so reformatting JSON or changing the HCL type of a value never produces a
+// diff.
+func planAttributesPair(config, state, plan attributesPair, diags *diag.Diagnostics) {
+	switch {
+	case !config.Dynamic.IsNull():
+		v, known, err := jsonFromAttrValue(*config.Dynamic)
+		if err != nil {
+			diags.AddAttributeError(config.DynamicPath, "Invalid attributes", err.Error())
+			return
+		}
+		if !known {
+			*plan.Json = types.StringUnknown()
+			break
+		}
+		jsonValue, err := json.Marshal(v)
+		if err != nil {
+			diags.AddAttributeError(config.DynamicPath, "Invalid attributes", err.Error())
+			return
+		}
+		*plan.Json = types.StringValue(string(jsonValue))
+	case !config.Json.IsNull():
+		if config.Json.IsUnknown() {
+			*plan.Dynamic = types.DynamicUnknown()
+			break
+		}
+		v, err := decodeJson(config.Json.ValueString())
+		if err != nil {
+			diags.AddAttributeError(config.JsonPath, "Invalid JSON", err.Error())
+			return
+		}
+		*plan.Dynamic = types.DynamicValue(attrValueFromJson(v))
+	default:
+		*plan.Json = types.StringValue("{}")
+		*plan.Dynamic = types.DynamicValue(attrValueFromJson(map[string]interface{}{}))
+	}
+
+	if state.Json == nil || state.Dynamic.IsNull() || state.Dynamic.IsUnknown() {
+		return
+	}
+	// An imported pair only has its dynamic side, and keeps it that way
+	// until the attributes change, so that generated configuration does not
+	// show a diff.
+	if state.Json.IsNull() {
+		if config.Json.IsNull() && dynamicJsonEquivalent(*plan.Dynamic, *state.Dynamic) {
+			*plan.Json = types.StringNull()
+			*plan.Dynamic = *state.Dynamic
+		}
+		return
+	}
+	if state.Json.IsUnknown() {
+		return
+	}
+	if !plan.Json.IsUnknown() && jsonEquivalent(plan.Json.ValueString(), state.Json.ValueString()) {
+		*plan.Json = *state.Json
+		*plan.Dynamic = *state.Dynamic
+	}
+}
+
+// setAttributesPair records attributes read from the Chef server. Each side
+// of the pair is left untouched if it is equivalent to what the server
+// returned, so values from the plan or the prior state survive a refresh.
+// The JSON side is left null when only the dynamic side is recorded, as
+// after an import.
+func setAttributesPair(p attributesPair, value interface{}) error {
+	serverJson, err := attributesJson(value)
+	if err != nil {
+		return err
+	}
+
+	onlyDynamic := p.Json.IsNull() && !p.Dynamic.IsNull()
+	if !onlyDynamic && (p.Json.IsUnknown() || p.Json.IsNull() || !jsonEquivalent(p.Json.ValueString(), serverJson)) {
+		*p.Json = types.StringValue(serverJson)
+	}
+
+	if !p.Dynamic.IsUnknown() && !p.Dynamic.IsNull() {
+		v, known, err := jsonFromAttrValue(*p.Dynamic)
+		if err == nil && known {
+			if dynamicJson, err := json.Marshal(v); err == nil && jsonEquivalent(string(dynamicJson), serverJson) {
+				return nil
+			}
+		}
+	}
+
+	v, err := decodeJson(serverJson)
+	if err != nil {
+		return err
+	}
+	*p.Dynamic = types.DynamicValue(attrValueFromJson(v))
+	return nil
+}
+
+// importAttributesPairs starts the state of an imported resource with an
+// empty dynamic side for each pair and a null JSON side, so that Read only
+// records the dynamic side and configuration generated from the state sets
+// one side of each pair.
+func importAttributesPairs(ctx context.Context, pairs []attributesPair, state *tfsdk.State) diag.Diagnostics {
+	var diags diag.Diagnostics
+	empty := types.DynamicValue(attrValueFromJson(map[string]interface{}{}))
+	for _, p := range pairs {
+		diags.Append(state.SetAttribute(ctx, p.DynamicPath, empty)...)
+	}
+	return diags
+}
+
+// upgradeAttributesPair fills in the dynamic side of a pair from state
+// written before it existed.
+func upgradeAttributesPair(p attributesPair, diags *diag.Diagnostics) {
+	if p.Json.IsNull() || p.Json.IsUnknown() {
+		*p.Json = types.StringValue("{}")
+	}
+	v, err := decodeJson(p.Json.ValueString())
+	if err != nil {
+		diags.AddAttributeError(p.JsonPath, "Error upgrading state", err.Error())
+		return
+	}
+	*p.Dynamic = types.DynamicValue(attrValueFromJson(v))
+}
+
+// planJsonString keeps the prior state of a plain JSON argument when the
+// planned value only differs in formatting.
+func planJsonString(plan *types.String, state types.String) {
+	if plan.IsNull() || plan.IsUnknown() || state.IsNull() || state.IsUnknown() {
+		return
+	}
+	if jsonEquivalent(plan.ValueString(), state.ValueString()) {
+		*plan = state
+	}
+}
+
+// setJsonString records a JSON document read from the Chef server unless
+// the current value is equivalent.
+func setJsonString(current *types.String, value interface{}) error {
+	serverJson, err := attributesJson(value)
+	if err != nil {
+		return err
+	}
+	if !current.IsUnknown() && !current.IsNull() && jsonEquivalent(current.ValueString(), serverJson) {
+		return nil
+	}
+	*current = types.StringValue(serverJson)
+	return nil
+}
+
+func runListFromList(ctx context.Context, l types.List) ([]string, diag.Diagnostics) {
+	runList := []string{}
+	if l.IsNull() || l.IsUnknown() {
+		return runList, nil
+	}
+	diags := l.ElementsAs(ctx, &runList, false)
+	return runList, diags
+}
+
+func runListEquivalent(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if runListEntryStateFunc(a[i]) != runListEntryStateFunc(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// planRunList keeps the prior state of a run list when the configuration
+// only differs in whether recipes are written naked or qualified.
+func planRunList(ctx context.Context, plan *types.List, state types.List) diag.Diagnostics {
+	if plan.IsNull() || plan.IsUnknown() || state.IsNull() || state.IsUnknown() {
+		return nil
+	}
+	for _, e := range plan.Elements() {
+		if e.IsUnknown() {
+			return nil
+		}
+	}
+
+	planned, diags := runListFromList(ctx, *plan)
+	prior, d := runListFromList(ctx, state)
+	diags.Append(d...)
+	if !diags.HasError() && runListEquivalent(planned, prior) {
+		*plan = state
+	}
+	return diags
+}
+
+// setRunList records a run list read from the Chef server unless the
+// current value is equivalent.
+func setRunList(ctx context.Context, current *types.List, runList []string) diag.Diagnostics {
+	if !current.IsUnknown() {
+		existing, diags := runListFromList(ctx, *current)
+		if diags.HasError() {
+			return diags
+		}
+		if runListEquivalent(existing, runList) {
+			return nil
+		}
+	}
+
+	if runList == nil {
+		runList = []string{}
+	}
+	l, diags := types.ListValueFrom(ctx, types.StringType, runList)
+	*current = l
+	return diags
+}
+
+// stringMapFromMap converts a map of strings from the configuration.
+func stringMapFromMap(ctx context.Context, m types.Map) (map[string]string, diag.Diagnostics) {
+	result := map[string]string{}
+	if m.IsNull() || m.IsUnknown() {
+		return result, nil
+	}
+	diags := m.ElementsAs(ctx, &result, false)
+	return result, diags
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
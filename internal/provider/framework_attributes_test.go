@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestAttrValueFromJson(t *testing.T) {
+	in := `{"a":1,"b":[true,"x",null],"c":{"d":1.5}}`
+	v, err := decodeJson(in)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	value := attrValueFromJson(v)
+	obj, ok := value.(types.Object)
+	if !ok {
+		t.Fatalf("expected an object, got %T", value)
+	}
+	if _, ok := obj.Attributes()["b"].(types.Tuple); !ok {
+		t.Errorf("expected a tuple for b, got %T", obj.Attributes()["b"])
+	}
+
+	out, known, err := jsonFromAttrValue(types.DynamicValue(value))
+	if err != nil || !known {
+		t.Fatalf("unexpected result: known=%v err=%v", known, err)
+	}
+	outJson, err := attributesJson(out)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !jsonEquivalent(in, outJson) {
+		t.Errorf("round trip changed the value: %s", outJson)
+	}
+}
+
+func TestJsonFromAttrValueUnknown(t *testing.T) {
+	value := types.ObjectValueMust(
+		map[string]attr.Type{"a": types.StringType},
+		map[string]attr.Value{"a": types.StringUnknown()},
+	)
+	if _, known, _ := jsonFromAttrValue(types.DynamicValue(value)); known {
+		t.Errorf("expected a nested unknown value to make the result unknown")
+	}
+}
+
+func TestJsonEquivalent(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{`{"a": 1, "b": 2}`, `{"b":2,"a":1}`, true},
+		{`{"a": 1.0}`, `{"a":1}`, true},
+		{`{"a": [1, 2]}`, `{"a":[2,1]}`, false},
+		{`{"a": "1"}`, `{"a":1}`, false},
+		{`{`, `{}`, false},
+	}
+	for _, c := range cases {
+		if got := jsonEquivalent(c.a, c.b); got != c.want {
+			t.Errorf("jsonEquivalent(%s, %s) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestPlanAttributesPair(t *testing.T) {
+	dynamicFromJson := func(s string) types.Dynamic {
+		v, err := decodeJson(s)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return types.DynamicValue(attrValueFromJson(v))
+	}
+
+	t.Run("dynamic configured", func(t *testing.T) {
+		config := nodeResourceModel{
+			DefaultAttributesJson: types.StringNull(),
+			DefaultAttributes:     dynamicFromJson(`{"a":{"b":1}}`),
+		}
+		plan := config
+		var diags diag.Diagnostics
+		planAttributesPair(config.attributePairs()[2], (&nodeResourceModel{}).attributePairs()[2], plan.attributePairs()[2], &diags)
+		if diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		if got := plan.DefaultAttributesJson.ValueString(); got != `{"a":{"b":1}}` {
+			t.Errorf("unexpected json: %s", got)
+		}
+	})
+
+	t.Run("json configured", func(t *testing.T) {
+		config := nodeResourceModel{
+			DefaultAttributesJson: types.StringValue(`{"a": true}`),
+			DefaultAttributes:     types.DynamicNull(),
+		}
+		plan := config
+		var diags diag.Diagnostics
+		planAttributesPair(config.attributePairs()[2], (&nodeResourceModel{}).attributePairs()[2], plan.attributePairs()[2], &diags)
+		if diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		if !plan.DefaultAttributes.Equal(dynamicFromJson(`{"a":true}`)) {
+			t.Errorf("unexpected dynamic value: %s", plan.DefaultAttributes)
+		}
+	})
+
+	t.Run("equivalent to state", func(t *testing.T) {
+		config := nodeResourceModel{
+			DefaultAttributesJson: types.StringValue("{\n  \"a\": true\n}"),
+			DefaultAttributes:     types.DynamicNull(),
+		}
+		state := nodeResourceModel{
+			DefaultAttributesJson: types.StringValue(`{"a":true}`),
+			DefaultAttributes:     dynamicFromJson(`{"a":true}`),
+		}
+		plan := config
+		var diags diag.Diagnostics
+		planAttributesPair(config.attributePairs()[2], state.attributePairs()[2], plan.attributePairs()[2], &diags)
+		if diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		if !plan.DefaultAttributesJson.Equal(state.DefaultAttributesJson) {
+			t.Errorf("expected the prior state to be kept, got %s", plan.DefaultAttributesJson)
+		}
+	})
+
+	t.Run("neither configured", func(t *testing.T) {
+		config := nodeResourceModel{
+			DefaultAttributesJson: types.StringNull(),
+			DefaultAttributes:     types.DynamicNull(),
+		}
+		plan := config
+		var diags diag.Diagnostics
+		planAttributesPair(config.attributePairs()[2], (&nodeResourceModel{}).attributePairs()[2], plan.attributePairs()[2], &diags)
+		if got := plan.DefaultAttributesJson.ValueString(); got != "{}" {
+			t.Errorf("unexpected json: %s", got)
+		}
+	})
+}
+
+func TestSetAttributesPair(t *testing.T) {
+	m := nodeResourceModel{
+		NormalAttributesJson: types.StringValue("{ \"a\": 1 }"),
+		NormalAttributes:     types.DynamicNull(),
+	}
+	p := m.attributePairs()[1]
+
+	if err := setAttributesPair(p, map[string]interface{}{"a": float64(1)}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := m.NormalAttributesJson.ValueString(); got != "{ \"a\": 1 }" {
+		t.Errorf("expected equivalent json to be kept, got %s", got)
+	}
+	if m.NormalAttributes.IsNull() {
+		t.Errorf("expected the dynamic value to be filled in")
+	}
+
+	if err := setAttributesPair(p, map[string]interface{}{"a": float64(2)}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := m.NormalAttributesJson.ValueString(); got != `{"a":2}` {
+		t.Errorf("expected the server value, got %s", got)
+	}
+}
+
+func TestPlanRunList(t *testing.T) {
+	ctx := context.Background()
+	state, _ := types.ListValueFrom(ctx, types.StringType, []string{"recipe[foo]", "role[bar]"})
+	plan, _ := types.ListValueFrom(ctx, types.StringType, []string{"foo", "role[bar]"})
+
+	if diags := planRunList(ctx, &plan, state); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if !plan.Equal(state) {
+		t.Errorf("expected the prior state to be kept, got %s", plan)
+	}
+}
@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	chefc "github.com/go-chef/chef"
+)
+
+// TestAccImport_generatedConfig imports objects the way an import block
+// does and plans the configuration that -generate-config-out writes for
+// them, which must show no changes. Terraform is not needed: the test calls
+// the provider server as Terraform would, and generates the configuration
+// the way Terraform does, from every attribute that is not computed only.
+func TestAccImport_generatedConfig(t *testing.T) {
+	if os.Getenv("TF_ACC") == "" {
+		t.Skip("acceptance tests are skipped unless TF_ACC is set")
+	}
+	testAccPreCheck(t)
+
+	client, err := testAccClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := "terraform-acc-test-import-" + testSuffix
+	attributes := map[string]interface{}{
+		"nginx": map[string]interface{}{"port": float64(8080), "sites": []interface{}{"a", "b"}},
+		"tags":  []interface{}{"web"},
+	}
+	if _, err := client.Nodes.Post(chefc.Node{
+		Name:                name,
+		Environment:         "_default",
+		ChefType:            "node",
+		JsonClass:           "Chef::Node",
+		RunList:             []string{"recipe[nginx]", "role[web]"},
+		NormalAttributes:    attributes,
+		DefaultAttributes:   map[string]interface{}{"default": true},
+		OverrideAttributes:  map[string]interface{}{"override": "yes"},
+		AutomaticAttributes: map[string]interface{}{"platform": "ubuntu"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Nodes.Delete(name)
+
+	if _, err := client.Roles.Create(&chefc.Role{
+		Name:               name,
+		ChefType:           "role",
+		JsonClass:          "Chef::Role",
+		RunList:            []string{"recipe[nginx]"},
+		DefaultAttributes:  map[string]interface{}{"nginx": map[string]interface{}{"port": float64(80)}},
+		OverrideAttributes: map[string]interface{}{},
+		EnvRunList:         chefc.EnvRunList{"production": chefc.RunList{"recipe[nginx]", "recipe[monitoring]"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Roles.Delete(name)
+
+	if _, err := client.Environments.Create(&chefc.Environment{
+		Name:               name,
+		ChefType:           "environment",
+		JsonClass:          "Chef::Environment",
+		DefaultAttributes:  map[string]interface{}{"region": "eu"},
+		OverrideAttributes: map[string]interface{}{"nginx": map[string]interface{}{"workers": float64(4)}},
+		CookbookVersions:   map[string]string{"nginx": "~> 2.0"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Environments.Delete(name)
+
+	if _, err := client.DataBags.Create(&chefc.DataBag{Name: name}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.DataBags.Delete(name)
+	if err := client.DataBags.CreateItem(name, map[string]interface{}{
+		"id":      "settings",
+		"servers": []interface{}{"a", "b"},
+		"limits":  map[string]interface{}{"connections": float64(100)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	server, schemas := testAccProtocolServer(t)
+
+	for typeName, id := range map[string]string{
+		"chef_node":          name,
+		"chef_role":          name,
+		"chef_environment":   name,
+		"chef_data_bag_item": name + "/settings",
+	} {
+		t.Run(typeName, func(t *testing.T) {
+			schema := schemas.ResourceSchemas[typeName]
+			typ := schema.ValueType()
+
+			imported, err := server.ImportResourceState(ctx, &tfprotov6.ImportResourceStateRequest{TypeName: typeName, ID: id})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkProtocolDiagnostics(t, "import", imported.Diagnostics)
+			if len(imported.ImportedResources) != 1 {
+				t.Fatalf("expected one imported resource, got %d", len(imported.ImportedResources))
+			}
+
+			read, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName:     typeName,
+				CurrentState: imported.ImportedResources[0].State,
+				Private:      imported.ImportedResources[0].Private,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkProtocolDiagnostics(t, "read", read.Diagnostics)
+
+			state, err := read.NewState.Unmarshal(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := generatedConfig(schema, state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			configValue, err := tfprotov6.NewDynamicValue(typ, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			validated, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{TypeName: typeName, Config: &configValue})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkProtocolDiagnostics(t, "validate", validated.Diagnostics)
+
+			// The generated configuration repeats the state, apart from the
+			// attributes that are computed only, which Terraform takes from
+			// the state; so the proposed state is the state.
+			planned, err := server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+				TypeName:         typeName,
+				PriorState:       read.NewState,
+				ProposedNewState: read.NewState,
+				Config:           &configValue,
+				PriorPrivate:     read.Private,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkProtocolDiagnostics(t, "plan", planned.Diagnostics)
+
+			plannedState, err := planned.PlannedState.Unmarshal(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diffs, err := state.Diff(plannedState); err != nil {
+				t.Fatal(err)
+			} else {
+				for _, d := range diffs {
+					t.Errorf("%s: planned %s, state has %s", d.Path, d.Value2, d.Value1)
+				}
+			}
+			if len(planned.RequiresReplace) > 0 {
+				t.Errorf("plan requires replacement because of %v", planned.RequiresReplace)
+			}
+		})
+	}
+}
+
+// generatedConfig returns the configuration that -generate-config-out
+// writes for state: every attribute that can be configured, with its value
+// from the state.
+func generatedConfig(schema *tfprotov6.Schema, state tftypes.Value) (tftypes.Value, error) {
+	var stateValues map[string]tftypes.Value
+	if err := state.As(&stateValues); err != nil {
+		return tftypes.Value{}, err
+	}
+	values := make(map[string]tftypes.Value, len(stateValues))
+	for name, v := range stateValues {
+		values[name] = v
+	}
+	for _, a := range schema.Block.Attributes {
+		if a.Computed && !a.Optional {
+			values[a.Name] = tftypes.NewValue(values[a.Name].Type(), nil)
+		}
+	}
+	return tftypes.NewValue(state.Type(), values), nil
+}
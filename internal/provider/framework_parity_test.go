@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+// The parity tests apply a configuration with a release of the provider
+// built on SDKv2, then check that the framework implementation plans no
+// changes against the state it left behind. An empty plan shows that the
+// ported resources, including any state upgrade, read back identical state.
+//
+// Set CHEF_SDK_PROVIDER_VERSION to the SDKv2 release to compare against,
+// in addition to the variables described in provider_test.go.
+
+func testAccParity(t *testing.T, config string) {
+	version := os.Getenv("CHEF_SDK_PROVIDER_VERSION")
+	if version == "" {
+		t.Skip("CHEF_SDK_PROVIDER_VERSION must be set for parity tests")
+	}
+	if os.Getenv("TF_ACC_CASSETTES") != "" {
+		t.Skip("parity tests cannot use cassettes, as the released provider bypasses them")
+	}
+
+	config = testSuffixRender(config)
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				ExternalProviders: map[string]resource.ExternalProvider{
+					"chef": {
+						Source:            "registry.terraform.io/bdwyertech/chef",
+						VersionConstraint: version,
+					},
+				},
+				Config: config,
+			},
+			{
+				ProtoV6ProviderFactories: protoV6ProviderFactories,
+				Config:                   config,
+				PlanOnly:                 true,
+			},
+		},
+	})
+}
+
+func TestAccParity_client(t *testing.T) {
+	testAccParity(t, testAccClientConfig_basic)
+}
+
+func TestAccParity_clientKey(t *testing.T) {
+	testAccParity(t, testAccClientKeyConfig_basic)
+}
+
+func TestAccParity_dataBag(t *testing.T) {
+	testAccParity(t, testAccDataBagConfig_basic)
+}
+
+func TestAccParity_dataBagItem(t *testing.T) {
+	testAccParity(t, testAccDataBagItemConfig_basic)
+}
+
+func TestAccParity_environment(t *testing.T) {
+	testAccParity(t, testAccEnvironmentConfig_basic)
+}
+
+func TestAccParity_node(t *testing.T) {
+	testAccParity(t, testAccNodeConfig_basic)
+}
+
+func TestAccParity_role(t *testing.T) {
+	testAccParity(t, testAccRoleConfig_basic)
+}
+
+func TestAccParity_userKey(t *testing.T) {
+	testAccParity(t, testAccUserKeyConfig_basic)
+}
@@ -0,0 +1,352 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwprovider "github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-mux/tf5to6server"
+	"github.com/hashicorp/terraform-plugin-mux/tf6muxserver"
+)
+
+// NewMuxServer serves the terraform-plugin-framework provider alongside the
+// SDKv2 provider. All resources are implemented with the framework; data
+// sources are moved from New to NewFramework as they are ported. Both
+// providers must keep an identical provider schema.
+func NewMuxServer(ctx context.Context, version string) (func() tfprotov6.ProviderServer, error) {
+	upgradedSdkServer, err := tf5to6server.UpgradeServer(ctx, New(version)().GRPCProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := []func() tfprotov6.ProviderServer{
+		providerserver.NewProtocol6(NewFramework(version)()),
+		func() tfprotov6.ProviderServer {
+			return upgradedSdkServer
+		},
+	}
+
+	muxServer, err := tf6muxserver.NewMuxServer(ctx, providers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return muxServer.ProviderServer, nil
+}
+
+func NewFramework(version string) func() fwprovider.Provider {
+	return func() fwprovider.Provider {
+		return &frameworkProvider{version: version}
+	}
+}
+
+var (
+	_ fwprovider.ProviderWithEphemeralResources = &frameworkProvider{}
+	_ fwprovider.ProviderWithFunctions          = &frameworkProvider{}
+)
+
+type frameworkProvider struct {
+	version string
+}
+
+type frameworkProviderModel struct {
+	ServerURL          types.String `tfsdk:"server_url"`
+	ClientName         types.String `tfsdk:"client_name"`
+	PrivateKeyPem      types.String `tfsdk:"private_key_pem"`
+	KeyMaterial        types.String `tfsdk:"key_material"`
+	AllowUnverifiedSSL types.Bool   `tfsdk:"allow_unverified_ssl"`
+	ValidateReferences types.Bool   `tfsdk:"validate_references"`
+	ConflictDetection  types.String `tfsdk:"conflict_detection"`
+	ReadOnly           types.Bool   `tfsdk:"read_only"`
+	AuditLogPath       types.String `tfsdk:"audit_log_path"`
+	AuditLogLabel      types.String `tfsdk:"audit_log_label"`
+	DefaultAttributes  types.List   `tfsdk:"default_attributes"`
+}
+
+type frameworkProviderDefaultAttributesModel struct {
+	NormalJson   types.String `tfsdk:"normal_json"`
+	DefaultJson  types.String `tfsdk:"default_json"`
+	OverrideJson types.String `tfsdk:"override_json"`
+}
+
+func (p *frameworkProvider) Metadata(ctx context.Context, req fwprovider.MetadataRequest, resp *fwprovider.MetadataResponse) {
+	resp.TypeName = "chef"
+	resp.Version = p.version
+}
+
+func (p *frameworkProvider) Schema(ctx context.Context, req fwprovider.SchemaRequest, resp *fwprovider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"server_url": schema.StringAttribute{
+				Optional:    true,
+				Description: "URL of the root of the target Chef server or organization.",
+			},
+			"client_name": schema.StringAttribute{
+				Optional:    true,
+				Description: "Name of a registered client within the Chef server.",
+			},
+			"private_key_pem": schema.StringAttribute{
+				Optional:           true,
+				DeprecationMessage: "Please use key_material instead",
+			},
+			"key_material": schema.StringAttribute{
+				Optional:    true,
+				Description: "PEM-formatted private key for client authentication.",
+			},
+			"allow_unverified_ssl": schema.BoolAttribute{
+				Optional:    true,
+				Description: "If set, the Chef client will permit unverifiable SSL certificates.",
+			},
+			"validate_references": schema.BoolAttribute{
+				Optional:    true,
+				Description: "If set, run lists, roles and environments referenced by nodes and roles, and the cookbook constraints of environments, are checked against the Chef server during plan. Objects created in the same plan must be referenced through their `id` so that the check is deferred until they exist.",
+			},
+			"read_only": schema.BoolAttribute{
+				Optional:    true,
+				Description: readOnlyDescription,
+			},
+			"conflict_detection": schema.StringAttribute{
+				Optional:    true,
+				Description: conflictDetectionDescription,
+			},
+			"audit_log_path": schema.StringAttribute{
+				Optional:    true,
+				Description: auditLogDescription,
+			},
+			"audit_log_label": schema.StringAttribute{
+				Optional:    true,
+				Description: auditLogLabelDescription,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"default_attributes": schema.ListNestedBlock{
+				Description: defaultAttributesDescription,
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"normal_json": schema.StringAttribute{
+							Optional:    true,
+							Description: defaultAttributesNormalDescription,
+						},
+						"default_json": schema.StringAttribute{
+							Optional:    true,
+							Description: defaultAttributesDefaultDescription,
+						},
+						"override_json": schema.StringAttribute{
+							Optional:    true,
+							Description: defaultAttributesOverrideDescription,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *frameworkProvider) Configure(ctx context.Context, req fwprovider.ConfigureRequest, resp *fwprovider.ConfigureResponse) {
+	var data frameworkProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Provider settings that depend on other resources are only known
+	// during apply; resources will be configured again at that point.
+	if data.ServerURL.IsUnknown() || data.ClientName.IsUnknown() || data.PrivateKeyPem.IsUnknown() || data.KeyMaterial.IsUnknown() ||
+		data.ConflictDetection.IsUnknown() || data.ReadOnly.IsUnknown() || data.AuditLogPath.IsUnknown() || data.AuditLogLabel.IsUnknown() {
+		return
+	}
+
+	var defaults []frameworkProviderDefaultAttributesModel
+	resp.Diagnostics.Append(data.DefaultAttributes.ElementsAs(ctx, &defaults, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	for _, d := range defaults {
+		if d.NormalJson.IsUnknown() || d.DefaultJson.IsUnknown() || d.OverrideJson.IsUnknown() {
+			return
+		}
+	}
+
+	cfg := chefClientConfig{
+		ServerURL:          stringValueOrEnv(data.ServerURL, "CHEF_SERVER_URL"),
+		ClientName:         stringValueOrEnv(data.ClientName, "CHEF_CLIENT_NAME"),
+		AllowUnverifiedSSL: data.AllowUnverifiedSSL.ValueBool(),
+		ValidateReferences: data.ValidateReferences.ValueBool(),
+		ConflictDetection:  data.ConflictDetection.ValueString(),
+		ReadOnly:           data.ReadOnly.ValueBool(),
+		AuditLogPath:       data.AuditLogPath.ValueString(),
+		AuditLogLabel:      data.AuditLogLabel.ValueString(),
+	}
+	cfg.DefaultAttributesCount = len(defaults)
+	if len(defaults) > 0 {
+		cfg.DefaultAttributes = map[string]string{
+			"normal_json":   defaults[0].NormalJson.ValueString(),
+			"default_json":  defaults[0].DefaultJson.ValueString(),
+			"override_json": defaults[0].OverrideJson.ValueString(),
+		}
+	}
+
+	privateKeyPem := data.PrivateKeyPem.ValueString()
+	if data.PrivateKeyPem.IsNull() {
+		v, err := providerPrivateKeyEnvDefault()
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("private_key_pem"), "Error reading CHEF_PRIVATE_KEY_FILE", err.Error())
+			return
+		}
+		privateKeyPem, _ = v.(string)
+	}
+	if privateKeyPem != "" {
+		cfg.Key = privateKeyPem
+	}
+	if keyMaterial := stringValueOrEnv(data.KeyMaterial, "CHEF_KEY_MATERIAL"); keyMaterial != "" {
+		cfg.Key = keyMaterial
+	}
+
+	client, err := newChefClient(cfg)
+	if err != nil {
+		attribute := "client_name"
+		if cfgErr, ok := err.(*chefClientConfigError); ok {
+			attribute = cfgErr.Attribute
+		}
+		resp.Diagnostics.AddAttributeError(path.Root(attribute), "Error creating Chef Client", err.Error())
+		return
+	}
+
+	resp.DataSourceData = client
+	resp.EphemeralResourceData = client
+	resp.ResourceData = client
+}
+
+func (p *frameworkProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		NewBootstrapBundleResource,
+		NewClientKeyResource,
+		NewClientResource,
+		NewDataBagItemResource,
+		NewDataBagResource,
+		NewEnvironmentResource,
+		NewNodeAttributeResource,
+		NewNodeResource,
+		NewRoleResource,
+		NewUserKeyResource,
+	}
+}
+
+func (p *frameworkProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		NewEnvironmentDataSource,
+		NewNodeDataSource,
+	}
+}
+
+func (p *frameworkProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		NewClientCredentialsEphemeralResource,
+		NewDataBagItemEphemeralResource,
+	}
+}
+
+func (p *frameworkProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		NewDeepMergeFunction,
+		NewNormalizeRunListFunction,
+		NewParseRunListItemFunction,
+		NewVersionSatisfiesFunction,
+	}
+}
+
+func stringValueOrEnv(v types.String, env string) string {
+	if v.IsNull() {
+		return os.Getenv(env)
+	}
+	return v.ValueString()
+}
+
+// requireClient adds an error to diags if the provider did not configure a
+// client, which it does not do while any of its settings is unknown.
+func requireClient(client *chefClient, diags *diag.Diagnostics) bool {
+	if client != nil {
+		return true
+	}
+	diags.AddError("Provider not configured",
+		"The chef provider has not been configured, because some of its settings depend on values that are not known yet. "+
+			"Apply the resources they depend on first, for example with -target.")
+	return false
+}
+
+// frameworkResource holds the configured client for resources implemented
+// with terraform-plugin-framework. The client is nil while the provider
+// settings are unknown: Read then keeps the prior state, and changes fail.
+type frameworkResource struct {
+	client *chefClient
+}
+
+func (r *frameworkResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*chefClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *chefClient, got %T", req.ProviderData))
+		return
+	}
+	r.client = client
+}
+
+// defaultFalse fills in a boolean argument that only affects creation or
+// destruction, such as adopt_existing, for state that was imported or
+// written before the argument existed.
+func defaultFalse(v *types.Bool) {
+	if v.IsNull() {
+		*v = types.BoolValue(false)
+	}
+}
+
+// frameworkDataSource is the data source counterpart of frameworkResource.
+type frameworkDataSource struct {
+	client *chefClient
+}
+
+func (d *frameworkDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*chefClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *chefClient, got %T", req.ProviderData))
+		return
+	}
+	d.client = client
+}
+
+// frameworkEphemeralResource is the ephemeral resource counterpart of
+// frameworkResource.
+type frameworkEphemeralResource struct {
+	client *chefClient
+}
+
+func (e *frameworkEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*chefClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *chefClient, got %T", req.ProviderData))
+		return
+	}
+	e.client = client
+}
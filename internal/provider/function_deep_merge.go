@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ function.Function = &deepMergeFunction{}
+
+func NewDeepMergeFunction() function.Function {
+	return &deepMergeFunction{}
+}
+
+type deepMergeFunction struct{}
+
+func (f *deepMergeFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "deep_merge"
+}
+
+func (f *deepMergeFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Merge attribute objects the way Chef merges precedence levels",
+		Description: "Merges the objects from lowest to highest precedence. Nested objects are merged key by key, " +
+			"while any other value, including lists, replaces the value from lower precedence. " +
+			"Null values never replace a lower precedence value.",
+		VariadicParameter: function.DynamicParameter{
+			Name:           "attributes",
+			AllowNullValue: true,
+		},
+		Return: function.DynamicReturn{},
+	}
+}
+
+func (f *deepMergeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var args []types.Dynamic
+	resp.Error = req.Arguments.Get(ctx, &args)
+	if resp.Error != nil {
+		return
+	}
+
+	var merged interface{} = map[string]interface{}{}
+	for i, arg := range args {
+		v, _, err := jsonFromAttrValue(arg)
+		if err != nil {
+			resp.Error = function.NewArgumentFuncError(int64(i), err.Error())
+			return
+		}
+		if _, ok := v.(map[string]interface{}); !ok && v != nil {
+			resp.Error = function.NewArgumentFuncError(int64(i), fmt.Sprintf("Argument %d is not an object.", i+1))
+			return
+		}
+		merged = hashOnlyMerge(merged, v)
+	}
+
+	resp.Error = resp.Result.Set(ctx, types.DynamicValue(attrValueFromJson(merged)))
+}
+
+// hashOnlyMerge merges b onto a like Chef::Mixin::DeepMerge.hash_only_merge,
+// which is how node attributes of different precedence levels are combined.
+func hashOnlyMerge(a, b interface{}) interface{} {
+	aMap, aOk := a.(map[string]interface{})
+	bMap, bOk := b.(map[string]interface{})
+	if !aOk || !bOk {
+		if b == nil {
+			return a
+		}
+		return b
+	}
+
+	result := make(map[string]interface{}, len(aMap)+len(bMap))
+	for k, v := range aMap {
+		result[k] = v
+	}
+	for k, v := range bMap {
+		if existing, ok := result[k]; ok {
+			result[k] = hashOnlyMerge(existing, v)
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
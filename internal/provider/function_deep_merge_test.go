@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestDeepMergeFunction(t *testing.T) {
+	dynamicFromJson := func(s string) types.Dynamic {
+		v, err := decodeJson(s)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return types.DynamicValue(attrValueFromJson(v))
+	}
+	args := func(values ...types.Dynamic) attr.Value {
+		elemTypes := make([]attr.Type, len(values))
+		elems := make([]attr.Value, len(values))
+		for i, v := range values {
+			elemTypes[i] = types.DynamicType
+			elems[i] = v
+		}
+		return types.TupleValueMust(elemTypes, elems)
+	}
+
+	got, funcErr := testRunFunction(t, NewDeepMergeFunction(), args(
+		dynamicFromJson(`{"a":{"b":1,"c":[1,2]},"d":"low","e":"kept"}`),
+		types.DynamicNull(),
+		dynamicFromJson(`{"a":{"c":[3],"f":true},"d":"high","e":null}`),
+	))
+	if funcErr != nil {
+		t.Fatalf("err: %s", funcErr)
+	}
+
+	v, _, err := jsonFromAttrValue(got)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	gotJson, err := attributesJson(v)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	want := `{"a":{"b":1,"c":[3],"f":true},"d":"high","e":"kept"}`
+	if !jsonEquivalent(gotJson, want) {
+		t.Errorf("got %s, want %s", gotJson, want)
+	}
+
+	if _, funcErr := testRunFunction(t, NewDeepMergeFunction(), args(dynamicFromJson(`[1]`))); funcErr == nil {
+		t.Errorf("expected an error for an argument that is not an object")
+	}
+}
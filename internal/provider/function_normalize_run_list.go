@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ function.Function = &normalizeRunListFunction{}
+
+func NewNormalizeRunListFunction() function.Function {
+	return &normalizeRunListFunction{}
+}
+
+type normalizeRunListFunction struct{}
+
+func (f *normalizeRunListFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "normalize_run_list"
+}
+
+func (f *normalizeRunListFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Normalize a run list the way the Chef server does",
+		Description: "Qualifies naked recipes such as `foo` as `recipe[foo]` and drops repeated entries, " +
+			"keeping the first occurrence. Fails if an entry is not a valid recipe or role.",
+		Parameters: []function.Parameter{
+			function.ListParameter{
+				Name:        "run_list",
+				ElementType: types.StringType,
+			},
+		},
+		Return: function.ListReturn{
+			ElementType: types.StringType,
+		},
+	}
+}
+
+func (f *normalizeRunListFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var runList []string
+	resp.Error = req.Arguments.Get(ctx, &runList)
+	if resp.Error != nil {
+		return
+	}
+
+	normalized, err := normalizeRunList(runList)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, normalized)
+}
+
+// normalizeRunList qualifies every entry and removes duplicates, as
+// Chef::RunList does when entries are added to it.
+func normalizeRunList(runList []string) ([]string, error) {
+	normalized := make([]string, 0, len(runList))
+	seen := make(map[string]bool, len(runList))
+	for i, entry := range runList {
+		entry = runListEntryStateFunc(strings.TrimSpace(entry))
+		if err := validateRunListEntrySyntax(entry); err != nil {
+			return nil, fmt.Errorf("run_list.%d: %s", i, err)
+		}
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		normalized = append(normalized, entry)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,27 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestNormalizeRunListFunction(t *testing.T) {
+	ctx := context.Background()
+	in, _ := types.ListValueFrom(ctx, types.StringType, []string{"foo", " role[bar] ", "recipe[foo]", "baz::qux@1.2.3"})
+
+	got, funcErr := testRunFunction(t, NewNormalizeRunListFunction(), in)
+	if funcErr != nil {
+		t.Fatalf("err: %s", funcErr)
+	}
+	want, _ := types.ListValueFrom(ctx, types.StringType, []string{"recipe[foo]", "role[bar]", "recipe[baz::qux@1.2.3]"})
+	if !got.Equal(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	invalid, _ := types.ListValueFrom(ctx, types.StringType, []string{"foo", "role[]"})
+	if _, funcErr := testRunFunction(t, NewNormalizeRunListFunction(), invalid); funcErr == nil {
+		t.Errorf("expected an error for an invalid entry")
+	}
+}
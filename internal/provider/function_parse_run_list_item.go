@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ function.Function = &parseRunListItemFunction{}
+
+func NewParseRunListItemFunction() function.Function {
+	return &parseRunListItemFunction{}
+}
+
+type parseRunListItemFunction struct{}
+
+var runListItemAttrTypes = map[string]attr.Type{
+	"type":     types.StringType,
+	"name":     types.StringType,
+	"cookbook": types.StringType,
+	"recipe":   types.StringType,
+	"version":  types.StringType,
+}
+
+func (f *parseRunListItemFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "parse_run_list_item"
+}
+
+func (f *parseRunListItemFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Parse a run list entry into its parts",
+		Description: "Returns an object with the `type` of the entry, `recipe` or `role`, and its `name`. " +
+			"For recipes, `cookbook` and `recipe` are set, with `recipe` being `default` when the entry " +
+			"names only a cookbook, and `version` is set when the entry is pinned. Naked recipes are accepted.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name: "item",
+			},
+		},
+		Return: function.ObjectReturn{
+			AttributeTypes: runListItemAttrTypes,
+		},
+	}
+}
+
+func (f *parseRunListItemFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var item string
+	resp.Error = req.Arguments.Get(ctx, &item)
+	if resp.Error != nil {
+		return
+	}
+
+	entry := runListEntryStateFunc(strings.TrimSpace(item))
+	if err := validateRunListEntrySyntax(entry); err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	attrs := map[string]attr.Value{
+		"cookbook": types.StringNull(),
+		"recipe":   types.StringNull(),
+		"version":  types.StringNull(),
+	}
+	if m := runListRoleRegexp.FindStringSubmatch(entry); m != nil {
+		attrs["type"] = types.StringValue("role")
+		attrs["name"] = types.StringValue(m[1])
+	} else {
+		m := runListRecipeRegexp.FindStringSubmatch(entry)
+		recipe := m[2]
+		if recipe == "" {
+			recipe = "default"
+		}
+		attrs["type"] = types.StringValue("recipe")
+		attrs["name"] = types.StringValue(m[1] + "::" + recipe)
+		attrs["cookbook"] = types.StringValue(m[1])
+		attrs["recipe"] = types.StringValue(recipe)
+		if m[3] != "" {
+			attrs["version"] = types.StringValue(m[3])
+		}
+	}
+
+	result, diags := types.ObjectValue(runListItemAttrTypes, attrs)
+	if diags.HasError() {
+		resp.Error = function.FuncErrorFromDiags(ctx, diags)
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, result)
+}
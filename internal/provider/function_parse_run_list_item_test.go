@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestParseRunListItemFunction(t *testing.T) {
+	null := types.StringNull()
+	str := types.StringValue
+
+	cases := []struct {
+		item string
+		want map[string]attr.Value
+	}{
+		{"foo", map[string]attr.Value{
+			"type": str("recipe"), "name": str("foo::default"), "cookbook": str("foo"), "recipe": str("default"), "version": null,
+		}},
+		{"recipe[foo::bar@1.2.3]", map[string]attr.Value{
+			"type": str("recipe"), "name": str("foo::bar"), "cookbook": str("foo"), "recipe": str("bar"), "version": str("1.2.3"),
+		}},
+		{"role[web]", map[string]attr.Value{
+			"type": str("role"), "name": str("web"), "cookbook": null, "recipe": null, "version": null,
+		}},
+	}
+	for _, c := range cases {
+		got, funcErr := testRunFunction(t, NewParseRunListItemFunction(), str(c.item))
+		if funcErr != nil {
+			t.Fatalf("%s: err: %s", c.item, funcErr)
+		}
+		want := types.ObjectValueMust(runListItemAttrTypes, c.want)
+		if !got.Equal(want) {
+			t.Errorf("%s: got %s, want %s", c.item, got, want)
+		}
+	}
+
+	if _, funcErr := testRunFunction(t, NewParseRunListItemFunction(), str("recipe[foo@1]")); funcErr == nil {
+		t.Errorf("expected an error for an invalid version")
+	}
+}
@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+var _ function.Function = &versionSatisfiesFunction{}
+
+func NewVersionSatisfiesFunction() function.Function {
+	return &versionSatisfiesFunction{}
+}
+
+type versionSatisfiesFunction struct{}
+
+func (f *versionSatisfiesFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "version_satisfies"
+}
+
+func (f *versionSatisfiesFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Check a cookbook version against a Chef version constraint",
+		Description: "Returns whether `version`, such as `1.2.3`, satisfies a Chef version constraint such as " +
+			"`~> 1.2`, `>= 2.0.0` or `= 1.0.0`. A constraint without an operator is an exact match.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name: "version",
+			},
+			function.StringParameter{
+				Name: "constraint",
+			},
+		},
+		Return: function.BoolReturn{},
+	}
+}
+
+func (f *versionSatisfiesFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var version, constraint string
+	resp.Error = req.Arguments.Get(ctx, &version, &constraint)
+	if resp.Error != nil {
+		return
+	}
+
+	v, err := parseChefVersion(version)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	c, err := parseChefVersionConstraint(constraint)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, err.Error())
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, c.satisfiedBy(v))
+}
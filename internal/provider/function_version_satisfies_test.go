@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestVersionSatisfiesFunction(t *testing.T) {
+	cases := []struct {
+		version, constraint string
+		want                bool
+	}{
+		{"1.2.3", "~> 1.2", true},
+		{"2.0.0", "~> 1.2", false},
+		{"1.2.9", "~> 1.2.3", true},
+		{"1.3.0", "~> 1.2.3", false},
+		{"2.0", ">= 2.0.0", true},
+		{"1.9.9", ">= 2.0.0", false},
+		{"1.0.0", "= 1.0.0", true},
+		{"1.0.1", "1.0.0", false},
+		{"0.9.0", "< 1.0", true},
+	}
+	for _, c := range cases {
+		got, funcErr := testRunFunction(t, NewVersionSatisfiesFunction(), types.StringValue(c.version), types.StringValue(c.constraint))
+		if funcErr != nil {
+			t.Fatalf("%s %s: err: %s", c.version, c.constraint, funcErr)
+		}
+		if !got.Equal(types.BoolValue(c.want)) {
+			t.Errorf("version_satisfies(%q, %q) = %s, want %v", c.version, c.constraint, got, c.want)
+		}
+	}
+
+	if _, funcErr := testRunFunction(t, NewVersionSatisfiesFunction(), types.StringValue("1"), types.StringValue(">= 1.0")); funcErr == nil {
+		t.Errorf("expected an error for an invalid version")
+	}
+	if _, funcErr := testRunFunction(t, NewVersionSatisfiesFunction(), types.StringValue("1.0"), types.StringValue("!= 1.0")); funcErr == nil {
+		t.Errorf("expected an error for an invalid constraint")
+	}
+}
@@ -2,14 +2,19 @@ package provider
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
 
 	chefc "github.com/go-chef/chef"
 )
@@ -19,31 +24,24 @@ func New(version string) func() *schema.Provider {
 		return &schema.Provider{
 			ConfigureContextFunc: providerConfigure,
 			DataSourcesMap: map[string]*schema.Resource{
-				"chef_environment": dataChefEnvironment(),
-				"chef_node":        dataChefNode(),
-				"chef_search":      dataChefSearch(),
-			},
-			ResourcesMap: map[string]*schema.Resource{
-				"chef_data_bag":      resourceChefDataBag(),
-				"chef_data_bag_item": resourceChefDataBagItem(),
-				"chef_environment":   resourceChefEnvironment(),
-				"chef_client":        resourceChefClient(),
-				"chef_client_key":    resourceChefClientKey(),
-				"chef_node":          resourceChefNode(),
-				"chef_role":          resourceChefRole(),
-				"chef_user_key":      resourceChefUserKey(),
+				"chef_cookbook":          dataChefCookbook(),
+				"chef_cookbook_solution": dataChefCookbookSolution(),
+				"chef_cookbook_versions": dataChefCookbookVersions(),
+				"chef_expanded_run_list": dataChefExpandedRunList(),
+				"chef_recipes":           dataChefRecipes(),
+				"chef_search":            dataChefSearch(),
 			},
 			Schema: map[string]*schema.Schema{
 				"server_url": {
 					Type:         schema.TypeString,
-					Required:     true,
+					Optional:     true,
 					DefaultFunc:  schema.EnvDefaultFunc("CHEF_SERVER_URL", nil),
 					Description:  "URL of the root of the target Chef server or organization.",
 					ValidateFunc: validateServerURL,
 				},
 				"client_name": {
 					Type:        schema.TypeString,
-					Required:    true,
+					Optional:    true,
 					DefaultFunc: schema.EnvDefaultFunc("CHEF_CLIENT_NAME", nil),
 					Description: "Name of a registered client within the Chef server.",
 				},
@@ -64,6 +62,55 @@ func New(version string) func() *schema.Provider {
 					Optional:    true,
 					Description: "If set, the Chef client will permit unverifiable SSL certificates.",
 				},
+				"validate_references": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Description: "If set, run lists, roles and environments referenced by nodes and roles, and the cookbook constraints of environments, are checked against the Chef server during plan. Objects created in the same plan must be referenced through their `id` so that the check is deferred until they exist.",
+				},
+				"read_only": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Description: readOnlyDescription,
+				},
+				"conflict_detection": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: conflictDetectionDescription,
+				},
+				"audit_log_path": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: auditLogDescription,
+				},
+				"audit_log_label": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: auditLogLabelDescription,
+				},
+				"default_attributes": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Description: defaultAttributesDescription,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"normal_json": {
+								Type:        schema.TypeString,
+								Optional:    true,
+								Description: defaultAttributesNormalDescription,
+							},
+							"default_json": {
+								Type:        schema.TypeString,
+								Optional:    true,
+								Description: defaultAttributesDefaultDescription,
+							},
+							"override_json": {
+								Type:        schema.TypeString,
+								Optional:    true,
+								Description: defaultAttributesOverrideDescription,
+							},
+						},
+					},
+				},
 			},
 		}
 	}
@@ -72,6 +119,10 @@ func New(version string) func() *schema.Provider {
 type chefClient struct {
 	*chefc.Client
 	Global *chefc.Client
+
+	ValidateReferences bool
+	ConflictDetection  string
+	DefaultAttributes  defaultAttributes
 }
 
 func validateServerURL(val interface{}, key string) (warns []string, errs []error) {
@@ -82,50 +133,172 @@ func validateServerURL(val interface{}, key string) (warns []string, errs []erro
 	return
 }
 
-func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+// chefClientConfig holds the provider settings shared by the SDKv2 and
+// framework providers, after environment defaults have been applied.
+type chefClientConfig struct {
+	ServerURL          string
+	ClientName         string
+	Key                string
+	AllowUnverifiedSSL bool
+	ValidateReferences bool
+	ConflictDetection  string
+	ReadOnly           bool
+	AuditLogPath       string
+	AuditLogLabel      string
+
+	// DefaultAttributes holds the JSON arguments of the default_attributes
+	// block by name. The block may be given at most once.
+	DefaultAttributes      map[string]string
+	DefaultAttributesCount int
+}
+
+// chefClientConfigError is returned by newChefClient when a setting is
+// missing or invalid, so that diagnostics can point at the attribute.
+type chefClientConfigError struct {
+	Attribute string
+	Err       error
+}
+
+func (e *chefClientConfigError) Error() string {
+	return e.Err.Error()
+}
+
+// chefTransport, when set, wraps the HTTP transport of every Chef client.
+// The acceptance tests use it to record and replay Chef server traffic.
+var chefTransport func(http.RoundTripper) http.RoundTripper
+
+func newChefClient(cfg chefClientConfig) (*chefClient, error) {
+	if cfg.ServerURL == "" {
+		return nil, &chefClientConfigError{"server_url", fmt.Errorf("server_url must be set, either in the provider configuration or with CHEF_SERVER_URL")}
+	}
+	if _, errs := validateServerURL(cfg.ServerURL, "server_url"); len(errs) > 0 {
+		return nil, &chefClientConfigError{"server_url", errs[0]}
+	}
+	if cfg.ClientName == "" {
+		return nil, &chefClientConfigError{"client_name", fmt.Errorf("client_name must be set, either in the provider configuration or with CHEF_CLIENT_NAME")}
+	}
+	if cfg.ConflictDetection != "" && !slices.Contains(conflictDetectionModes, cfg.ConflictDetection) {
+		return nil, &chefClientConfigError{"conflict_detection", fmt.Errorf("conflict_detection must be one of %s, got %q", strings.Join(conflictDetectionModes, ", "), cfg.ConflictDetection)}
+	}
+	if cfg.DefaultAttributesCount > 1 {
+		return nil, &chefClientConfigError{"default_attributes", fmt.Errorf("default_attributes may only be given once")}
+	}
+	if cfg.AuditLogPath != "" {
+		if err := openAuditLog(cfg.AuditLogPath); err != nil {
+			return nil, &chefClientConfigError{"audit_log_path", fmt.Errorf("audit_log_path cannot be written: %w", err)}
+		}
+	}
+	defaults, err := parseDefaultAttributes(cfg.DefaultAttributes["normal_json"], cfg.DefaultAttributes["default_json"], cfg.DefaultAttributes["override_json"])
+	if err != nil {
+		return nil, err
+	}
+
 	config := &chefc.Config{
-		Name:    d.Get("client_name").(string),
-		BaseURL: d.Get("server_url").(string),
-		SkipSSL: d.Get("allow_unverified_ssl").(bool),
+		Name:    cfg.ClientName,
+		BaseURL: cfg.ServerURL,
+		Key:     cfg.Key,
+		SkipSSL: cfg.AllowUnverifiedSSL,
 		Timeout: 10,
 	}
 
+	// go-chef ignores SkipSSL and Timeout when given a client, so they are
+	// applied to the wrapped transport here instead.
+	readOnly := cfg.ReadOnly || readOnlyForced()
+	var audit *auditTransport
+	if chefTransport != nil || readOnly || cfg.AuditLogPath != "" {
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		base.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.AllowUnverifiedSSL}
+		var transport http.RoundTripper = base
+		if chefTransport != nil {
+			transport = chefTransport(transport)
+		}
+		if readOnly {
+			transport = &readOnlyTransport{base: transport}
+		}
+		if cfg.AuditLogPath != "" {
+			audit = &auditTransport{base: transport, path: cfg.AuditLogPath, label: cfg.AuditLogLabel, clientName: cfg.ClientName}
+			transport = audit
+		}
+		config.Client = &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(config.Timeout) * time.Second,
+		}
+	}
+
+	client, err := chefc.NewClient(config)
+	if err != nil {
+		return nil, &chefClientConfigError{"client_name", err}
+	}
+	if audit != nil {
+		audit.signer = client
+	}
+
+	globalClient := client
+	if split := strings.Split(config.BaseURL, "/organizations/"); len(split) > 1 {
+		config.BaseURL = split[0]
+		globalClient, err = chefc.NewClient(config)
+		if err != nil {
+			return nil, &chefClientConfigError{"client_name", err}
+		}
+	}
+
+	return &chefClient{
+		Client:             client,
+		Global:             globalClient,
+		ValidateReferences: cfg.ValidateReferences,
+		ConflictDetection:  cfg.ConflictDetection,
+		DefaultAttributes:  defaults,
+	}, nil
+}
+
+func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	cfg := chefClientConfig{
+		ServerURL:          d.Get("server_url").(string),
+		ClientName:         d.Get("client_name").(string),
+		AllowUnverifiedSSL: d.Get("allow_unverified_ssl").(bool),
+		ValidateReferences: d.Get("validate_references").(bool),
+		ConflictDetection:  d.Get("conflict_detection").(string),
+		ReadOnly:           d.Get("read_only").(bool),
+		AuditLogPath:       d.Get("audit_log_path").(string),
+		AuditLogLabel:      d.Get("audit_log_label").(string),
+	}
+
 	if v, ok := d.GetOk("private_key_pem"); ok {
-		config.Key = v.(string)
+		cfg.Key = v.(string)
 	}
 
 	if v, ok := d.GetOk("key_material"); ok {
-		config.Key = v.(string)
+		cfg.Key = v.(string)
 	}
 
-	client, err := chefc.NewClient(config)
+	blocks := d.Get("default_attributes").([]interface{})
+	cfg.DefaultAttributesCount = len(blocks)
+	if len(blocks) > 0 {
+		cfg.DefaultAttributes = map[string]string{}
+		if block, ok := blocks[0].(map[string]interface{}); ok {
+			for k, v := range block {
+				cfg.DefaultAttributes[k] = v.(string)
+			}
+		}
+	}
+
+	client, err := newChefClient(cfg)
 	if err != nil {
+		attribute := "client_name"
+		if cfgErr, ok := err.(*chefClientConfigError); ok {
+			attribute = cfgErr.Attribute
+		}
 		return nil, diag.Diagnostics{
 			{
 				Severity:      diag.Error,
 				Summary:       "Error creating Chef Client",
 				Detail:        fmt.Sprint(err),
-				AttributePath: cty.GetAttrPath("client_name"),
+				AttributePath: cty.GetAttrPath(attribute),
 			},
 		}
 	}
-	if split := strings.Split(config.BaseURL, "/organizations/"); len(split) > 1 {
-		config.BaseURL = split[0]
-		globalClient, err := chefc.NewClient(config)
-		if err != nil {
-			return nil, diag.Diagnostics{
-				{
-					Severity:      diag.Error,
-					Summary:       "Error creating Chef Client",
-					Detail:        fmt.Sprint(err),
-					AttributePath: cty.GetAttrPath("client_name"),
-				},
-			}
-		}
-		return &chefClient{client, globalClient}, nil
-	}
 
-	return &chefClient{client, client}, nil
+	return client, nil
 }
 
 func providerPrivateKeyEnvDefault() (interface{}, error) {
@@ -140,15 +313,22 @@ func providerPrivateKeyEnvDefault() (interface{}, error) {
 	return nil, nil
 }
 
-func jsonStateFunc(value interface{}) string {
-	// Parse and re-stringify the JSON to make sure it's always kept
-	// in a normalized form.
-	jsonValue, err := structure.NormalizeJsonString(value)
-	if err != nil {
-		return "null"
+// attributesJson renders an attribute map as JSON. A missing map is rendered
+// as an empty object to match the "{}" default of the *_json arguments,
+// otherwise imported objects would show a perpetual diff.
+func attributesJson(value interface{}) (string, error) {
+	if value == nil {
+		return "{}", nil
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Map && v.IsNil() {
+		return "{}", nil
 	}
 
-	return jsonValue
+	attrJson, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(attrJson), nil
 }
 
 func runListEntryStateFunc(value interface{}) string {
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"log"
@@ -9,34 +10,72 @@ import (
 	"testing"
 	"text/template"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/bdwyertech/terraform-provider-chef/internal/chefzero"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
-// To run these acceptance tests, you will need access to a Chef server.
-// An easy way to get one is to sign up for a hosted Chef server account
-// at https://manage.chef.io/signup , after which your base URL will
-// be something like https://api.opscode.com/organizations/example/ .
-// You will also need to create a "client" and write its private key to
-// a file somewhere.
+// The acceptance tests run against an in-memory fake Chef server from the
+// internal/chefzero package, which is started when TF_ACC is set:
+//    make testacc TEST=./internal/provider
 //
-// You can then set the following environment variables to make these
-// tests work:
-// CHEF_SERVER_URL to the base URL as described above.
-// CHEF_CLIENT_NAME to the name of the client object you created.
-// CHEF_KEY_MATERIAL the key file contents.
+// To run them against a real Chef server instead, set TF_ACC_REAL_CHEF
+// along with the following environment variables:
+// CHEF_SERVER_URL to the base URL of the organization, something like
+// https://chef.example.com/organizations/example/ .
+// CHEF_CLIENT_NAME to the name of a client of the organization.
+// CHEF_KEY_MATERIAL the key file contents of that client.
 //
-// You will probably need to edit the global permissions on your Chef
-// Server account to allow this client (or all clients, if you're lazy)
-// to have both List and Create access on all types of object:
-//     https://manage.chef.io/organizations/yourorg/global_permissions
+// You will probably need to edit the global permissions of the organization
+// to allow this client (or all clients, if you're lazy) to have both List
+// and Create access on all types of object. The user key tests also expect
+// the client to be able to manage the keys of the bdwyertech-github user.
 //
-// With all of that done, you can run like this:
-//    make testacc TEST=./builtin/providers/chef
+// The traffic of a run can also be recorded into cassettes and replayed
+// without any server, see cassette_test.go:
+//    TF_ACC_REAL_CHEF=1 TF_ACC_CASSETTES=record make testacc TEST=./internal/provider
+//    TF_ACC_CASSETTES=replay make testacc TEST=./internal/provider
 
-var testAccProvider *schema.Provider
+// testAccUser is the user whose keys the user key tests manage.
+const testAccUser = "bdwyertech-github"
+
+func TestMain(m *testing.M) {
+	if os.Getenv("TF_ACC") == "" {
+		os.Exit(m.Run())
+	}
+
+	mode := os.Getenv("TF_ACC_CASSETTES")
+	var server *chefzero.Server
+	if os.Getenv("TF_ACC_REAL_CHEF") == "" && mode != "replay" {
+		var err error
+		server, err = chefzero.NewServer("terraform")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := server.AddUser(testAccUser); err != nil {
+			log.Fatal(err)
+		}
+		os.Setenv("CHEF_SERVER_URL", server.OrganizationURL())
+		os.Setenv("CHEF_CLIENT_NAME", server.ClientName)
+		os.Setenv("CHEF_KEY_MATERIAL", server.ClientKey)
+	}
+	if mode != "" {
+		if err := testAccCassettes(mode); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	code := m.Run()
+	if server != nil {
+		server.Close()
+	}
+	os.Exit(code)
+}
 
 func init() {
-	testAccProvider = New("dev")()
 	if testSuffix == "" {
 		bytes := make([]byte, 4)
 		if _, err := rand.Read(bytes); err != nil {
@@ -56,22 +95,164 @@ func testSuffixRender(cfg string) string {
 	return b.String()
 }
 
-// providerFactories are used to instantiate a provider during acceptance testing.
+// protoV6ProviderFactories are used to instantiate a provider during acceptance testing.
 // The factory function will be invoked for every Terraform CLI command executed
 // to create a provider server to which the CLI can reattach.
-var providerFactories = map[string]func() (*schema.Provider, error){
-	"chef": func() (*schema.Provider, error) {
-		return testAccProvider, nil
+var protoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
+	"chef": func() (tfprotov6.ProviderServer, error) {
+		server, err := NewMuxServer(context.Background(), "dev")
+		if err != nil {
+			return nil, err
+		}
+		return server(), nil
 	},
 }
 
+// testAccProtocolServer returns a provider server configured from the
+// environment, for tests that call it the way Terraform does instead of
+// running Terraform.
+func testAccProtocolServer(t *testing.T) (tfprotov6.ProviderServer, *tfprotov6.GetProviderSchemaResponse) {
+	t.Helper()
+	ctx := context.Background()
+
+	factory, err := NewMuxServer(ctx, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := factory()
+
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A configuration that sets nothing, so that the provider is configured
+	// from the environment. Terraform sends blocks that are left out as
+	// empty lists and sets.
+	typ := schemas.Provider.ValueType().(tftypes.Object)
+	values := make(map[string]tftypes.Value, len(typ.AttributeTypes))
+	for name, t := range typ.AttributeTypes {
+		values[name] = tftypes.NewValue(t, nil)
+	}
+	for _, b := range schemas.Provider.Block.BlockTypes {
+		values[b.TypeName] = tftypes.NewValue(typ.AttributeTypes[b.TypeName], []tftypes.Value{})
+	}
+	config, err := tfprotov6.NewDynamicValue(typ, tftypes.NewValue(typ, values))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configured, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{Config: &config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkProtocolDiagnostics(t, "configure", configured.Diagnostics)
+	return server, schemas
+}
+
+// checkProtocolDiagnostics fails the test on any error diagnostic returned
+// by a provider server call.
+func checkProtocolDiagnostics(t *testing.T, call string, diags []*tfprotov6.Diagnostic) {
+	t.Helper()
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("%s: %s: %s", call, d.Summary, d.Detail)
+		}
+	}
+}
+
 func TestProvider(t *testing.T) {
 	if err := New("dev")().InternalValidate(); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 }
 
+// TestMuxServer checks that the SDKv2 and framework providers can be served
+// together, which requires identical provider schemas and no resource
+// implemented twice. The descriptions of the provider arguments are shared
+// constants for that reason.
+func TestMuxServer(t *testing.T) {
+	server, err := NewMuxServer(context.Background(), "dev")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resp, err := server().GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, d := range resp.Diagnostics {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Errorf("%s: %s", d.Summary, d.Detail)
+		}
+	}
+}
+
+// TestFrameworkResource_unconfigured checks that resources do not need a
+// client to refresh while the provider settings are unknown, and that
+// changes fail instead.
+func TestFrameworkResource_unconfigured(t *testing.T) {
+	ctx := context.Background()
+	for _, newResource := range NewFramework("dev")().Resources(ctx) {
+		r := newResource()
+		var metadata resource.MetadataResponse
+		r.Metadata(ctx, resource.MetadataRequest{ProviderTypeName: "chef"}, &metadata)
+
+		var readResp resource.ReadResponse
+		r.Read(ctx, resource.ReadRequest{}, &readResp)
+		if readResp.Diagnostics.HasError() {
+			t.Errorf("%s: unexpected error reading without a client: %v", metadata.TypeName, readResp.Diagnostics)
+		}
+
+		var deleteResp resource.DeleteResponse
+		r.Delete(ctx, resource.DeleteRequest{}, &deleteResp)
+		if !deleteResp.Diagnostics.HasError() {
+			t.Errorf("%s: expected an error deleting without a client", metadata.TypeName)
+		}
+	}
+}
+
+// testRunFunction calls a provider function directly, without Terraform.
+// Variadic arguments are passed as a single tuple, as the framework does.
+func testRunFunction(t *testing.T, f function.Function, args ...attr.Value) (attr.Value, *function.FuncError) {
+	t.Helper()
+	ctx := context.Background()
+
+	var def function.DefinitionResponse
+	f.Definition(ctx, function.DefinitionRequest{}, &def)
+	result, funcErr := def.Definition.Return.NewResultData(ctx)
+	if funcErr != nil {
+		t.Fatalf("err: %s", funcErr)
+	}
+
+	resp := function.RunResponse{Result: result}
+	f.Run(ctx, function.RunRequest{Arguments: function.NewArgumentsData(args)}, &resp)
+	return resp.Result.Value(), resp.Error
+}
+
+// testAccClient returns a client configured from the same environment
+// variables as the provider under test, for checking the Chef server
+// directly.
+func testAccClient() (*chefClient, error) {
+	key := os.Getenv("CHEF_KEY_MATERIAL")
+	if key == "" {
+		v, err := providerPrivateKeyEnvDefault()
+		if err != nil {
+			return nil, err
+		}
+		key, _ = v.(string)
+	}
+
+	return newChefClient(chefClientConfig{
+		ServerURL:  os.Getenv("CHEF_SERVER_URL"),
+		ClientName: os.Getenv("CHEF_CLIENT_NAME"),
+		Key:        key,
+	})
+}
+
 func testAccPreCheck(t *testing.T) {
+	testAccCassette(t)
+
 	if v := os.Getenv("CHEF_SERVER_URL"); v == "" {
 		t.Fatal("CHEF_SERVER_URL must be set for acceptance tests")
 	}
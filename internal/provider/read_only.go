@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// readOnlyDescription describes the read_only provider argument.
+const readOnlyDescription = "If set, the provider refuses every request that would change the Chef server, so that it can only plan and read. " +
+	"Data sources keep working. It is also set by the CHEF_PROVIDER_READ_ONLY environment variable, which takes precedence."
+
+// readOnlyEnv forces read_only on when set to true, whatever the provider
+// configuration says.
+const readOnlyEnv = "CHEF_PROVIDER_READ_ONLY"
+
+// readOnlyForced reports whether read_only is forced by the environment.
+func readOnlyForced() bool {
+	forced, _ := strconv.ParseBool(os.Getenv(readOnlyEnv))
+	return forced
+}
+
+// readOnlyTransport refuses the requests of a read-only provider that would
+// change the Chef server.
+type readOnlyTransport struct {
+	base http.RoundTripper
+}
+
+func (t *readOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !changesChefServer(req) {
+		return t.base.RoundTrip(req)
+	}
+	container, object := chefErrorTarget(req.URL.Path)
+	target := container
+	if object != "" {
+		target += "/" + object
+	}
+	action := map[string]string{
+		http.MethodPost:   "create in",
+		http.MethodPut:    "update",
+		http.MethodDelete: "delete",
+	}[req.Method]
+	if action == "" {
+		action = "change"
+	}
+	return nil, fmt.Errorf("the provider is read-only, as read_only or %s is set, and refused to %s %s (%s %s)",
+		readOnlyEnv, action, target, req.Method, req.URL.Path)
+}
+
+// changesChefServer reports whether a request may change the Chef server.
+// Searches are sent with POST but do not change anything.
+func changesChefServer(req *http.Request) bool {
+	container, _ := chefErrorTarget(req.URL.Path)
+	switch {
+	case req.Method == http.MethodGet, req.Method == http.MethodHead:
+		return false
+	case req.Method == http.MethodPost && container == "search":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestReadOnlyTransport(t *testing.T) {
+	transport := &readOnlyTransport{base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Request: req}, nil
+	})}
+
+	allowed := map[string]string{
+		http.MethodGet:  "https://chef.example.com/organizations/test/nodes/web-1",
+		http.MethodHead: "https://chef.example.com/organizations/test/roles",
+		http.MethodPost: "https://chef.example.com/organizations/test/search/node?q=*:*",
+	}
+	for method, url := range allowed {
+		req, _ := http.NewRequest(method, url, nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Errorf("%s %s: unexpected error: %s", method, url, err)
+		}
+	}
+
+	refused := map[string]string{
+		http.MethodPost:   "create in nodes",
+		http.MethodPut:    "update nodes/web-1",
+		http.MethodDelete: "delete nodes/web-1",
+	}
+	for method, expected := range refused {
+		url := "https://chef.example.com/organizations/test/nodes/web-1"
+		if method == http.MethodPost {
+			url = "https://chef.example.com/organizations/test/nodes"
+		}
+		req, _ := http.NewRequest(method, url, nil)
+		_, err := transport.RoundTrip(req)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s %s: expected an error containing %q, got %v", method, url, expected, err)
+		}
+	}
+}
+
+func TestAccProvider_readOnly(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccProviderConfig_readOnlySearch),
+				Check:  resource.TestCheckResourceAttrSet("data.chef_search.test", "total_num"),
+			},
+			{
+				Config:      testSuffixRender(testAccProviderConfig_readOnlyNode),
+				ExpectError: regexp.MustCompile("refused to create in nodes"),
+			},
+		},
+	})
+}
+
+const testAccProviderConfig_readOnlySearch = `
+provider "chef" {
+  read_only = true
+}
+
+data "chef_search" "test" {
+  index = "node"
+  query = "name:terraform-acc-test-read-only-{{.}}"
+}
+`
+
+const testAccProviderConfig_readOnlyNode = `
+provider "chef" {
+  read_only = true
+}
+
+resource "chef_node" "test" {
+  name = "terraform-acc-test-read-only-{{.}}"
+}
+`
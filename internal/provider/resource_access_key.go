@@ -0,0 +1,281 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	chefc "github.com/go-chef/chef"
+)
+
+var (
+	_ resource.ResourceWithImportState = &accessKeyResource{}
+	_ resource.ResourceWithModifyPlan  = &accessKeyResource{}
+)
+
+// accessKeyAPI is the part of the Chef API that manages the keys of one
+// kind of actor, either clients or users.
+type accessKeyAPI interface {
+	AddKey(name string, key chefc.AccessKey) (chefc.KeyItem, error)
+	GetKey(name, keyName string) (chefc.AccessKey, error)
+	UpdateKey(name, keyName string, key chefc.AccessKey) (chefc.AccessKey, error)
+	DeleteKey(name, keyName string) (chefc.AccessKey, error)
+}
+
+// accessKeyResource implements chef_client_key and chef_user_key, which
+// only differ in the actor that owns the key.
+type accessKeyResource struct {
+	frameworkResource
+
+	// Owner is the name of the attribute holding the actor, "client" or
+	// "user".
+	Owner string
+	API   func(client *chefClient) accessKeyAPI
+}
+
+type accessKeyResourceModel struct {
+	ID            types.String
+	Owner         types.String
+	KeyName       types.String
+	PublicKey     types.String
+	AdoptExisting types.Bool
+}
+
+func (r *accessKeyResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + r.Owner + "_key"
+}
+
+func (r *accessKeyResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			r.Owner: schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"key_name": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString("default"),
+			},
+			"public_key": schema.StringAttribute{
+				Required: true,
+			},
+			"adopt_existing": adoptExistingSchema("key"),
+		},
+	}
+}
+
+func (r *accessKeyResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	plan, diags := r.get(ctx, req.Plan.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	r.adoption(plan).plan(ctx, req, &resp.Diagnostics)
+}
+
+// get reads the model from a plan or state. The attribute holding the owner
+// depends on the resource, so the model is read and written attribute by
+// attribute rather than through struct tags.
+func (r *accessKeyResource) get(ctx context.Context, getAttribute func(context.Context, path.Path, interface{}) diag.Diagnostics) (accessKeyResourceModel, diag.Diagnostics) {
+	var m accessKeyResourceModel
+	var diags diag.Diagnostics
+	diags.Append(getAttribute(ctx, path.Root("id"), &m.ID)...)
+	diags.Append(getAttribute(ctx, path.Root(r.Owner), &m.Owner)...)
+	diags.Append(getAttribute(ctx, path.Root("key_name"), &m.KeyName)...)
+	diags.Append(getAttribute(ctx, path.Root("public_key"), &m.PublicKey)...)
+	diags.Append(getAttribute(ctx, path.Root("adopt_existing"), &m.AdoptExisting)...)
+	return m, diags
+}
+
+func (r *accessKeyResource) set(ctx context.Context, setAttribute func(context.Context, path.Path, interface{}) diag.Diagnostics, m accessKeyResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags.Append(setAttribute(ctx, path.Root("id"), m.ID)...)
+	diags.Append(setAttribute(ctx, path.Root(r.Owner), m.Owner)...)
+	diags.Append(setAttribute(ctx, path.Root("key_name"), m.KeyName)...)
+	diags.Append(setAttribute(ctx, path.Root("public_key"), m.PublicKey)...)
+	diags.Append(setAttribute(ctx, path.Root("adopt_existing"), m.AdoptExisting)...)
+	return diags
+}
+
+func (m accessKeyResourceModel) accessKey() chefc.AccessKey {
+	return chefc.AccessKey{
+		Name:           m.KeyName.ValueString(),
+		PublicKey:      m.PublicKey.ValueString(),
+		ExpirationDate: "infinity",
+	}
+}
+
+func (r *accessKeyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	plan, diags := r.get(ctx, req.Plan.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	key := plan.accessKey()
+	resp.Diagnostics.Append(r.adoption(plan).create(ctx, func() error {
+		_, err := r.API(r.client).AddKey(plan.Owner.ValueString(), key)
+		return err
+	})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ID = types.StringValue(plan.Owner.ValueString() + "+" + key.Name)
+	r.readInto(ctx, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(r.set(ctx, resp.State.SetAttribute, plan)...)
+}
+
+func (r *accessKeyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.client == nil {
+		return
+	}
+
+	state, diags := r.get(ctx, req.State.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	k, err := r.API(r.client).GetKey(state.Owner.ValueString(), state.KeyName.ValueString())
+	if err != nil {
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(chefDiagnostic(fmt.Sprintf("Error reading %s key", r.Owner), err, path.Root("key_name")))
+		return
+	}
+
+	state.setKey(k)
+	defaultFalse(&state.AdoptExisting)
+	resp.Diagnostics.Append(r.set(ctx, resp.State.SetAttribute, state)...)
+}
+
+func (r *accessKeyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	plan, diags := r.get(ctx, req.Plan.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+	state, diags := r.get(ctx, req.State.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Renaming a key is done by updating the key under its previous name.
+	key := plan.accessKey()
+	if _, err := r.API(r.client).UpdateKey(plan.Owner.ValueString(), state.KeyName.ValueString(), key); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic(fmt.Sprintf("Error updating %s key", r.Owner), err, path.Root("key_name")))
+		return
+	}
+
+	plan.ID = types.StringValue(plan.Owner.ValueString() + "+" + key.Name)
+	r.readInto(ctx, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(r.set(ctx, resp.State.SetAttribute, plan)...)
+}
+
+func (r *accessKeyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	state, diags := r.get(ctx, req.State.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if _, err := r.API(r.client).DeleteKey(state.Owner.ValueString(), state.KeyName.ValueString()); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic(fmt.Sprintf("Error deleting %s key", r.Owner), err, path.Root("key_name")))
+	}
+}
+
+func (r *accessKeyResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	parts := strings.Split(req.ID, "+")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		resp.Diagnostics.AddError("Unexpected import identifier",
+			fmt.Sprintf("unexpected format of ID (%s), expected %s+key_name", req.ID, r.Owner))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root(r.Owner), parts[0])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("key_name"), parts[1])...)
+}
+
+// adoption takes over an existing key by updating its public key and
+// expiration date.
+func (r *accessKeyResource) adoption(m accessKeyResourceModel) adoption {
+	name := ""
+	if !m.Owner.IsUnknown() && !m.KeyName.IsUnknown() {
+		name = m.Owner.ValueString() + "+" + m.KeyName.ValueString()
+	}
+	return adoption{
+		Kind:      r.Owner + " key",
+		Name:      name,
+		Adopt:     m.AdoptExisting,
+		Attribute: path.Root("key_name"),
+		Get: func() (interface{}, error) {
+			return r.API(r.client).GetKey(m.Owner.ValueString(), m.KeyName.ValueString())
+		},
+		Update: func() error {
+			_, err := r.API(r.client).UpdateKey(m.Owner.ValueString(), m.KeyName.ValueString(), m.accessKey())
+			return err
+		},
+	}
+}
+
+// readInto reads a key back from the Chef server after it was written.
+func (r *accessKeyResource) readInto(ctx context.Context, m *accessKeyResourceModel, diags *diag.Diagnostics) {
+	k, err := r.API(r.client).GetKey(m.Owner.ValueString(), m.KeyName.ValueString())
+	if err != nil {
+		diags.Append(chefDiagnostic(fmt.Sprintf("Error reading %s key", r.Owner), err, path.Root("key_name")))
+		return
+	}
+	m.setKey(k)
+}
+
+// setKey records a key read from the Chef server. The server may reformat
+// the public key, so a key that only differs in surrounding whitespace is
+// left as configured.
+func (m *accessKeyResourceModel) setKey(k chefc.AccessKey) {
+	m.KeyName = types.StringValue(k.Name)
+	if strings.TrimSpace(m.PublicKey.ValueString()) != strings.TrimSpace(k.PublicKey) {
+		m.PublicKey = types.StringValue(k.PublicKey)
+	}
+}
@@ -0,0 +1,502 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	chefc "github.com/go-chef/chef"
+)
+
+var (
+	_ resource.ResourceWithModifyPlan     = &bootstrapBundleResource{}
+	_ resource.ResourceWithValidateConfig = &bootstrapBundleResource{}
+)
+
+// Paths that the bootstrap files are written to by user_data, which are
+// the defaults of chef-client.
+const (
+	bootstrapClientRbPath      = "/etc/chef/client.rb"
+	bootstrapClientPemPath     = "/etc/chef/client.pem"
+	bootstrapFirstBootJsonPath = "/etc/chef/first-boot.json"
+)
+
+var chefClientVersionRegexp = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+
+func NewBootstrapBundleResource() resource.Resource {
+	return &bootstrapBundleResource{}
+}
+
+// bootstrapBundleResource registers a machine with the Chef server before
+// it boots: it creates a client with a key pair generated locally and the
+// node that the client runs, and renders the files that chef-client needs
+// on its first run.
+type bootstrapBundleResource struct {
+	frameworkResource
+}
+
+type bootstrapBundleResourceModel struct {
+	ID              types.String `tfsdk:"id"`
+	Name            types.String `tfsdk:"name"`
+	EnvironmentName types.String `tfsdk:"environment_name"`
+	RunList         types.List   `tfsdk:"run_list"`
+	PolicyName      types.String `tfsdk:"policy_name"`
+	PolicyGroup     types.String `tfsdk:"policy_group"`
+	ChefServerURL   types.String `tfsdk:"chef_server_url"`
+	ChefVersion     types.String `tfsdk:"chef_version"`
+	ClientRb        types.String `tfsdk:"client_rb"`
+	FirstBootJson   types.String `tfsdk:"first_boot_json"`
+	ClientPem       types.String `tfsdk:"client_pem"`
+	UserData        types.String `tfsdk:"user_data"`
+}
+
+func (r *bootstrapBundleResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bootstrap_bundle"
+}
+
+func (r *bootstrapBundleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Registers a machine before it boots: creates a client with a locally generated key and its node, " +
+			"and renders the files for the first chef-client run. Destroying the bundle deletes the client and the node.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of both the node and its client.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"environment_name": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString("_default"),
+			},
+			"run_list": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Run list of the node. Conflicts with `policy_name` and `policy_group`.",
+			},
+			"policy_name": schema.StringAttribute{
+				Optional: true,
+				Description: "Policyfile the node runs. Requires `policy_group`. " +
+					"The run list that chef-client saves from the Policyfile is not recorded in `run_list`.",
+			},
+			"policy_group": schema.StringAttribute{
+				Optional:    true,
+				Description: "Policy group the node belongs to. Requires `policy_name`.",
+			},
+			"chef_server_url": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Chef server URL written to client.rb. Defaults to the server URL of the provider.",
+			},
+			"chef_version": schema.StringAttribute{
+				Optional:    true,
+				Description: "Version of Chef Infra Client that user_data installs. Defaults to the latest release.",
+			},
+			"client_rb": schema.StringAttribute{
+				Computed:    true,
+				Description: "Content of " + bootstrapClientRbPath + ".",
+			},
+			"first_boot_json": schema.StringAttribute{
+				Computed:    true,
+				Description: "Content of the JSON file passed to the first chef-client run with `-j`.",
+			},
+			"client_pem": schema.StringAttribute{
+				Computed:    true,
+				Sensitive:   true,
+				Description: "Private key of the client, the content of " + bootstrapClientPemPath + ".",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"user_data": schema.StringAttribute{
+				Computed:    true,
+				Sensitive:   true,
+				Description: "cloud-init user data that writes the files above, installs Chef Infra Client and runs it.",
+			},
+		},
+	}
+}
+
+func (r *bootstrapBundleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config bootstrapBundleResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	validateNodePolicy(config.RunList, config.PolicyName, config.PolicyGroup, &resp.Diagnostics)
+	if !config.ChefVersion.IsNull() && !config.ChefVersion.IsUnknown() && !chefClientVersionRegexp.MatchString(config.ChefVersion.ValueString()) {
+		resp.Diagnostics.AddAttributeError(path.Root("chef_version"), "Invalid chef_version",
+			fmt.Sprintf("%q is not a version such as 18 or 18.5.0.", config.ChefVersion.ValueString()))
+	}
+}
+
+func (r *bootstrapBundleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var config, plan bootstrapBundleResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state bootstrapBundleResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(planRunList(ctx, &plan.RunList, state.RunList)...)
+	}
+
+	if config.ChefServerURL.IsNull() && r.client != nil {
+		plan.ChefServerURL = types.StringValue(r.client.BaseURL.String())
+	}
+
+	// The files are rendered from the arguments, so they are shown in the
+	// plan whenever the arguments are known.
+	resp.Diagnostics.Append(plan.render(ctx)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *bootstrapBundleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var plan bootstrapBundleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	node, diags := plan.node(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	privateKey, publicKey, err := generateClientKeyPair()
+	if err != nil {
+		resp.Diagnostics.AddError("Error generating client key", err.Error())
+		return
+	}
+
+	name := plan.Name.ValueString()
+	if _, err := r.client.Clients.Create(chefc.ApiNewClient{Name: name}); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error creating client", err, path.Root("name")))
+		return
+	}
+
+	// Once the client exists, failures delete what was created so far, so
+	// that the bundle can be created again.
+	if err := r.register(name, publicKey, node); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error creating bootstrap bundle", err, path.Root("name")))
+		if err := r.deregister(name); err != nil {
+			resp.Diagnostics.Append(chefDiagnostic("Error cleaning up bootstrap bundle", err, path.Root("name")))
+		}
+		return
+	}
+
+	plan.ID = types.StringValue(name)
+	plan.ClientPem = types.StringValue(privateKey)
+	if plan.ChefServerURL.IsUnknown() {
+		plan.ChefServerURL = types.StringValue(r.client.BaseURL.String())
+	}
+	resp.Diagnostics.Append(plan.render(ctx)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// register adds the key to a new client and creates the node that the
+// client owns.
+func (r *bootstrapBundleResource) register(name, publicKey string, node *chefc.Node) error {
+	key := chefc.AccessKey{
+		Name:           "default",
+		PublicKey:      publicKey,
+		ExpirationDate: "infinity",
+	}
+	if _, err := r.client.Clients.AddKey(name, key); err != nil {
+		return err
+	}
+	if _, err := r.client.Nodes.Post(*node); err != nil {
+		return err
+	}
+	return updateNodeClientACL(r.client, name, name, true)
+}
+
+// deregister deletes the node and the client of a bundle, either of which
+// may already be gone.
+func (r *bootstrapBundleResource) deregister(name string) error {
+	if err := r.client.Nodes.Delete(name); err != nil && !isNotFound(err) {
+		return err
+	}
+	if err := r.client.Clients.Delete(name); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *bootstrapBundleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.client == nil {
+		return
+	}
+
+	var state bootstrapBundleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The key in the state is only useful while the client exists.
+	name := state.ID.ValueString()
+	if _, err := r.client.Clients.Get(name); err != nil {
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(chefDiagnostic("Error reading client", err, path.Root("name")))
+		return
+	}
+	node, err := r.client.Nodes.Get(name)
+	if err != nil {
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(chefDiagnostic("Error reading node", err, path.Root("name")))
+		return
+	}
+
+	resp.Diagnostics.Append(state.setNode(ctx, &node)...)
+	resp.Diagnostics.Append(state.render(ctx)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *bootstrapBundleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var plan bootstrapBundleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	planned, diags := plan.node(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// chef-client saves the node after every run, so only the arguments of
+	// the bundle are changed and the attributes it saved are kept.
+	node, err := r.client.Nodes.Get(planned.Name)
+	if err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error reading node", err, path.Root("name")))
+		return
+	}
+	node.Environment = planned.Environment
+	node.RunList = planned.RunList
+	node.PolicyName = planned.PolicyName
+	node.PolicyGroup = planned.PolicyGroup
+	if _, err := r.client.Nodes.Put(node); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error updating node", err, path.Root("name")))
+		return
+	}
+
+	resp.Diagnostics.Append(plan.render(ctx)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *bootstrapBundleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var state bootstrapBundleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.deregister(state.ID.ValueString()); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error deleting bootstrap bundle", err, path.Root("name")))
+	}
+}
+
+// node returns the node that the bundle creates.
+func (m *bootstrapBundleResourceModel) node(ctx context.Context) (*chefc.Node, diag.Diagnostics) {
+	runList, diags := runListFromList(ctx, m.RunList)
+	if diags.HasError() {
+		return nil, diags
+	}
+	for i, entry := range runList {
+		runList[i] = runListEntryStateFunc(entry)
+	}
+
+	return &chefc.Node{
+		Name:        m.Name.ValueString(),
+		Environment: m.EnvironmentName.ValueString(),
+		ChefType:    "node",
+		JsonClass:   "Chef::Node",
+		RunList:     runList,
+		PolicyName:  m.PolicyName.ValueString(),
+		PolicyGroup: m.PolicyGroup.ValueString(),
+	}, nil
+}
+
+// setNode records the arguments of the bundle that are stored on the node.
+func (m *bootstrapBundleResourceModel) setNode(ctx context.Context, node *chefc.Node) diag.Diagnostics {
+	m.EnvironmentName = types.StringValue(node.Environment)
+	m.PolicyName = stringOrNull(node.PolicyName)
+	m.PolicyGroup = stringOrNull(node.PolicyGroup)
+	// chef-client saves the run list expanded from the Policyfile on nodes
+	// that use one, which is not an argument of the bundle.
+	if node.PolicyName != "" || node.PolicyGroup != "" {
+		m.RunList = types.ListNull(types.StringType)
+		return nil
+	}
+	if len(node.RunList) == 0 && m.RunList.IsNull() {
+		return nil
+	}
+	return setRunList(ctx, &m.RunList, node.RunList)
+}
+
+// render fills in the files of the bundle from its arguments. Files that
+// depend on an argument that is not known yet are left unknown.
+func (m *bootstrapBundleResourceModel) render(ctx context.Context) diag.Diagnostics {
+	m.ClientRb = types.StringUnknown()
+	m.FirstBootJson = types.StringUnknown()
+	m.UserData = types.StringUnknown()
+
+	for _, v := range []types.String{m.Name, m.EnvironmentName, m.PolicyName, m.PolicyGroup, m.ChefServerURL, m.ChefVersion} {
+		if v.IsUnknown() {
+			return nil
+		}
+	}
+	if m.RunList.IsUnknown() {
+		return nil
+	}
+	for _, e := range m.RunList.Elements() {
+		if e.IsUnknown() {
+			return nil
+		}
+	}
+
+	node, diags := m.node(ctx)
+	if diags.HasError() {
+		return diags
+	}
+
+	clientRb := m.clientRb()
+	firstBoot := map[string]interface{}{"run_list": node.RunList}
+	if node.PolicyName != "" {
+		firstBoot = map[string]interface{}{
+			"policy_name":  node.PolicyName,
+			"policy_group": node.PolicyGroup,
+		}
+	}
+	b, err := json.MarshalIndent(firstBoot, "", "  ")
+	if err != nil {
+		diags.AddError("Error rendering first-boot JSON", err.Error())
+		return diags
+	}
+	firstBootJson := string(b) + "\n"
+
+	m.ClientRb = types.StringValue(clientRb)
+	m.FirstBootJson = types.StringValue(firstBootJson)
+	if !m.ClientPem.IsUnknown() && !m.ClientPem.IsNull() {
+		m.UserData = types.StringValue(m.userData(clientRb, firstBootJson))
+	}
+	return diags
+}
+
+func (m *bootstrapBundleResourceModel) clientRb() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "chef_server_url %s\n", rubyString(m.ChefServerURL.ValueString()))
+	fmt.Fprintf(&b, "node_name %s\n", rubyString(m.Name.ValueString()))
+	fmt.Fprintf(&b, "client_key %s\n", rubyString(bootstrapClientPemPath))
+	if !m.PolicyName.IsNull() {
+		// Nodes that use a Policyfile ignore environments.
+		fmt.Fprintf(&b, "policy_name %s\n", rubyString(m.PolicyName.ValueString()))
+		fmt.Fprintf(&b, "policy_group %s\n", rubyString(m.PolicyGroup.ValueString()))
+	} else {
+		fmt.Fprintf(&b, "environment %s\n", rubyString(m.EnvironmentName.ValueString()))
+	}
+	return b.String()
+}
+
+// userData renders a cloud-config document that writes the bootstrap files,
+// installs Chef Infra Client with the omnitruck installer and runs it.
+func (m *bootstrapBundleResourceModel) userData(clientRb, firstBootJson string) string {
+	var b strings.Builder
+	b.WriteString("#cloud-config\nwrite_files:\n")
+	files := []struct {
+		path, permissions, content string
+	}{
+		{bootstrapClientRbPath, "0644", clientRb},
+		{bootstrapClientPemPath, "0600", m.ClientPem.ValueString()},
+		{bootstrapFirstBootJsonPath, "0644", firstBootJson},
+	}
+	for _, f := range files {
+		fmt.Fprintf(&b, "  - path: %s\n    permissions: \"%s\"\n    content: |\n", f.path, f.permissions)
+		for _, line := range strings.Split(strings.TrimRight(f.content, "\n"), "\n") {
+			b.WriteString("      " + line + "\n")
+		}
+	}
+
+	install := "curl -L https://omnitruck.chef.io/install.sh | bash"
+	if !m.ChefVersion.IsNull() {
+		install += " -s -- -v " + m.ChefVersion.ValueString()
+	}
+	b.WriteString("runcmd:\n")
+	fmt.Fprintf(&b, "  - %s\n", yamlString(install))
+	fmt.Fprintf(&b, "  - %s\n", yamlString("chef-client -j "+bootstrapFirstBootJsonPath))
+	return b.String()
+}
+
+// rubyString quotes s as a Ruby string literal that is not interpolated.
+func rubyString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// yamlString quotes s as a YAML string, which JSON strings are.
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
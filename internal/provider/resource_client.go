@@ -1,110 +1,217 @@
 package provider
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	chefc "github.com/go-chef/chef"
 )
 
-func resourceChefClient() *schema.Resource {
-	return &schema.Resource{
-		Create: CreateClient,
-		Update: UpdateClient,
-		Read:   ReadClient,
-		Delete: DeleteClient,
-		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
-		},
+var (
+	_ resource.ResourceWithImportState = &clientResource{}
+	_ resource.ResourceWithModifyPlan  = &clientResource{}
+)
+
+func NewClientResource() resource.Resource {
+	return &clientResource{}
+}
+
+type clientResource struct {
+	frameworkResource
+}
+
+type clientResourceModel struct {
+	ID            types.String `tfsdk:"id"`
+	Name          types.String `tfsdk:"name"`
+	Validator     types.Bool   `tfsdk:"validator"`
+	AdoptExisting types.Bool   `tfsdk:"adopt_existing"`
+}
 
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:     schema.TypeString,
+func (r *clientResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_client"
+}
+
+func (r *clientResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
 				Required: true,
-				ForceNew: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"validator": {
-				Type:     schema.TypeBool,
+			"validator": schema.BoolAttribute{
 				Optional: true,
-				Default:  false,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
 			},
+			"adopt_existing": adoptExistingSchema("client"),
 		},
 	}
 }
 
-func CreateClient(d *schema.ResourceData, meta interface{}) error {
-	c := meta.(*chefClient)
-
-	client, err := clientFromResourceData(d)
-	if err != nil {
-		return err
+func (r *clientResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
 	}
 
-	if _, err := c.Clients.Create(*client); err != nil {
-		return err
+	var plan clientResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-
-	d.SetId(client.Name)
-	return ReadClient(d, meta)
+	r.adoption(&plan, nil).plan(ctx, req, &resp.Diagnostics)
 }
 
-func UpdateClient(d *schema.ResourceData, meta interface{}) error {
-	c := meta.(*chefClient)
+func (r *clientResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
 
-	client, err := clientFromResourceData(d)
-	if err != nil {
-		return err
+	var plan clientResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	_, err = c.Clients.Update(client.Name, *client)
-	if err != nil {
+	client := clientFromModel(&plan)
+	resp.Diagnostics.Append(r.adoption(&plan, client).create(ctx, func() error {
+		_, err := r.client.Clients.Create(*client)
 		return err
+	})...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	d.SetId(client.Name)
-	return ReadClient(d, meta)
+	r.readInto(ctx, client.Name, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func ReadClient(d *schema.ResourceData, meta interface{}) error {
-	c := meta.(*chefClient)
+func (r *clientResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.client == nil {
+		return
+	}
 
-	name := d.Id()
+	var state clientResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	client, err := c.Clients.Get(name)
+	client, err := r.client.Clients.Get(state.ID.ValueString())
 	if err != nil {
-		if errRes, ok := err.(*chefc.ErrorResponse); ok {
-			if errRes.Response.StatusCode == 404 {
-				d.SetId("")
-				return nil
-			}
-		} else {
-			return err
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
 		}
+		resp.Diagnostics.Append(chefDiagnostic("Error reading client", err, path.Root("name")))
+		return
+	}
+
+	defaultFalse(&state.AdoptExisting)
+	clientToModel(&client, &state)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *clientResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var plan clientResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	d.Set("name", client.Name)
-	d.Set("validator", client.Validator)
+	client := clientFromModel(&plan)
+	if _, err := r.client.Clients.Update(client.Name, *client); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error updating client", err, path.Root("name")))
+		return
+	}
 
-	return nil
+	r.readInto(ctx, client.Name, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func DeleteClient(d *schema.ResourceData, meta interface{}) error {
-	c := meta.(*chefClient)
+func (r *clientResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var state clientResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.Clients.Delete(state.ID.ValueString()); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error deleting client", err, path.Root("name")))
+	}
+}
 
-	name := d.Id()
-	err := c.Clients.Delete(name)
+func (r *clientResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
 
-	if err == nil {
-		d.SetId("")
+// adoption takes over an existing client by updating it to client. The
+// keys of the client are left alone.
+func (r *clientResource) adoption(m *clientResourceModel, client *chefc.ApiNewClient) adoption {
+	return adoption{
+		Kind:      "client",
+		Name:      m.Name.ValueString(),
+		Adopt:     m.AdoptExisting,
+		Attribute: path.Root("name"),
+		Get: func() (interface{}, error) {
+			return r.client.Clients.Get(m.Name.ValueString())
+		},
+		Update: func() error {
+			_, err := r.client.Clients.Update(client.Name, *client)
+			return err
+		},
 	}
+}
 
-	return err
+// readInto reads a client back from the Chef server after it was written.
+func (r *clientResource) readInto(ctx context.Context, name string, m *clientResourceModel, diags *diag.Diagnostics) {
+	client, err := r.client.Clients.Get(name)
+	if err != nil {
+		diags.Append(chefDiagnostic("Error reading client", err, path.Root("name")))
+		return
+	}
+	clientToModel(&client, m)
 }
 
-func clientFromResourceData(d *schema.ResourceData) (*chefc.ApiNewClient, error) {
-	client := &chefc.ApiNewClient{
-		Name:      d.Get("name").(string),
-		Validator: d.Get("validator").(bool),
+func clientFromModel(m *clientResourceModel) *chefc.ApiNewClient {
+	return &chefc.ApiNewClient{
+		Name:      m.Name.ValueString(),
+		Validator: m.Validator.ValueBool(),
 		CreateKey: false,
 	}
-	return client, nil
+}
+
+func clientToModel(client *chefc.ApiClient, m *clientResourceModel) {
+	m.ID = types.StringValue(client.Name)
+	m.Name = types.StringValue(client.Name)
+	m.Validator = types.BoolValue(client.Validator)
 }
@@ -10,13 +10,19 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
+// chefClientKey is the key read back from the Chef server by the checks below.
+type chefClientKey struct {
+	Client string
+	Key    chefc.AccessKey
+}
+
 func TestAccClientKey_basic(t *testing.T) {
 	var key chefClientKey
 
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccClientKeyCheckDestroy(&key),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccClientKeyCheckDestroy(&key),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccClientKeyConfig_basic),
@@ -33,6 +39,11 @@ func TestAccClientKey_basic(t *testing.T) {
 					},
 				),
 			},
+			{
+				ResourceName:      "chef_client_key.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 }
@@ -48,7 +59,10 @@ func testAccClientKeyCheckExists(rn string, key *chefClientKey) resource.TestChe
 			return fmt.Errorf("key id not set")
 		}
 
-		c := testAccProvider.Meta().(*chefClient)
+		c, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		gotClient, err := c.Clients.Get(rs.Primary.Attributes["client"])
 		if err != nil {
 			return fmt.Errorf("error getting client: %s", err)
@@ -69,7 +83,10 @@ func testAccClientKeyCheckExists(rn string, key *chefClientKey) resource.TestChe
 
 func testAccClientKeyCheckDestroy(key *chefClientKey) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
-		c := testAccProvider.Meta().(*chefClient)
+		c, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		o, err := c.Clients.Get(key.Client)
 		if err == nil {
 			return fmt.Errorf("client still exists: %#v", o)
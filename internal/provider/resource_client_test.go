@@ -14,9 +14,9 @@ func TestAccClient_basic(t *testing.T) {
 	var client chefc.ApiNewClient
 
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccClientCheckDestroy(&client),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccClientCheckDestroy(&client),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccClientConfig_basic),
@@ -35,6 +35,11 @@ func TestAccClient_basic(t *testing.T) {
 					},
 				),
 			},
+			{
+				ResourceName:      "chef_client.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 }
@@ -50,7 +55,10 @@ func testAccClientCheckExists(rn string, client *chefc.ApiNewClient) resource.Te
 			return fmt.Errorf("client id not set")
 		}
 
-		c := testAccProvider.Meta().(*chefClient)
+		c, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		gotClient, err := c.Clients.Get(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("error getting client: %s", err)
@@ -65,7 +73,10 @@ func testAccClientCheckExists(rn string, client *chefc.ApiNewClient) resource.Te
 
 func testAccClientCheckDestroy(client *chefc.ApiNewClient) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
-		c := testAccProvider.Meta().(*chefClient)
+		c, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		o, err := c.Clients.Get(client.Name)
 		if err == nil {
 			return fmt.Errorf("client still exists: %#v", o)
@@ -1,80 +1,234 @@
 package provider
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	chefc "github.com/go-chef/chef"
 )
 
-func resourceChefDataBag() *schema.Resource {
-	return &schema.Resource{
-		Create: CreateDataBag,
-		Read:   ReadDataBag,
-		Delete: DeleteDataBag,
-		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
-		},
+var (
+	_ resource.ResourceWithImportState = &dataBagResource{}
+	_ resource.ResourceWithModifyPlan  = &dataBagResource{}
+)
+
+func NewDataBagResource() resource.Resource {
+	return &dataBagResource{}
+}
+
+type dataBagResource struct {
+	frameworkResource
+}
 
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:     schema.TypeString,
+type dataBagResourceModel struct {
+	ID                 types.String `tfsdk:"id"`
+	Name               types.String `tfsdk:"name"`
+	APIURI             types.String `tfsdk:"api_uri"`
+	AdoptExisting      types.Bool   `tfsdk:"adopt_existing"`
+	DeletionProtection types.Bool   `tfsdk:"deletion_protection"`
+	ForceDestroy       types.Bool   `tfsdk:"force_destroy"`
+}
+
+func (r *dataBagResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_data_bag"
+}
+
+func (r *dataBagResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
 				Required: true,
-				ForceNew: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"api_uri": {
-				Type:     schema.TypeString,
+			"api_uri": schema.StringAttribute{
 				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"adopt_existing":      adoptExistingSchema("data bag"),
+			"deletion_protection": deletionProtectionSchema("data bag"),
+			"force_destroy": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "If set, the data bag is deleted along with items that are not managed by Terraform. Otherwise deleting a data bag that still holds items fails.",
 			},
 		},
 	}
 }
 
-func CreateDataBag(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*chefClient)
+func (r *dataBagResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var plan dataBagResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	r.adoption(&plan).plan(ctx, req, &resp.Diagnostics)
+}
+
+func (r *dataBagResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var plan dataBagResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	dataBag := &chefc.DataBag{
-		Name: d.Get("name").(string),
+		Name: plan.Name.ValueString(),
 	}
 
-	result, err := client.DataBags.Create(dataBag)
-	if err != nil {
+	resp.Diagnostics.Append(r.adoption(&plan).create(ctx, func() error {
+		_, err := r.client.DataBags.Create(dataBag)
 		return err
+	})...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	d.SetId(dataBag.Name)
-	d.Set("api_uri", result.URI)
-	return nil
+	plan.ID = types.StringValue(dataBag.Name)
+	plan.APIURI = types.StringValue(dataBagURI(r.client.Client, dataBag.Name))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func ReadDataBag(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*chefClient)
+func (r *dataBagResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.client == nil {
+		return
+	}
+
+	var state dataBagResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	// The Chef API provides no API to read a data bag's metadata,
 	// but we can try to read its items and use that as a proxy for
 	// whether it still exists.
 
-	name := d.Id()
+	name := state.ID.ValueString()
 
-	_, err := client.DataBags.ListItems(name)
-	if err != nil {
-		if errRes, ok := err.(*chefc.ErrorResponse); ok {
-			if errRes.Response.StatusCode == 404 {
-				d.SetId("")
-				return nil
-			}
+	if _, err := r.client.DataBags.ListItems(name); err != nil {
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
 		}
+		resp.Diagnostics.Append(chefDiagnostic("Error reading data bag", err, path.Root("name")))
+		return
+	}
+
+	state.Name = types.StringValue(name)
+	defaultFalse(&state.AdoptExisting)
+	defaultFalse(&state.DeletionProtection)
+	defaultFalse(&state.ForceDestroy)
+	state.APIURI = types.StringValue(dataBagURI(r.client.Client, name))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *dataBagResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	// Every argument either requires replacement or only affects creation
+	// and deletion.
+	var plan dataBagResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	return err
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func DeleteDataBag(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*chefClient)
+func (r *dataBagResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var state dataBagResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := state.ID.ValueString()
+	if !checkDeletionProtection(state.DeletionProtection, "data bag", name, &resp.Diagnostics) {
+		return
+	}
+
+	// Items managed by Terraform depend on the data bag and are deleted
+	// before it, so any items left were created some other way.
+	if !state.ForceDestroy.ValueBool() {
+		items, err := r.client.DataBags.ListItems(name)
+		if err != nil && !isNotFound(err) {
+			resp.Diagnostics.Append(chefDiagnostic("Error reading data bag", err, path.Root("name")))
+			return
+		}
+		if items != nil && len(*items) > 0 {
+			names := make([]string, 0, len(*items))
+			for item := range *items {
+				names = append(names, item)
+			}
+			sort.Strings(names)
+			resp.Diagnostics.AddAttributeError(path.Root("force_destroy"), "Cannot delete data bag with items",
+				fmt.Sprintf("The data bag %q still holds items that are not managed by Terraform: %s. "+
+					"Set force_destroy to true and apply to delete them along with the data bag.", name, strings.Join(names, ", ")))
+			return
+		}
+	}
+
+	if _, err := r.client.DataBags.Delete(name); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error deleting data bag", err, path.Root("name")))
+	}
+}
 
-	name := d.Id()
+func (r *dataBagResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
 
-	_, err := client.DataBags.Delete(name)
-	if err == nil {
-		d.SetId("")
+// adoption takes over an existing data bag. Data bags have no content of
+// their own, so there is nothing to update; their items are managed with
+// chef_data_bag_item.
+func (r *dataBagResource) adoption(m *dataBagResourceModel) adoption {
+	return adoption{
+		Kind:      "data bag",
+		Name:      m.Name.ValueString(),
+		Adopt:     m.AdoptExisting,
+		Attribute: path.Root("name"),
+		Get: func() (interface{}, error) {
+			return r.client.DataBags.ListItems(m.Name.ValueString())
+		},
 	}
-	return err
+}
+
+// dataBagURI builds the URI that the Chef server reports for a data bag
+// when it is created, so that it can be filled in on import as well.
+func dataBagURI(client *chefc.Client, name string) string {
+	return client.BaseURL.String() + "data/" + name
 }
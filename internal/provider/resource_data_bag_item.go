@@ -1,135 +1,321 @@
 package provider
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strings"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
 
-	chefc "github.com/go-chef/chef"
+var (
+	_ resource.ResourceWithImportState    = &dataBagItemResource{}
+	_ resource.ResourceWithModifyPlan     = &dataBagItemResource{}
+	_ resource.ResourceWithUpgradeState   = &dataBagItemResource{}
+	_ resource.ResourceWithValidateConfig = &dataBagItemResource{}
 )
 
-func resourceChefDataBagItem() *schema.Resource {
-	return &schema.Resource{
-		Create: CreateDataBagItem,
-		Read:   ReadDataBagItem,
-		Delete: DeleteDataBagItem,
-		Importer: &schema.ResourceImporter{
-			State: DataBagItemImporter,
-		},
+func NewDataBagItemResource() resource.Resource {
+	return &dataBagItemResource{}
+}
+
+type dataBagItemResource struct {
+	frameworkResource
+}
+
+type dataBagItemResourceModel struct {
+	ID            types.String  `tfsdk:"id"`
+	DataBagName   types.String  `tfsdk:"data_bag_name"`
+	ContentJson   types.String  `tfsdk:"content_json"`
+	Content       types.Dynamic `tfsdk:"content"`
+	AdoptExisting types.Bool    `tfsdk:"adopt_existing"`
+}
+
+func (m *dataBagItemResourceModel) content() attributesPair {
+	return newAttributesPair("content", &m.ContentJson, &m.Content)
+}
 
-		Schema: map[string]*schema.Schema{
-			"data_bag_name": {
-				Type:     schema.TypeString,
+func (r *dataBagItemResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_data_bag_item"
+}
+
+func (r *dataBagItemResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"data_bag_name": schema.StringAttribute{
 				Required: true,
-				ForceNew: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"content_json": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "JSON encoded content of the item, which must include a string `id`. Conflicts with `content`.",
 			},
-			"content_json": {
-				Type:      schema.TypeString,
-				Required:  true,
-				ForceNew:  true,
-				StateFunc: jsonStateFunc,
+			"content": schema.DynamicAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Object holding the content of the item, which must include a string `id`. Conflicts with `content_json`.",
 			},
+			"adopt_existing": adoptExistingSchema("data bag item"),
 		},
 	}
 }
 
-func CreateDataBagItem(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*chefClient)
+func (r *dataBagItemResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config dataBagItemResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	dataBagName := d.Get("data_bag_name").(string)
-	itemId, itemContent, err := prepareDataBagItemContent(d.Get("content_json").(string))
-	if err != nil {
-		return err
+	if config.ContentJson.IsNull() && config.Content.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("content"), "Missing required argument",
+			`One of "content" or "content_json" must be specified.`)
+		return
 	}
+	config.content().validateConfig(&resp.Diagnostics)
+}
 
-	err = client.DataBags.CreateItem(dataBagName, itemContent)
+func (r *dataBagItemResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var config, plan dataBagItemResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state dataBagItemResourceModel
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	planAttributesPair(config.content(), state.content(), plan.content(), &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Items are replaced rather than updated, as before.
+	if !req.State.Raw.IsNull() && !plan.ContentJson.Equal(state.ContentJson) {
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("content_json"), path.Root("content"))
+	}
+
+	if r.client != nil {
+		// The item is named by its content, which may not be known yet.
+		var itemId string
+		if content, diags := plan.content().value(); !diags.HasError() {
+			itemId, _ = dataBagItemId(content)
+		}
+		r.adoption(&plan, itemId, nil).plan(ctx, req, &resp.Diagnostics)
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *dataBagItemResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var plan dataBagItemResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	content, diags := plan.content().value()
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	itemId, err := dataBagItemId(content)
 	if err != nil {
-		return err
+		resp.Diagnostics.AddAttributeError(path.Root("content"), "Error creating data bag item", err.Error())
+		return
 	}
 
-	d.SetId(itemId)
+	resp.Diagnostics.Append(r.adoption(&plan, itemId, content).create(ctx, func() error {
+		return r.client.DataBags.CreateItem(plan.DataBagName.ValueString(), content)
+	})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	return nil
+	plan.ID = types.StringValue(itemId)
+	if err := setAttributesPair(plan.content(), content); err != nil {
+		resp.Diagnostics.AddError("Error creating data bag item", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func ReadDataBagItem(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*chefClient)
+func (r *dataBagItemResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.client == nil {
+		return
+	}
+
+	var state dataBagItemResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	// The Chef API provides no API to read a data bag's metadata,
 	// but we can try to read its items and use that as a proxy for
 	// whether it still exists.
 
-	itemId := d.Id()
-	dataBagName := d.Get("data_bag_name").(string)
-
-	value, err := client.DataBags.GetItem(dataBagName, itemId)
+	value, err := r.client.DataBags.GetItem(state.DataBagName.ValueString(), state.ID.ValueString())
 	if err != nil {
-		if errRes, ok := err.(*chefc.ErrorResponse); ok {
-			if errRes.Response.StatusCode == 404 {
-				d.SetId("")
-				return nil
-			}
-		} else {
-			return err
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
 		}
+		resp.Diagnostics.Append(chefDiagnostic("Error reading data bag item", err, path.Root("data_bag_name")))
+		return
 	}
 
-	jsonContent, err := json.Marshal(value)
-	if err != nil {
-		return err
+	if err := setAttributesPair(state.content(), value); err != nil {
+		resp.Diagnostics.AddError("Error reading data bag item", err.Error())
+		return
 	}
+	defaultFalse(&state.AdoptExisting)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
 
-	d.Set("content_json", string(jsonContent))
+func (r *dataBagItemResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
 
-	return nil
+	// Every change either requires replacement or only touches the
+	// representation of the content, so the plan can be stored as is.
+	var plan dataBagItemResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func DeleteDataBagItem(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*chefClient)
+func (r *dataBagItemResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var state dataBagItemResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	itemId := d.Id()
-	dataBagName := d.Get("data_bag_name").(string)
+	if err := r.client.DataBags.DeleteItem(state.DataBagName.ValueString(), state.ID.ValueString()); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error deleting data bag item", err, path.Root("data_bag_name")))
+	}
+}
 
-	err := client.DataBags.DeleteItem(dataBagName, itemId)
-	if err == nil {
-		d.SetId("")
+func (r *dataBagItemResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	parts := strings.Split(req.ID, "/")
+	if len(parts) != 2 {
+		resp.Diagnostics.AddError("Unexpected import identifier",
+			fmt.Sprintf("unexpected format of ID (%s), expected databag_name/item_name", req.ID))
+		return
 	}
-	return err
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("data_bag_name"), parts[0])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), parts[1])...)
+	resp.Diagnostics.Append(importAttributesPairs(ctx, []attributesPair{(&dataBagItemResourceModel{}).content()}, &resp.State)...)
 }
 
-func prepareDataBagItemContent(contentJson string) (string, interface{}, error) {
-	var value map[string]interface{}
-	err := json.Unmarshal([]byte(contentJson), &value)
-	if err != nil {
-		return "", nil, err
+// adoption takes over an existing item by replacing its content. Conflicts
+// are attributed to the content, which holds the id of the item.
+func (r *dataBagItemResource) adoption(m *dataBagItemResourceModel, itemId string, content map[string]interface{}) adoption {
+	name := ""
+	if itemId != "" && !m.DataBagName.IsUnknown() {
+		name = m.DataBagName.ValueString() + "/" + itemId
+	}
+	return adoption{
+		Kind:      "data bag item",
+		Name:      name,
+		Adopt:     m.AdoptExisting,
+		Attribute: path.Root("content"),
+		Get: func() (interface{}, error) {
+			return r.client.DataBags.GetItem(m.DataBagName.ValueString(), itemId)
+		},
+		Update: func() error {
+			return r.client.DataBags.UpdateItem(m.DataBagName.ValueString(), itemId, content)
+		},
 	}
+}
 
+// dataBagItemId returns the id attribute that every data bag item must have.
+func dataBagItemId(content map[string]interface{}) (string, error) {
 	var itemId string
-	if itemIdI, ok := value["id"]; ok {
+	if itemIdI, ok := content["id"]; ok {
 		itemId, _ = itemIdI.(string)
 	}
 
 	if itemId == "" {
-		return "", nil, fmt.Errorf("content_json must have id attribute, set to a string")
+		return "", fmt.Errorf("content must have id attribute, set to a string")
 	}
 
-	return itemId, value, nil
+	return itemId, nil
 }
 
-func DataBagItemImporter(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
-	id := d.Id()
-	parts := strings.Split(id, "/")
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("unexpected format of ID (%s), expected databag_name/item_name", id)
-	}
+func (r *dataBagItemResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// Version 0 is the state written by the SDKv2 implementation,
+		// which only had content_json.
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":            schema.StringAttribute{Computed: true},
+					"data_bag_name": schema.StringAttribute{Required: true},
+					"content_json":  schema.StringAttribute{Required: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior struct {
+					ID          types.String `tfsdk:"id"`
+					DataBagName types.String `tfsdk:"data_bag_name"`
+					ContentJson types.String `tfsdk:"content_json"`
+				}
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
 
-	d.SetId(parts[1])
-	d.Set("data_bag_name", parts[0])
-	if err := ReadDataBagItem(d, meta); err != nil {
-		return nil, err
+				state := dataBagItemResourceModel{
+					ID:            prior.ID,
+					DataBagName:   prior.DataBagName,
+					ContentJson:   prior.ContentJson,
+					AdoptExisting: types.BoolValue(false),
+				}
+				upgradeAttributesPair(state.content(), &resp.Diagnostics)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			},
+		},
 	}
-
-	return []*schema.ResourceData{d}, nil
 }
@@ -14,9 +14,9 @@ import (
 func TestAccDataBagItem_basic(t *testing.T) {
 	var dataBagItemName string
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccDataBagItemCheckDestroy(dataBagItemName),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccDataBagItemCheckDestroy(dataBagItemName),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccDataBagItemConfig_basic),
@@ -24,6 +24,13 @@ func TestAccDataBagItem_basic(t *testing.T) {
 					"chef_data_bag_item.test", &dataBagItemName,
 				),
 			},
+			{
+				ResourceName:            "chef_data_bag_item.test",
+				ImportState:             true,
+				ImportStateId:           "terraform-acc-test-bag-item-basic-" + testSuffix + "/terraform_acc_test",
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"content_json"},
+			},
 		},
 	})
 }
@@ -39,7 +46,10 @@ func testAccDataBagItemCheck(rn string, name *string) resource.TestCheckFunc {
 			return fmt.Errorf("data bag item id not set")
 		}
 
-		client := testAccProvider.Meta().(*chefClient)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		content, err := client.DataBags.GetItem("terraform-acc-test-bag-item-basic-"+testSuffix, rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("error getting data bag item: %s", err)
@@ -65,8 +75,11 @@ func testAccDataBagItemCheck(rn string, name *string) resource.TestCheckFunc {
 
 func testAccDataBagItemCheckDestroy(name string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
-		client := testAccProvider.Meta().(*chefClient)
-		_, err := client.DataBags.GetItem("terraform-acc-test-bag-item-basic-"+testSuffix, name)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
+		_, err = client.DataBags.GetItem("terraform-acc-test-bag-item-basic-"+testSuffix, name)
 		if err == nil {
 			return fmt.Errorf("data bag item still exists")
 		}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	chefc "github.com/go-chef/chef"
@@ -13,14 +14,51 @@ import (
 func TestAccDataBag_basic(t *testing.T) {
 	dataBagName := "terraform-acc-test-basic-" + testSuffix
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccDataBagCheckDestroy(dataBagName),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccDataBagCheckDestroy(dataBagName),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccDataBagConfig_basic),
 				Check:  testAccDataBagCheckExists("chef_data_bag.test"),
 			},
+			{
+				ResourceName:      "chef_data_bag.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccDataBag_forceDestroy(t *testing.T) {
+	dataBagName := "terraform-acc-test-force-destroy-" + testSuffix
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccDataBagCheckDestroy(dataBagName),
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccDataBagConfig_forceDestroy(false)),
+				Check:  testAccDataBagCheckExists("chef_data_bag.test"),
+			},
+			{
+				PreConfig: func() {
+					client, err := testAccClient()
+					if err != nil {
+						t.Fatalf("err: %s", err)
+					}
+					if err := client.DataBags.CreateItem(dataBagName, map[string]interface{}{"id": "unmanaged"}); err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				},
+				Config:      testSuffixRender(testAccDataBagConfig_forceDestroy(false)),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("Cannot delete data bag with items"),
+			},
+			{
+				Config: testSuffixRender(testAccDataBagConfig_forceDestroy(true)),
+			},
 		},
 	})
 }
@@ -36,8 +74,11 @@ func testAccDataBagCheckExists(rn string) resource.TestCheckFunc {
 			return fmt.Errorf("data bag id not set")
 		}
 
-		client := testAccProvider.Meta().(*chefClient)
-		_, err := client.DataBags.ListItems(rs.Primary.ID)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
+		_, err = client.DataBags.ListItems(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("error getting data bag: %s", err)
 		}
@@ -48,7 +89,10 @@ func testAccDataBagCheckExists(rn string) resource.TestCheckFunc {
 
 func testAccDataBagCheckDestroy(name string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
-		client := testAccProvider.Meta().(*chefClient)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		result, err := client.DataBags.ListItems(name)
 		if err == nil && len(*result) != 0 {
 			return fmt.Errorf("data bag still exists")
@@ -66,3 +110,12 @@ resource "chef_data_bag" "test" {
   name = "terraform-acc-test-basic-{{.}}"
 }
 `
+
+func testAccDataBagConfig_forceDestroy(force bool) string {
+	return fmt.Sprintf(`
+resource "chef_data_bag" "test" {
+  name          = "terraform-acc-test-force-destroy-{{.}}"
+  force_destroy = %t
+}
+`, force)
+}
@@ -4,252 +4,605 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	chefc "github.com/go-chef/chef"
 )
 
-func resourceChefEnvironment() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: CreateEnvironment,
-		UpdateContext: UpdateEnvironment,
-		ReadContext:   ReadEnvironment,
-		DeleteContext: DeleteEnvironment,
-		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
-		},
+var (
+	_ resource.ResourceWithImportState    = &environmentResource{}
+	_ resource.ResourceWithModifyPlan     = &environmentResource{}
+	_ resource.ResourceWithUpgradeState   = &environmentResource{}
+	_ resource.ResourceWithValidateConfig = &environmentResource{}
+)
 
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+func NewEnvironmentResource() resource.Resource {
+	return &environmentResource{}
+}
+
+type environmentResource struct {
+	frameworkResource
+}
+
+type environmentResourceModel struct {
+	ID                     types.String  `tfsdk:"id"`
+	Name                   types.String  `tfsdk:"name"`
+	Description            types.String  `tfsdk:"description"`
+	AllowOverwrite         types.Bool    `tfsdk:"allow_overwrite"`
+	AdoptExisting          types.Bool    `tfsdk:"adopt_existing"`
+	DeletionProtection     types.Bool    `tfsdk:"deletion_protection"`
+	IgnoreProviderDefaults types.Bool    `tfsdk:"ignore_provider_default_attributes"`
+	DefaultAttributesJson  types.String  `tfsdk:"default_attributes_json"`
+	DefaultAttributes      types.Dynamic `tfsdk:"default_attributes"`
+	OverrideAttributesJson types.String  `tfsdk:"override_attributes_json"`
+	OverrideAttributes     types.Dynamic `tfsdk:"override_attributes"`
+	CookbookConstraints    types.Map     `tfsdk:"cookbook_constraints"`
+	Json                   types.String  `tfsdk:"json"`
+}
+
+func (m *environmentResourceModel) attributePairs() []attributesPair {
+	return []attributesPair{
+		newAttributesPair("default_attributes", &m.DefaultAttributesJson, &m.DefaultAttributes),
+		newAttributesPair("override_attributes", &m.OverrideAttributesJson, &m.OverrideAttributes),
+	}
+}
+
+func (r *environmentResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_environment"
+}
+
+func (r *environmentResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
-			"description": {
-				Type:     schema.TypeString,
-				Optional: true,
-				Default:  "Managed by Terraform",
+			"name": schema.StringAttribute{
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"allow_overwrite": {
-				Type:     schema.TypeBool,
+			"description": schema.StringAttribute{
 				Optional: true,
-				Default:  false,
+				Computed: true,
+				Default:  stringdefault.StaticString("Managed by Terraform"),
 			},
-			"default_attributes_json": {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Default:   "{}",
-				StateFunc: jsonStateFunc,
+			"allow_overwrite": schema.BoolAttribute{
+				Optional:           true,
+				Computed:           true,
+				Default:            booldefault.StaticBool(false),
+				Description:        "If set, an environment that already exists is updated instead of failing the creation.",
+				DeprecationMessage: "Use adopt_existing instead.",
 			},
-			"override_attributes_json": {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Default:   "{}",
-				StateFunc: jsonStateFunc,
+			"adopt_existing":                     adoptExistingSchema("environment"),
+			"deletion_protection":                deletionProtectionSchema("environment"),
+			"ignore_provider_default_attributes": ignoreProviderDefaultAttributesSchema("environment"),
+			"default_attributes_json":            attributesJsonSchema("default_attributes"),
+			"default_attributes":                 attributesDynamicSchema("default_attributes"),
+			"override_attributes_json":           attributesJsonSchema("override_attributes"),
+			"override_attributes":                attributesDynamicSchema("override_attributes"),
+			"cookbook_constraints": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Version constraints of cookbooks, such as `~> 1.2` or `= 1.0.0`, by cookbook name. " +
+					"Constraints are checked during plan and sent to the Chef server in its canonical form.",
 			},
-			"cookbook_constraints": {
-				Type:     schema.TypeMap,
-				Optional: true,
-				Elem: &schema.Schema{
-					Type: schema.TypeString,
-				},
-			},
-			"json": {
-				Type:     schema.TypeString,
+			"json": schema.StringAttribute{
 				Computed: true,
 			},
 		},
 	}
 }
 
-func CreateEnvironment(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+func (r *environmentResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config environmentResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	env, err := environmentFromResourceData(d)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error loading environment from resource data",
-				Detail:   fmt.Sprint(err),
-			},
-		}
+	for _, p := range config.attributePairs() {
+		p.validateConfig(&resp.Diagnostics)
 	}
 
-	// Check if the environment already exists
-	if d.Id() != "" {
-		if d.Get("allow_overwrite").(bool) {
-			return UpdateEnvironment(ctx, d, meta)
-		} else {
-			return diag.Diagnostics{
-				{
-					Severity: diag.Error,
-					Summary:  "Environment already exists and allow_overwrite is set to false",
-				},
-			}
+	for name, v := range config.CookbookConstraints.Elements() {
+		constraint, ok := v.(types.String)
+		if !ok || constraint.IsUnknown() || constraint.IsNull() {
+			continue
+		}
+		if _, err := parseChefVersionConstraint(constraint.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("cookbook_constraints").AtMapKey(name), "Invalid cookbook constraint",
+				err.Error()+". Constraints take the form \"<operator> <version>\" with one of the operators =, >, >=, <, <= or ~>.")
 		}
 	}
+}
 
-	_, err = client.Environments.Create(env)
-	if err != nil {
-		resp := diag.Diagnostic{Severity: diag.Error, Summary: "Error creating environment"}
-		if cheferr, ok := err.(*chefc.ErrorResponse); ok {
-			resp.Detail = fmt.Sprintln(cheferr.ErrorMsg, cheferr)
-		} else {
-			resp.Detail = fmt.Sprint(err)
+func (r *environmentResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var config, plan environmentResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state *environmentResourceModel
+	if !req.State.Raw.IsNull() {
+		state = &environmentResourceModel{}
+		resp.Diagnostics.Append(req.State.Get(ctx, state)...)
+		if resp.Diagnostics.HasError() {
+			return
 		}
-		return diag.Diagnostics{resp}
 	}
 
-	return ReadEnvironment(ctx, d, meta)
+	statePairs := (&environmentResourceModel{}).attributePairs()
+	if state != nil {
+		statePairs = state.attributePairs()
+		resp.Diagnostics.Append(planCookbookConstraints(ctx, &plan.CookbookConstraints, state.CookbookConstraints)...)
+	}
+	configPairs, planPairs := config.attributePairs(), plan.attributePairs()
+	for i := range planPairs {
+		planAttributesPair(configPairs[i], statePairs[i], planPairs[i], &resp.Diagnostics)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateEnvironmentReferences(ctx, r.client, &plan, state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client != nil {
+		r.adoption(&plan, nil).plan(ctx, req, &resp.Diagnostics)
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
 }
 
-func UpdateEnvironment(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+func (r *environmentResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
 
-	env, err := environmentFromResourceData(d)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error loading environment from resource data",
-				Detail:   fmt.Sprint(err),
-			},
-		}
+	var plan environmentResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	_, err = client.Environments.Put(env)
-	if err != nil {
-		resp := diag.Diagnostic{Severity: diag.Error, Summary: "Error updating environment"}
-		if cheferr, ok := err.(*chefc.ErrorResponse); ok {
-			resp.Detail = fmt.Sprintln(cheferr.ErrorMsg, cheferr)
-		} else {
-			resp.Detail = fmt.Sprint(err)
-		}
-		return diag.Diagnostics{resp}
+	env, diags := environmentFromModel(ctx, &plan, r.client.defaultAttributesFor(plan.IgnoreProviderDefaults))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.adoption(&plan, env).create(ctx, func() error {
+		_, err := r.client.Environments.Create(env)
+		return err
+	})...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	return ReadEnvironment(ctx, d, meta)
+	r.readInto(ctx, env.Name, &plan, resp.Private, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func ReadEnvironment(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+func (r *environmentResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.client == nil {
+		return
+	}
 
-	env, err := client.Environments.Get(d.Get("name").(string))
-	if err != nil {
-		resp := diag.Diagnostic{Severity: diag.Error, Summary: "Error reading environment"}
-		if cheferr, ok := err.(*chefc.ErrorResponse); ok {
-			if cheferr.Response.StatusCode == 404 {
-				d.SetId("")
-				return nil
-			}
-			resp.Detail = fmt.Sprintln(cheferr.ErrorMsg, cheferr)
-		} else {
-			resp.Detail = fmt.Sprint(err)
-		}
-		return diag.Diagnostics{resp}
+	var state environmentResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	d.SetId(env.Name)
-	d.Set("name", env.Name)
-	d.Set("description", env.Description)
-	envJson, err := json.Marshal(env)
+	env, err := r.client.Environments.Get(state.ID.ValueString())
 	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error converting environment into JSON",
-				Detail:   fmt.Sprint(err),
-			},
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
 		}
+		resp.Diagnostics.Append(chefDiagnostic("Error reading environment", err, path.Root("name")))
+		return
 	}
-	d.Set("json", string(envJson))
 
-	defaultAttrJson, err := json.Marshal(env.DefaultAttributes)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error parsing default attributes",
-				Detail:   fmt.Sprint(err),
-			},
-		}
+	// allow_overwrite only affects creation, so an imported environment
+	// takes the default rather than leaving it unset.
+	if state.AllowOverwrite.IsNull() {
+		state.AllowOverwrite = types.BoolValue(false)
 	}
-	d.Set("default_attributes_json", string(defaultAttrJson))
+	defaultFalse(&state.AdoptExisting)
+	defaultFalse(&state.DeletionProtection)
+	defaultFalse(&state.IgnoreProviderDefaults)
+
+	resp.Diagnostics.Append(environmentToModel(ctx, env, &state, r.client.defaultAttributesFor(state.IgnoreProviderDefaults))...)
+	resp.Diagnostics.Append(setSnapshot(ctx, resp.Private, env)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
 
-	overrideAttrJson, err := json.Marshal(env.OverrideAttributes)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error parsing override attributes",
-				Detail:   fmt.Sprint(err),
-			},
+func (r *environmentResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var plan, state environmentResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	env, diags := environmentFromModel(ctx, &plan, r.client.defaultAttributesFor(plan.IgnoreProviderDefaults))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	base, diags := environmentFromModel(ctx, &state, r.client.defaultAttributesFor(state.IgnoreProviderDefaults))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	merged, diags := r.client.checkConflicts(ctx, req.Private, "environment", base, env, func() (interface{}, error) {
+		return r.client.Environments.Get(env.Name)
+	})
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if merged != nil {
+		env = &chefc.Environment{}
+		if err := json.Unmarshal(merged, env); err != nil {
+			resp.Diagnostics.AddError("Error merging changes", err.Error())
+			return
 		}
 	}
-	d.Set("override_attributes_json", string(overrideAttrJson))
 
-	cookbookVersionsI := map[string]interface{}{}
-	for k, v := range env.CookbookVersions {
-		cookbookVersionsI[k] = v
+	if _, err := r.client.Environments.Put(env); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error updating environment", err, path.Root("name")))
+		return
 	}
-	d.Set("cookbook_constraints", cookbookVersionsI)
 
-	return nil
+	// Merged changes made outside of Terraform are left for the next plan,
+	// as the state must match the plan.
+	read := plan
+	r.readInto(ctx, env.Name, &read, resp.Private, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if merged == nil {
+		plan = read
+	} else {
+		fillUnknown(&plan, &read)
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func DeleteEnvironment(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+func (r *environmentResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
 
-	name := d.Id()
+	var state environmentResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	if _, err := client.Environments.Delete(name); err == nil {
-		d.SetId("")
-	} else {
-		resp := diag.Diagnostic{Severity: diag.Error, Summary: "Error deleting environment"}
-		if cheferr, ok := err.(*chefc.ErrorResponse); ok {
-			resp.Detail = fmt.Sprintln(cheferr.ErrorMsg, cheferr)
-		} else {
-			resp.Detail = fmt.Sprint(err)
+	name := state.ID.ValueString()
+	if !checkDeletionProtection(state.DeletionProtection, "environment", name, &resp.Diagnostics) {
+		return
+	}
+
+	// Deleting an environment leaves its nodes pointing at an environment
+	// that no longer exists, which breaks their next Chef run.
+	nodes, err := r.environmentNodes(name)
+	if err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error searching for nodes in environment", err, path.Root("name")))
+		return
+	}
+	if len(nodes) > 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "Cannot delete environment with nodes",
+			fmt.Sprintf("The environment %q still has nodes: %s. Move the nodes to another environment or delete them first.",
+				name, strings.Join(nodes, ", ")))
+		return
+	}
+
+	if _, err := r.client.Environments.Delete(name); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error deleting environment", err, path.Root("name")))
+	}
+}
+
+func (r *environmentResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	resp.Diagnostics.Append(importAttributesPairs(ctx, (&environmentResourceModel{}).attributePairs(), &resp.State)...)
+}
+
+// adoption takes over an existing environment by replacing it with env.
+// The deprecated allow_overwrite has the same effect as adopt_existing.
+func (r *environmentResource) adoption(m *environmentResourceModel, env *chefc.Environment) adoption {
+	return adoption{
+		Kind:      "environment",
+		Name:      m.Name.ValueString(),
+		Adopt:     types.BoolValue(m.AdoptExisting.ValueBool() || m.AllowOverwrite.ValueBool()),
+		Attribute: path.Root("name"),
+		Get: func() (interface{}, error) {
+			return r.client.Environments.Get(m.Name.ValueString())
+		},
+		Update: func() error {
+			_, err := r.client.Environments.Put(env)
+			return err
+		},
+	}
+}
+
+// environmentNodesLimit is the number of nodes named when an environment
+// cannot be deleted.
+const environmentNodesLimit = 10
+
+// environmentNodesPage is the number of search results fetched at once.
+const environmentNodesPage = 100
+
+// environmentNodes returns the names of the nodes in an environment, naming
+// only the first of them. Search lags behind changes, so each node found is
+// fetched to check that it still exists and is still in the environment, as
+// it may have been deleted or moved earlier in the same apply. Nodes are
+// only fetched until one more than can be named is confirmed; the others
+// are counted from the search results, which may include stale ones.
+func (r *environmentResource) environmentNodes(name string) ([]string, error) {
+	query, err := r.client.Search.NewQuery("node", "chef_environment:"+name)
+	if err != nil {
+		return nil, err
+	}
+	query.Rows = environmentNodesPage
+
+	var nodes []string
+	checked := 0
+	for {
+		res, err := query.DoPartial(r.client.Client, map[string]interface{}{"name": []string{"name"}})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range res.Rows {
+			checked++
+			data, _ := row.(map[string]interface{})["data"].(map[string]interface{})
+			nodeName, _ := data["name"].(string)
+			if nodeName == "" {
+				continue
+			}
+			node, err := r.client.Nodes.Get(nodeName)
+			if err != nil {
+				if isNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			if node.Environment != name {
+				continue
+			}
+			nodes = append(nodes, nodeName)
+			if len(nodes) > environmentNodesLimit {
+				more := res.Total - checked + 1
+				return append(nodes[:environmentNodesLimit], fmt.Sprintf("and about %d more", more)), nil
+			}
+		}
+		query.Start += len(res.Rows)
+		if len(res.Rows) == 0 || query.Start >= res.Total {
+			break
 		}
-		return diag.Diagnostics{resp}
 	}
+	return nodes, nil
+}
 
-	return nil
+// readInto reads an environment back from the Chef server after it was
+// written.
+func (r *environmentResource) readInto(ctx context.Context, name string, m *environmentResourceModel, private privateStateSetter, diags *diag.Diagnostics) {
+	env, err := r.client.Environments.Get(name)
+	if err != nil {
+		diags.Append(chefDiagnostic("Error reading environment", err, path.Root("name")))
+		return
+	}
+	diags.Append(environmentToModel(ctx, env, m, r.client.defaultAttributesFor(m.IgnoreProviderDefaults))...)
+	diags.Append(setSnapshot(ctx, private, env)...)
 }
 
-func environmentFromResourceData(d *schema.ResourceData) (*chefc.Environment, error) {
+// environmentFromModel returns the environment to write to the Chef server,
+// with the default attributes of the provider merged in.
+func environmentFromModel(ctx context.Context, m *environmentResourceModel, defaults defaultAttributes) (*chefc.Environment, diag.Diagnostics) {
+	var diags diag.Diagnostics
 
 	env := &chefc.Environment{
-		Name:        d.Get("name").(string),
-		Description: d.Get("description").(string),
+		Name:        m.Name.ValueString(),
+		Description: m.Description.ValueString(),
 		ChefType:    "environment",
 	}
 
-	var err error
-
-	err = json.Unmarshal(
-		[]byte(d.Get("default_attributes_json").(string)),
+	targets := []*interface{}{
 		&env.DefaultAttributes,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("default_attributes_json: %s", err)
+		&env.OverrideAttributes,
+	}
+	precedences := []map[string]interface{}{defaults.Default, defaults.Override}
+	for i, p := range m.attributePairs() {
+		v, valueDiags := p.value()
+		diags.Append(valueDiags...)
+		if diags.HasError() {
+			return nil, diags
+		}
+		*targets[i] = mergeDefaultAttributes(precedences[i], v)
 	}
 
-	err = json.Unmarshal(
-		[]byte(d.Get("override_attributes_json").(string)),
-		&env.OverrideAttributes,
-	)
+	env.CookbookVersions, diags = stringMapFromMap(ctx, m.CookbookConstraints)
+	for name, constraint := range env.CookbookVersions {
+		c, err := parseChefVersionConstraint(constraint)
+		if err != nil {
+			diags.AddAttributeError(path.Root("cookbook_constraints").AtMapKey(name), "Invalid cookbook constraint", err.Error())
+			continue
+		}
+		env.CookbookVersions[name] = c.normalize()
+	}
+	return env, diags
+}
+
+// environmentToModel records an environment read from the Chef server,
+// leaving out the default attributes that the provider merged in.
+func environmentToModel(ctx context.Context, env *chefc.Environment, m *environmentResourceModel, defaults defaultAttributes) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	m.ID = types.StringValue(env.Name)
+	m.Name = types.StringValue(env.Name)
+	m.Description = types.StringValue(env.Description)
+
+	envJson, err := json.Marshal(env)
 	if err != nil {
-		return nil, fmt.Errorf("override_attributes_json: %s", err)
+		diags.AddError("Error converting environment into JSON", err.Error())
+		return diags
+	}
+	m.Json = types.StringValue(string(envJson))
+
+	values := []interface{}{
+		env.DefaultAttributes,
+		env.OverrideAttributes,
+	}
+	precedences := []map[string]interface{}{defaults.Default, defaults.Override}
+	for i, p := range m.attributePairs() {
+		if err := setAttributesPair(p, withoutDefaultAttributes(values[i], precedences[i], p)); err != nil {
+			diags.AddAttributeError(p.JsonPath, "Error parsing attributes", err.Error())
+		}
+	}
+
+	diags.Append(setCookbookConstraints(ctx, &m.CookbookConstraints, env.CookbookVersions)...)
+	return diags
+}
+
+// planCookbookConstraints keeps the prior state of the cookbook constraints
+// when the configuration only differs in how they are written, such as
+// "1.0" for "= 1.0.0".
+func planCookbookConstraints(ctx context.Context, plan *types.Map, state types.Map) diag.Diagnostics {
+	if plan.IsNull() || plan.IsUnknown() || state.IsNull() || state.IsUnknown() {
+		return nil
+	}
+	for _, e := range plan.Elements() {
+		if e.IsUnknown() {
+			return nil
+		}
+	}
+
+	planned, diags := stringMapFromMap(ctx, *plan)
+	prior, d := stringMapFromMap(ctx, state)
+	diags.Append(d...)
+	if !diags.HasError() && cookbookConstraintsEquivalent(planned, prior) {
+		*plan = state
+	}
+	return diags
+}
+
+// setCookbookConstraints records the cookbook constraints read from the
+// Chef server unless the current value holds equivalent constraints. An
+// empty map leaves an unset value unset.
+func setCookbookConstraints(ctx context.Context, current *types.Map, values map[string]string) diag.Diagnostics {
+	if !current.IsUnknown() {
+		existing, diags := stringMapFromMap(ctx, *current)
+		if diags.HasError() {
+			return diags
+		}
+		if cookbookConstraintsEquivalent(existing, values) {
+			return nil
+		}
 	}
 
-	env.CookbookVersions = make(map[string]string)
-	for k, vI := range d.Get("cookbook_constraints").(map[string]interface{}) {
-		env.CookbookVersions[k] = vI.(string)
+	if values == nil {
+		values = map[string]string{}
 	}
+	m, diags := types.MapValueFrom(ctx, types.StringType, values)
+	*current = m
+	return diags
+}
 
-	return env, nil
+func (r *environmentResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// Version 0 is the state written by the SDKv2 implementation,
+		// which only had the *_json arguments.
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":                       schema.StringAttribute{Computed: true},
+					"name":                     schema.StringAttribute{Required: true},
+					"description":              schema.StringAttribute{Optional: true},
+					"allow_overwrite":          schema.BoolAttribute{Optional: true},
+					"default_attributes_json":  schema.StringAttribute{Optional: true},
+					"override_attributes_json": schema.StringAttribute{Optional: true},
+					"cookbook_constraints":     schema.MapAttribute{ElementType: types.StringType, Optional: true},
+					"json":                     schema.StringAttribute{Computed: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior struct {
+					ID                     types.String `tfsdk:"id"`
+					Name                   types.String `tfsdk:"name"`
+					Description            types.String `tfsdk:"description"`
+					AllowOverwrite         types.Bool   `tfsdk:"allow_overwrite"`
+					DefaultAttributesJson  types.String `tfsdk:"default_attributes_json"`
+					OverrideAttributesJson types.String `tfsdk:"override_attributes_json"`
+					CookbookConstraints    types.Map    `tfsdk:"cookbook_constraints"`
+					Json                   types.String `tfsdk:"json"`
+				}
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				state := environmentResourceModel{
+					ID:                     prior.ID,
+					Name:                   prior.Name,
+					Description:            prior.Description,
+					AllowOverwrite:         prior.AllowOverwrite,
+					DefaultAttributesJson:  prior.DefaultAttributesJson,
+					OverrideAttributesJson: prior.OverrideAttributesJson,
+					CookbookConstraints:    prior.CookbookConstraints,
+					Json:                   prior.Json,
+				}
+				if state.AllowOverwrite.IsNull() {
+					state.AllowOverwrite = types.BoolValue(false)
+				}
+				state.AdoptExisting = types.BoolValue(false)
+				state.DeletionProtection = types.BoolValue(false)
+				state.IgnoreProviderDefaults = types.BoolValue(false)
+				for _, p := range state.attributePairs() {
+					upgradeAttributesPair(p, &resp.Diagnostics)
+				}
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			},
+		},
+	}
 }
@@ -1,8 +1,15 @@
 package provider
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 
 	chefc "github.com/go-chef/chef"
@@ -15,9 +22,9 @@ func TestAccEnvironment_basic(t *testing.T) {
 	var env chefc.Environment
 
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccEnvironmentCheckDestroy(&env),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccEnvironmentCheckDestroy(&env),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccEnvironmentConfig_basic),
@@ -53,6 +60,56 @@ func TestAccEnvironment_basic(t *testing.T) {
 					},
 				),
 			},
+			{
+				ResourceName:            "chef_environment.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"default_attributes_json", "override_attributes_json"},
+			},
+		},
+	})
+}
+
+func TestAccEnvironment_nodes(t *testing.T) {
+	var env chefc.Environment
+	envName := "terraform-acc-test-nodes-" + testSuffix
+	nodeName := "terraform-acc-test-env-nodes-" + testSuffix
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccEnvironmentCheckDestroy(&env),
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccEnvironmentConfig_nodes),
+				Check:  testAccEnvironmentCheckExists("chef_environment.test", &env),
+			},
+			{
+				PreConfig: func() {
+					client, err := testAccClient()
+					if err != nil {
+						t.Fatalf("err: %s", err)
+					}
+					if _, err := client.Nodes.Post(chefc.Node{Name: nodeName, Environment: envName}); err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				},
+				Config:      testSuffixRender(testAccEnvironmentConfig_nodes),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("Cannot delete environment with nodes"),
+			},
+			{
+				PreConfig: func() {
+					client, err := testAccClient()
+					if err != nil {
+						t.Fatalf("err: %s", err)
+					}
+					if err := client.Nodes.Delete(nodeName); err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				},
+				Config: testSuffixRender(testAccEnvironmentConfig_nodes),
+			},
 		},
 	})
 }
@@ -68,7 +125,10 @@ func testAccEnvironmentCheckExists(rn string, env *chefc.Environment) resource.T
 			return fmt.Errorf("environment id not set")
 		}
 
-		client := testAccProvider.Meta().(*chefClient)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		gotEnv, err := client.Environments.Get(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("error getting environment: %s", err)
@@ -82,8 +142,11 @@ func testAccEnvironmentCheckExists(rn string, env *chefc.Environment) resource.T
 
 func testAccEnvironmentCheckDestroy(env *chefc.Environment) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
-		client := testAccProvider.Meta().(*chefClient)
-		_, err := client.Environments.Get(env.Name)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
+		_, err = client.Environments.Get(env.Name)
 		if err == nil {
 			return fmt.Errorf("environment still exists")
 		}
@@ -98,6 +161,46 @@ func testAccEnvironmentCheckDestroy(env *chefc.Environment) resource.TestCheckFu
 	}
 }
 
+func TestAccEnvironment_cookbookConstraints(t *testing.T) {
+	var env chefc.Environment
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccEnvironmentCheckDestroy(&env),
+		Steps: []resource.TestStep{
+			{
+				Config:      testSuffixRender(testAccEnvironmentConfig_cookbookConstraints(`"^1.0"`)),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("Invalid cookbook constraint"),
+			},
+			{
+				// Constraints are sent in canonical form, and the way they
+				// were written is kept so that they do not drift.
+				Config: testSuffixRender(testAccEnvironmentConfig_cookbookConstraints(`"~>1.2"`)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccEnvironmentCheckExists("chef_environment.test", &env),
+					func(s *terraform.State) error {
+						expected := map[string]string{
+							"terraform": "~> 1.2",
+							"consul":    "= 1.0.0",
+						}
+						if !reflect.DeepEqual(env.CookbookVersions, expected) {
+							return fmt.Errorf("wrong cookbook constraints; expected %#v, got %#v", expected, env.CookbookVersions)
+						}
+						return nil
+					},
+					resource.TestCheckResourceAttr("chef_environment.test", "cookbook_constraints.terraform", "~>1.2"),
+				),
+			},
+			{
+				Config:   testSuffixRender(testAccEnvironmentConfig_cookbookConstraints(`"~> 1.2"`)),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
 const testAccEnvironmentConfig_basic = `
 resource "chef_environment" "test" {
   name = "terraform-acc-test-basic-{{.}}"
@@ -118,3 +221,107 @@ EOT
   }
 }
 `
+
+const testAccEnvironmentConfig_nodes = `
+resource "chef_environment" "test" {
+  name = "terraform-acc-test-nodes-{{.}}"
+}
+`
+
+func testAccEnvironmentConfig_cookbookConstraints(constraint string) string {
+	return fmt.Sprintf(`
+resource "chef_environment" "test" {
+  name = "terraform-acc-test-constraints-{{.}}"
+  cookbook_constraints = {
+    terraform = %s
+    consul    = "1.0"
+  }
+}
+`, constraint)
+}
+
+// TestAccEnvironment_nodesSearchLag checks that the nodes blocking the
+// deletion of an environment are checked against the nodes themselves, as
+// search results can name nodes that were since deleted or moved.
+func TestAccEnvironment_nodesSearchLag(t *testing.T) {
+	if os.Getenv("TF_ACC") == "" {
+		t.Skip("acceptance tests are skipped unless TF_ACC is set")
+	}
+	if os.Getenv("TF_ACC_CASSETTES") != "" {
+		t.Skip("the test makes search results stale, which cassettes cannot record")
+	}
+	testAccPreCheck(t)
+
+	// Search results are made stale by naming two more nodes first: one
+	// that was deleted, and one that was moved to another environment.
+	gets := 0
+	defer func(transport func(http.RoundTripper) http.RoundTripper) { chefTransport = transport }(chefTransport)
+	chefTransport = func(base http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet && strings.Contains(req.URL.Path, "/nodes/") {
+				gets++
+			}
+			resp, err := base.RoundTrip(req)
+			if err != nil || !strings.Contains(req.URL.Path, "/search/node") {
+				return resp, err
+			}
+			var res map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+				return nil, err
+			}
+			resp.Body.Close()
+			if res["start"].(float64) == 0 {
+				var rows []interface{}
+				for _, name := range []string{"deleted", "moved"} {
+					rows = append(rows, map[string]interface{}{"data": map[string]interface{}{"name": "terraform-acc-test-lag-" + name + "-" + testSuffix}})
+				}
+				res["rows"] = append(rows, res["rows"].([]interface{})...)
+				res["total"] = res["total"].(float64) + 2
+			}
+			body, _ := json.Marshal(res)
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			resp.ContentLength = int64(len(body))
+			return resp, nil
+		})
+	}
+	client, err := testAccClient()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	envName := "terraform-acc-test-lag-" + testSuffix
+	if _, err := client.Environments.Create(&chefc.Environment{Name: envName}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Environments.Delete(envName)
+	var names []string
+	for i := 0; i < environmentNodesLimit+2; i++ {
+		names = append(names, fmt.Sprintf("terraform-acc-test-lag-%02d-%s", i, testSuffix))
+	}
+	names = append(names, "terraform-acc-test-lag-moved-"+testSuffix)
+	for _, name := range names {
+		if _, err := client.Nodes.Post(chefc.Node{Name: name, Environment: envName}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		defer client.Nodes.Delete(name)
+	}
+	if _, err := client.Nodes.Put(chefc.Node{Name: "terraform-acc-test-lag-moved-" + testSuffix, Environment: "_default"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	r := &environmentResource{frameworkResource{client: client}}
+	gets = 0
+	nodes, err := r.environmentNodes(envName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := append(names[:environmentNodesLimit:environmentNodesLimit], "and about 2 more")
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("expected %#v, got %#v", expected, nodes)
+	}
+	// The stale nodes and one more than are named are fetched, but not the
+	// last node.
+	if expected := 2 + environmentNodesLimit + 1; gets != expected {
+		t.Errorf("expected %d nodes to be fetched, got %d", expected, gets)
+	}
+}
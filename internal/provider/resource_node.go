@@ -4,275 +4,723 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	chefc "github.com/go-chef/chef"
 )
 
-func resourceChefNode() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: CreateNode,
-		UpdateContext: UpdateNode,
-		ReadContext:   ReadNode,
-		DeleteContext: DeleteNode,
-		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
-		},
+var (
+	_ resource.ResourceWithImportState    = &nodeResource{}
+	_ resource.ResourceWithModifyPlan     = &nodeResource{}
+	_ resource.ResourceWithUpgradeState   = &nodeResource{}
+	_ resource.ResourceWithValidateConfig = &nodeResource{}
+)
+
+func NewNodeResource() resource.Resource {
+	return &nodeResource{}
+}
+
+type nodeResource struct {
+	frameworkResource
+}
+
+type nodeResourceModel struct {
+	ID                      types.String  `tfsdk:"id"`
+	Name                    types.String  `tfsdk:"name"`
+	EnvironmentName         types.String  `tfsdk:"environment_name"`
+	AdoptExisting           types.Bool    `tfsdk:"adopt_existing"`
+	DeletionProtection      types.Bool    `tfsdk:"deletion_protection"`
+	ClientName              types.String  `tfsdk:"client_name"`
+	DeleteClientOnDestroy   types.Bool    `tfsdk:"delete_client_on_destroy"`
+	IgnoreProviderDefaults  types.Bool    `tfsdk:"ignore_provider_default_attributes"`
+	AutomaticAttributesJson types.String  `tfsdk:"automatic_attributes_json"`
+	AutomaticAttributes     types.Dynamic `tfsdk:"automatic_attributes"`
+	NormalAttributesJson    types.String  `tfsdk:"normal_attributes_json"`
+	NormalAttributes        types.Dynamic `tfsdk:"normal_attributes"`
+	DefaultAttributesJson   types.String  `tfsdk:"default_attributes_json"`
+	DefaultAttributes       types.Dynamic `tfsdk:"default_attributes"`
+	OverrideAttributesJson  types.String  `tfsdk:"override_attributes_json"`
+	OverrideAttributes      types.Dynamic `tfsdk:"override_attributes"`
+	RunList                 types.List    `tfsdk:"run_list"`
+	Tags                    types.Set     `tfsdk:"tags"`
+	PolicyName              types.String  `tfsdk:"policy_name"`
+	PolicyGroup             types.String  `tfsdk:"policy_group"`
+}
+
+func (m *nodeResourceModel) attributePairs() []attributesPair {
+	return []attributesPair{
+		newAttributesPair("automatic_attributes", &m.AutomaticAttributesJson, &m.AutomaticAttributes),
+		newAttributesPair("normal_attributes", &m.NormalAttributesJson, &m.NormalAttributes),
+		newAttributesPair("default_attributes", &m.DefaultAttributesJson, &m.DefaultAttributes),
+		newAttributesPair("override_attributes", &m.OverrideAttributesJson, &m.OverrideAttributes),
+	}
+}
+
+func (r *nodeResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_node"
+}
 
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:     schema.TypeString,
+func (r *nodeResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
 				Required: true,
-				ForceNew: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"environment_name": {
-				Type:     schema.TypeString,
+			"environment_name": schema.StringAttribute{
 				Optional: true,
-				Default:  "_default",
+				Computed: true,
+				Default:  stringdefault.StaticString("_default"),
 			},
-			"automatic_attributes_json": {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Default:   "{}",
-				StateFunc: jsonStateFunc,
+			"adopt_existing":      adoptExistingSchema("node"),
+			"deletion_protection": deletionProtectionSchema("node"),
+			"client_name": schema.StringAttribute{
+				Optional:    true,
+				Description: "Name of the API client that runs chef-client on the node. The client is granted read and update on the node, as `knife bootstrap` does.",
 			},
-			"normal_attributes_json": {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Default:   "{}",
-				StateFunc: jsonStateFunc,
+			"delete_client_on_destroy": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+				Description: "If set, the API client of the node is deleted along with it, so that it can no longer authenticate. " +
+					"The client is `client_name`, or the client named after the node if not set.",
 			},
-			"default_attributes_json": {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Default:   "{}",
-				StateFunc: jsonStateFunc,
+			"ignore_provider_default_attributes": ignoreProviderDefaultAttributesSchema("node"),
+			"automatic_attributes_json":          attributesJsonSchema("automatic_attributes"),
+			"automatic_attributes":               attributesDynamicSchema("automatic_attributes"),
+			"normal_attributes_json":             attributesJsonSchema("normal_attributes"),
+			"normal_attributes":                  attributesDynamicSchema("normal_attributes"),
+			"default_attributes_json":            attributesJsonSchema("default_attributes"),
+			"default_attributes":                 attributesDynamicSchema("default_attributes"),
+			"override_attributes_json":           attributesJsonSchema("override_attributes"),
+			"override_attributes":                attributesDynamicSchema("override_attributes"),
+			"run_list": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
 			},
-			"override_attributes_json": {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Default:   "{}",
-				StateFunc: jsonStateFunc,
+			"tags": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Tags of the node, kept in `normal.tags` where searches such as `tags:web` find them. " +
+					"When set, the tags are managed here and left out of `normal_attributes`. " +
+					"If neither `normal_attributes` nor `normal_attributes_json` is set, only the tags are written, and the other normal attributes, such as those saved by chef-client, are kept. " +
+					"Imported nodes that have tags record them here.",
 			},
-			"run_list": {
-				Type:     schema.TypeList,
+			"policy_name": schema.StringAttribute{
 				Optional: true,
-				Elem: &schema.Schema{
-					Type:      schema.TypeString,
-					StateFunc: runListEntryStateFunc,
-				},
+				Description: "Policyfile the node runs. Requires `policy_group` and conflicts with `run_list`. " +
+					"The run list that chef-client saves from the Policyfile is not recorded in `run_list`.",
+			},
+			"policy_group": schema.StringAttribute{
+				Optional:    true,
+				Description: "Policy group the node belongs to. Requires `policy_name`.",
 			},
 		},
 	}
 }
 
-func CreateNode(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+// attributesJsonSchema and attributesDynamicSchema describe the two halves
+// of an attributesPair.
+func attributesJsonSchema(name string) schema.StringAttribute {
+	return schema.StringAttribute{
+		Optional:    true,
+		Computed:    true,
+		Description: fmt.Sprintf("Attributes as a JSON string. Conflicts with `%s`.", name),
+	}
+}
 
-	node, err := nodeFromResourceData(d)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error loading node from resource data",
-				Detail:   fmt.Sprint(err),
-			},
-		}
+func attributesDynamicSchema(name string) schema.DynamicAttribute {
+	return schema.DynamicAttribute{
+		Optional:    true,
+		Computed:    true,
+		Description: fmt.Sprintf("Attributes as an object, so that plans show a diff per key. Conflicts with `%s_json`.", name),
 	}
+}
 
-	_, err = client.Nodes.Post(*node)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error creating node",
-				Detail:   fmt.Sprint(err),
-			},
+func (r *nodeResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config nodeResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, p := range config.attributePairs() {
+		p.validateConfig(&resp.Diagnostics)
+	}
+	validateNodePolicy(config.RunList, config.PolicyName, config.PolicyGroup, &resp.Diagnostics)
+
+	if !config.Tags.IsNull() {
+		if normal, diags := config.attributePairs()[1].value(); !diags.HasError() {
+			if _, ok := normal["tags"]; ok {
+				resp.Diagnostics.AddAttributeError(path.Root("tags"), "Conflicting arguments",
+					"tags cannot be combined with a \"tags\" key in normal_attributes, as both set normal.tags.")
+			}
 		}
 	}
+}
 
-	return ReadNode(ctx, d, meta)
+// validateNodePolicy checks the policy_name and policy_group arguments of a
+// node, which are set together and replace its run list.
+func validateNodePolicy(runList types.List, policyName, policyGroup types.String, diags *diag.Diagnostics) {
+	if !runList.IsNull() && (!policyName.IsNull() || !policyGroup.IsNull()) {
+		diags.AddAttributeError(path.Root("run_list"), "Conflicting arguments",
+			"run_list cannot be combined with policy_name and policy_group, as nodes that use a Policyfile take their run list from it.")
+	}
+	if policyName.IsNull() != policyGroup.IsNull() && !policyName.IsUnknown() && !policyGroup.IsUnknown() {
+		diags.AddAttributeError(path.Root("policy_name"), "Missing required argument",
+			"policy_name and policy_group must be set together.")
+	}
 }
 
-func UpdateNode(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+func (r *nodeResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
 
-	node, err := nodeFromResourceData(d)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error loading node from resource data",
-				Detail:   fmt.Sprint(err),
-			},
+	var config, plan nodeResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state *nodeResourceModel
+	if !req.State.Raw.IsNull() {
+		state = &nodeResourceModel{}
+		resp.Diagnostics.Append(req.State.Get(ctx, state)...)
+		if resp.Diagnostics.HasError() {
+			return
 		}
 	}
 
-	_, err = client.Nodes.Put(*node)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error updating node",
-				Detail:   fmt.Sprint(err),
-			},
+	statePairs := (&nodeResourceModel{}).attributePairs()
+	if state != nil {
+		statePairs = state.attributePairs()
+		resp.Diagnostics.Append(planRunList(ctx, &plan.RunList, state.RunList)...)
+	}
+	configPairs, planPairs := config.attributePairs(), plan.attributePairs()
+	for i := range planPairs {
+		planAttributesPair(configPairs[i], statePairs[i], planPairs[i], &resp.Diagnostics)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if nodeTagsOnly(&config) {
+		if state != nil {
+			plan.NormalAttributesJson, plan.NormalAttributes = state.NormalAttributesJson, state.NormalAttributes
+		} else {
+			plan.NormalAttributesJson, plan.NormalAttributes = types.StringUnknown(), types.DynamicUnknown()
 		}
 	}
 
-	return ReadNode(ctx, d, meta)
+	resp.Diagnostics.Append(validateNodeReferences(ctx, r.client, &plan, state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client != nil {
+		r.adoption(&plan, nil, false).plan(ctx, req, &resp.Diagnostics)
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
 }
 
-func ReadNode(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+func (r *nodeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
 
-	node, err := client.Nodes.Get(d.Get("name").(string))
-	if err != nil {
-		if errRes, ok := err.(*chefc.ErrorResponse); ok {
-			if errRes.Response.StatusCode == 404 {
-				d.SetId("")
-				return nil
-			}
-		} else {
-			return diag.Diagnostics{
-				{
-					Severity: diag.Error,
-					Summary:  "Error reading node",
-					Detail:   fmt.Sprint(err),
-				},
-			}
+	var config, plan nodeResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	node, diags := nodeFromModel(ctx, &plan, r.client.defaultAttributesFor(plan.IgnoreProviderDefaults))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.adoption(&plan, node, nodeTagsOnly(&config)).create(ctx, func() error {
+		_, err := r.client.Nodes.Post(*node)
+		return err
+	})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.readInto(ctx, node.Name, &plan, resp.Private, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The node is saved to the state first, so that it is tainted rather
+	// than lost if the permissions cannot be granted.
+	if !plan.ClientName.IsNull() {
+		if err := updateNodeClientACL(r.client, node.Name, plan.ClientName.ValueString(), true); err != nil {
+			resp.Diagnostics.Append(chefDiagnostic("Error granting the client permissions on the node", err, path.Root("client_name")))
 		}
 	}
+}
+
+func (r *nodeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.client == nil {
+		return
+	}
 
-	d.SetId(node.Name)
-	d.Set("name", node.Name)
-	d.Set("environment_name", node.Environment)
+	var state nodeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	automaticAttrJson, err := json.Marshal(node.AutomaticAttributes)
+	node, err := r.client.Nodes.Get(state.ID.ValueString())
 	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error parsing automatic attributes as JSON",
-				Detail:   fmt.Sprint(err),
-			},
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
 		}
+		resp.Diagnostics.Append(chefDiagnostic("Error reading node", err, path.Root("name")))
+		return
 	}
-	d.Set("automatic_attributes_json", string(automaticAttrJson))
 
-	normalAttrJson, err := json.Marshal(node.NormalAttributes)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error parsing normal attributes as JSON",
-				Detail:   fmt.Sprint(err),
-			},
+	defaultFalse(&state.AdoptExisting)
+	defaultFalse(&state.DeletionProtection)
+	defaultFalse(&state.DeleteClientOnDestroy)
+	defaultFalse(&state.IgnoreProviderDefaults)
+	resp.Diagnostics.Append(nodeToModel(ctx, &node, &state, r.client.defaultAttributesFor(state.IgnoreProviderDefaults))...)
+	resp.Diagnostics.Append(setSnapshot(ctx, resp.Private, node)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *nodeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var config, plan, state nodeResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	tagsOnly := nodeTagsOnly(&config)
+
+	node, diags := nodeFromModel(ctx, &plan, r.client.defaultAttributesFor(plan.IgnoreProviderDefaults))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// When only tags are managed, the node is written so as to keep the
+	// normal attributes chef-client saved, which are not conflicts.
+	var merged []byte
+	if !tagsOnly {
+		base, diags := nodeFromModel(ctx, &state, r.client.defaultAttributesFor(state.IgnoreProviderDefaults))
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		merged, diags = r.client.checkConflicts(ctx, req.Private, "node", base, node, func() (interface{}, error) {
+			return r.client.Nodes.Get(node.Name)
+		})
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if merged != nil {
+			node = &chefc.Node{}
+			if err := json.Unmarshal(merged, node); err != nil {
+				resp.Diagnostics.AddError("Error merging changes", err.Error())
+				return
+			}
 		}
 	}
-	d.Set("normal_attributes_json", string(normalAttrJson))
 
-	defaultAttrJson, err := json.Marshal(node.DefaultAttributes)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error parsing default attributes as JSON",
-				Detail:   fmt.Sprint(err),
-			},
+	if err := r.putNode(node, tagsOnly); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error updating node", err, path.Root("name")))
+		return
+	}
+
+	if !plan.ClientName.Equal(state.ClientName) {
+		if !state.ClientName.IsNull() {
+			if err := updateNodeClientACL(r.client, node.Name, state.ClientName.ValueString(), false); err != nil {
+				resp.Diagnostics.Append(chefDiagnostic("Error revoking the permissions of the previous client on the node", err, path.Root("client_name")))
+				return
+			}
+		}
+		if !plan.ClientName.IsNull() {
+			if err := updateNodeClientACL(r.client, node.Name, plan.ClientName.ValueString(), true); err != nil {
+				resp.Diagnostics.Append(chefDiagnostic("Error granting the client permissions on the node", err, path.Root("client_name")))
+				return
+			}
 		}
 	}
-	d.Set("default_attributes_json", string(defaultAttrJson))
 
-	overrideAttrJson, err := json.Marshal(node.OverrideAttributes)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error parsing override attributes as JSON",
-				Detail:   fmt.Sprint(err),
-			},
+	// Merged changes made outside of Terraform, and normal attributes left
+	// to chef-client, are left for the next plan, as the state must match
+	// the plan.
+	read := plan
+	r.readInto(ctx, node.Name, &read, resp.Private, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if merged == nil && !tagsOnly {
+		plan = read
+	} else {
+		fillUnknown(&plan, &read)
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *nodeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var state nodeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !checkDeletionProtection(state.DeletionProtection, "node", state.ID.ValueString(), &resp.Diagnostics) {
+		return
+	}
+
+	// The node may already be gone if deleting its client failed before.
+	if err := r.client.Nodes.Delete(state.ID.ValueString()); err != nil && !isNotFound(err) {
+		resp.Diagnostics.Append(chefDiagnostic("Error deleting node", err, path.Root("name")))
+		return
+	}
+
+	if state.DeleteClientOnDestroy.ValueBool() {
+		clientName := state.ID.ValueString()
+		if !state.ClientName.IsNull() {
+			clientName = state.ClientName.ValueString()
+		}
+		if err := r.client.Clients.Delete(clientName); err != nil && !isNotFound(err) {
+			resp.Diagnostics.Append(chefDiagnostic("Error deleting the client of the node", err, path.Root("client_name")))
 		}
 	}
-	d.Set("override_attributes_json", string(overrideAttrJson))
+}
 
-	runListI := make([]interface{}, len(node.RunList))
-	for i, v := range node.RunList {
-		runListI[i] = v
+// ImportState records the tags of a node in tags rather than in its normal
+// attributes, if it has any.
+func (r *nodeResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	resp.Diagnostics.Append(importAttributesPairs(ctx, (&nodeResourceModel{}).attributePairs(), &resp.State)...)
+	if r.client == nil {
+		return
 	}
-	d.Set("run_list", runListI)
 
-	return nil
+	// Read reports the node missing.
+	node, err := r.client.Nodes.Get(req.ID)
+	if err != nil {
+		return
+	}
+	if tags := nodeTags(node.NormalAttributes); len(tags) > 0 {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("tags"), tags)...)
+	}
 }
 
-func DeleteNode(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+// adoption takes over an existing node by replacing it with node.
+func (r *nodeResource) adoption(m *nodeResourceModel, node *chefc.Node, tagsOnly bool) adoption {
+	return adoption{
+		Kind:      "node",
+		Name:      m.Name.ValueString(),
+		Adopt:     m.AdoptExisting,
+		Attribute: path.Root("name"),
+		Get: func() (interface{}, error) {
+			return r.client.Nodes.Get(m.Name.ValueString())
+		},
+		Update: func() error {
+			return r.putNode(node, tagsOnly)
+		},
+	}
+}
 
-	name := d.Id()
-	if err := client.Nodes.Delete(name); err != nil {
-		return diag.Diagnostics{
-			{
-				Severity: diag.Error,
-				Summary:  "Error deleting node",
-				Detail:   fmt.Sprint(err),
-			},
+// nodeTagsOnly reports whether a node is configured with tags but not with
+// normal attributes, which are then left to chef-client.
+func nodeTagsOnly(config *nodeResourceModel) bool {
+	return !config.Tags.IsNull() && config.NormalAttributesJson.IsNull() && config.NormalAttributes.IsNull()
+}
+
+// putNode replaces a node with node. When tagsOnly is set, the normal
+// attributes on the Chef server are kept but for normal.tags, and the node
+// is written with a GET-modify-PUT so that writes by chef-client in the
+// meantime are not lost.
+func (r *nodeResource) putNode(node *chefc.Node, tagsOnly bool) error {
+	if !tagsOnly {
+		_, err := r.client.Nodes.Put(*node)
+		return err
+	}
+	return updateNode(r.client, node.Name, "its tags", func(current *chefc.Node) error {
+		normal := current.NormalAttributes
+		if normal == nil {
+			normal = map[string]interface{}{}
 		}
+		*current = *node
+		current.NormalAttributes = mergeDefaultAttributes(node.NormalAttributes, normal).(map[string]interface{})
+		current.NormalAttributes["tags"] = node.NormalAttributes["tags"]
+		return nil
+	})
+}
+
+// nodeClientPermissions are granted to the client of a node, so that
+// chef-client can save the node at the end of its runs.
+var nodeClientPermissions = []string{"read", "update"}
+
+// updateNodeClientACL grants the client of a node its permissions on the
+// node, or revokes them. Other actors and groups are left alone.
+func updateNodeClientACL(client *chefClient, node, clientName string, grant bool) error {
+	acl, err := client.ACLs.Get("nodes", node)
+	if err != nil {
+		return err
 	}
 
-	d.SetId("")
+	for _, perm := range nodeClientPermissions {
+		items := acl[perm]
+		actors := make(chefc.ACLitem, 0, len(items.Actors)+1)
+		for _, actor := range items.Actors {
+			if actor != clientName {
+				actors = append(actors, actor)
+			}
+		}
+		if grant {
+			actors = append(actors, clientName)
+		}
+		if reflect.DeepEqual([]string(actors), []string(items.Actors)) {
+			continue
+		}
 
+		groups := items.Groups
+		if groups == nil {
+			groups = chefc.ACLitem{}
+		}
+		if err := client.ACLs.Put("nodes", node, perm, chefc.NewACL(perm, actors, groups)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func nodeFromResourceData(d *schema.ResourceData) (*chefc.Node, error) {
+// readInto reads a node back from the Chef server after it was written.
+func (r *nodeResource) readInto(ctx context.Context, name string, m *nodeResourceModel, private privateStateSetter, diags *diag.Diagnostics) {
+	node, err := r.client.Nodes.Get(name)
+	if err != nil {
+		diags.Append(chefDiagnostic("Error reading node", err, path.Root("name")))
+		return
+	}
+	diags.Append(nodeToModel(ctx, &node, m, r.client.defaultAttributesFor(m.IgnoreProviderDefaults))...)
+	diags.Append(setSnapshot(ctx, private, node)...)
+}
 
+// nodeFromModel returns the node to write to the Chef server, with the
+// default attributes of the provider merged in.
+func nodeFromModel(ctx context.Context, m *nodeResourceModel, defaults defaultAttributes) (*chefc.Node, diag.Diagnostics) {
 	node := &chefc.Node{
-		Name:        d.Get("name").(string),
-		Environment: d.Get("environment_name").(string),
+		Name:        m.Name.ValueString(),
+		Environment: m.EnvironmentName.ValueString(),
 		ChefType:    "node",
 		JsonClass:   "Chef::Node",
+		PolicyName:  m.PolicyName.ValueString(),
+		PolicyGroup: m.PolicyGroup.ValueString(),
 	}
 
-	var err error
-
-	err = json.Unmarshal(
-		[]byte(d.Get("automatic_attributes_json").(string)),
+	targets := []*map[string]interface{}{
 		&node.AutomaticAttributes,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("automatic_attributes_json: %s", err)
+		&node.NormalAttributes,
+		&node.DefaultAttributes,
+		&node.OverrideAttributes,
 	}
+	for i, p := range m.attributePairs() {
+		v, diags := p.value()
+		if diags.HasError() {
+			return nil, diags
+		}
+		*targets[i] = v
+	}
+	if !m.Tags.IsNull() && !m.Tags.IsUnknown() {
+		var tags []string
+		if diags := m.Tags.ElementsAs(ctx, &tags, false); diags.HasError() {
+			return nil, diags
+		}
+		sort.Strings(tags)
+		node.NormalAttributes["tags"] = tags
+	}
+	node.NormalAttributes = mergeDefaultAttributes(defaults.Normal, node.NormalAttributes).(map[string]interface{})
+	node.DefaultAttributes = mergeDefaultAttributes(defaults.Default, node.DefaultAttributes).(map[string]interface{})
+	node.OverrideAttributes = mergeDefaultAttributes(defaults.Override, node.OverrideAttributes).(map[string]interface{})
 
-	err = json.Unmarshal(
-		[]byte(d.Get("normal_attributes_json").(string)),
-		&node.NormalAttributes,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("normal_attributes_json: %s", err)
+	runList, diags := runListFromList(ctx, m.RunList)
+	if diags.HasError() {
+		return nil, diags
 	}
+	node.RunList = runList
 
-	err = json.Unmarshal(
-		[]byte(d.Get("default_attributes_json").(string)),
-		&node.DefaultAttributes,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("default_attributes_json: %s", err)
+	return node, nil
+}
+
+// nodeToModel records a node read from the Chef server, leaving out the
+// default attributes that the provider merged in.
+func nodeToModel(ctx context.Context, node *chefc.Node, m *nodeResourceModel, defaults defaultAttributes) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	m.ID = types.StringValue(node.Name)
+	m.Name = types.StringValue(node.Name)
+	m.EnvironmentName = types.StringValue(node.Environment)
+	m.PolicyName = stringOrNull(node.PolicyName)
+	m.PolicyGroup = stringOrNull(node.PolicyGroup)
+
+	values := []map[string]interface{}{
+		node.AutomaticAttributes,
+		node.NormalAttributes,
+		node.DefaultAttributes,
+		node.OverrideAttributes,
+	}
+	if !m.Tags.IsNull() {
+		tags, tagsDiags := types.SetValueFrom(ctx, types.StringType, nodeTags(node.NormalAttributes))
+		diags.Append(tagsDiags...)
+		m.Tags = tags
+
+		normal := make(map[string]interface{}, len(node.NormalAttributes))
+		for k, v := range node.NormalAttributes {
+			if k != "tags" {
+				normal[k] = v
+			}
+		}
+		values[1] = normal
 	}
 
-	err = json.Unmarshal(
-		[]byte(d.Get("override_attributes_json").(string)),
-		&node.OverrideAttributes,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("override_attributes_json: %s", err)
+	precedences := []map[string]interface{}{nil, defaults.Normal, defaults.Default, defaults.Override}
+	for i, p := range m.attributePairs() {
+		if err := setAttributesPair(p, withoutDefaultAttributes(values[i], precedences[i], p)); err != nil {
+			diags.AddAttributeError(p.JsonPath, "Error parsing attributes as JSON", err.Error())
+		}
 	}
 
-	runListI := d.Get("run_list").([]interface{})
-	node.RunList = make([]string, len(runListI))
-	for i, vI := range runListI {
-		node.RunList[i] = vI.(string)
+	// chef-client saves the run list expanded from the Policyfile on nodes
+	// that use one, which is not managed here.
+	if node.PolicyName != "" || node.PolicyGroup != "" {
+		m.RunList = types.ListNull(types.StringType)
+		return diags
 	}
+	diags.Append(setRunList(ctx, &m.RunList, node.RunList)...)
+	return diags
+}
 
-	return node, nil
+// nodeTags returns the tags of a node, which knife and chef-client keep as a
+// list in normal.tags.
+func nodeTags(normal map[string]interface{}) []string {
+	list, _ := normal["tags"].([]interface{})
+	tags := make([]string, 0, len(list))
+	for _, v := range list {
+		if tag, ok := v.(string); ok && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// stringOrNull converts an optional string of a Chef object, which is empty
+// when it is not set.
+func stringOrNull(s string) types.String {
+	if s == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(s)
+}
+
+func (r *nodeResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// Version 0 is the state written by the SDKv2 implementation,
+		// which only had the *_json arguments.
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":                        schema.StringAttribute{Computed: true},
+					"name":                      schema.StringAttribute{Required: true},
+					"environment_name":          schema.StringAttribute{Optional: true},
+					"automatic_attributes_json": schema.StringAttribute{Optional: true},
+					"normal_attributes_json":    schema.StringAttribute{Optional: true},
+					"default_attributes_json":   schema.StringAttribute{Optional: true},
+					"override_attributes_json":  schema.StringAttribute{Optional: true},
+					"run_list":                  schema.ListAttribute{ElementType: types.StringType, Optional: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior struct {
+					ID                      types.String `tfsdk:"id"`
+					Name                    types.String `tfsdk:"name"`
+					EnvironmentName         types.String `tfsdk:"environment_name"`
+					AutomaticAttributesJson types.String `tfsdk:"automatic_attributes_json"`
+					NormalAttributesJson    types.String `tfsdk:"normal_attributes_json"`
+					DefaultAttributesJson   types.String `tfsdk:"default_attributes_json"`
+					OverrideAttributesJson  types.String `tfsdk:"override_attributes_json"`
+					RunList                 types.List   `tfsdk:"run_list"`
+				}
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				state := nodeResourceModel{
+					ID:                      prior.ID,
+					Name:                    prior.Name,
+					EnvironmentName:         prior.EnvironmentName,
+					AdoptExisting:           types.BoolValue(false),
+					DeletionProtection:      types.BoolValue(false),
+					DeleteClientOnDestroy:   types.BoolValue(false),
+					IgnoreProviderDefaults:  types.BoolValue(false),
+					AutomaticAttributesJson: prior.AutomaticAttributesJson,
+					NormalAttributesJson:    prior.NormalAttributesJson,
+					DefaultAttributesJson:   prior.DefaultAttributesJson,
+					OverrideAttributesJson:  prior.OverrideAttributesJson,
+					RunList:                 prior.RunList,
+					Tags:                    types.SetNull(types.StringType),
+					PolicyName:              types.StringNull(),
+					PolicyGroup:             types.StringNull(),
+				}
+				for _, p := range state.attributePairs() {
+					upgradeAttributesPair(p, &resp.Diagnostics)
+				}
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			},
+		},
+	}
 }
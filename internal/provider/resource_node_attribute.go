@@ -0,0 +1,511 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	chefc "github.com/go-chef/chef"
+)
+
+var (
+	_ resource.ResourceWithImportState    = &nodeAttributeResource{}
+	_ resource.ResourceWithModifyPlan     = &nodeAttributeResource{}
+	_ resource.ResourceWithValidateConfig = &nodeAttributeResource{}
+)
+
+func NewNodeAttributeResource() resource.Resource {
+	return &nodeAttributeResource{}
+}
+
+type nodeAttributeResource struct {
+	frameworkResource
+}
+
+type nodeAttributeResourceModel struct {
+	ID         types.String  `tfsdk:"id"`
+	Node       types.String  `tfsdk:"node"`
+	Precedence types.String  `tfsdk:"precedence"`
+	Path       types.String  `tfsdk:"path"`
+	Value      types.Dynamic `tfsdk:"value"`
+}
+
+// nodeAttributePrecedences are the precedence levels of node attributes that
+// can be set. Automatic attributes belong to ohai.
+var nodeAttributePrecedences = []string{"normal", "default", "override"}
+
+func (r *nodeAttributeResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_node_attribute"
+}
+
+func (r *nodeAttributeResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages a single attribute of an existing node, leaving the rest of the node alone, " +
+			"so that several configurations can set attributes of the same node. " +
+			"Destroying the resource removes the attribute.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"node": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the node.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"precedence": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString("normal"),
+				Description: "Precedence level of the attribute: `normal`, `default` or `override`.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"path": schema.StringAttribute{
+				Required: true,
+				Description: "Path of the attribute, either as a JSON pointer such as `/nginx/port` or as dotted keys such as `nginx.port`. " +
+					"Objects along the path are created as needed.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIf(attributePathChanged,
+						"Changing the attribute the path refers to requires replacement.",
+						"Changing the attribute the path refers to requires replacement."),
+				},
+			},
+			"value": schema.DynamicAttribute{
+				Required:    true,
+				Description: "Value of the attribute, of any type.",
+			},
+		},
+	}
+}
+
+func (r *nodeAttributeResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config nodeAttributeResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.Precedence.IsNull() && !config.Precedence.IsUnknown() {
+		if _, err := nodeAttributesOf(&chefc.Node{}, config.Precedence.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("precedence"), "Invalid precedence", err.Error())
+		}
+	}
+	if !config.Path.IsUnknown() {
+		if _, err := parseAttributePath(config.Path.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("path"), "Invalid attribute path", err.Error())
+		}
+	}
+}
+
+// ModifyPlan keeps the prior value when the configured one only differs in
+// its HCL type, such as a tuple in place of a list.
+func (r *nodeAttributeResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var plan, state nodeAttributeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if dynamicJsonEquivalent(plan.Value, state.Value) {
+		plan.Value = state.Value
+		resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+	}
+}
+
+func (r *nodeAttributeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var plan nodeAttributeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.write(&plan, "Error setting node attribute", &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *nodeAttributeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.client == nil {
+		return
+	}
+
+	var state nodeAttributeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	keys, err := parseAttributePath(state.Path.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "Invalid attribute path", err.Error())
+		return
+	}
+
+	node, err := r.client.Nodes.Get(state.Node.ValueString())
+	if err != nil {
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(chefDiagnostic("Error reading node", err, path.Root("node")))
+		return
+	}
+
+	attributes, err := nodeAttributesOf(&node, state.Precedence.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("precedence"), "Invalid precedence", err.Error())
+		return
+	}
+	value, ok := lookupAttribute(*attributes, keys)
+	if !ok {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	if current, known, err := jsonFromAttrValue(state.Value); err != nil || !known || !jsonValuesEqual(current, value) {
+		state.Value = types.DynamicValue(attrValueFromJson(value))
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *nodeAttributeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var plan nodeAttributeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.write(&plan, "Error updating node attribute", &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *nodeAttributeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var state nodeAttributeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	keys, err := parseAttributePath(state.Path.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "Invalid attribute path", err.Error())
+		return
+	}
+
+	err = updateNodeAttribute(r.client, state.Node.ValueString(), state.Precedence.ValueString(), keys, nil, true)
+	if err != nil && !isNotFound(err) {
+		resp.Diagnostics.Append(chefDiagnostic("Error removing node attribute", err, path.Root("path")))
+	}
+}
+
+// ImportState takes an ID of the form node/precedence/pointer, such as
+// web-1/normal/nginx/port.
+func (r *nodeAttributeResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	parts := strings.SplitN(req.ID, "/", 3)
+	if len(parts) != 3 {
+		resp.Diagnostics.AddError("Unexpected import identifier",
+			fmt.Sprintf("unexpected format of ID (%s), expected node/precedence/path", req.ID))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("node"), parts[0])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("precedence"), parts[1])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("path"), "/"+parts[2])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("value"), types.DynamicNull())...)
+}
+
+// attributePathChanged reports whether a path refers to another attribute
+// than before. Imported resources have their path as a JSON pointer, which
+// is updated in place to the dotted keys of the configuration.
+func attributePathChanged(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+	before, err := parseAttributePath(req.StateValue.ValueString())
+	if err != nil {
+		resp.RequiresReplace = true
+		return
+	}
+	after, err := parseAttributePath(req.PlanValue.ValueString())
+	resp.RequiresReplace = err != nil || !reflect.DeepEqual(before, after)
+}
+
+// write sets the attribute of m on its node and fills in the ID.
+func (r *nodeAttributeResource) write(m *nodeAttributeResourceModel, summary string, diags *diag.Diagnostics) {
+	keys, err := parseAttributePath(m.Path.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("path"), "Invalid attribute path", err.Error())
+		return
+	}
+	value, known, err := jsonFromAttrValue(m.Value)
+	if err != nil || !known {
+		if err == nil {
+			err = fmt.Errorf("the value is not known")
+		}
+		diags.AddAttributeError(path.Root("value"), "Invalid attribute value", err.Error())
+		return
+	}
+
+	if err := updateNodeAttribute(r.client, m.Node.ValueString(), m.Precedence.ValueString(), keys, value, false); err != nil {
+		diags.Append(chefDiagnostic(summary, err, path.Root("node")))
+		return
+	}
+	m.ID = types.StringValue(m.Node.ValueString() + "/" + m.Precedence.ValueString() + jsonPointer(keys))
+}
+
+// nodeWriteAttempts bounds how often a node is written before giving up on a
+// node that keeps changing.
+const nodeWriteAttempts = 5
+
+// nodeWriteLocks serializes writes to the same node from this process, as
+// several chef_node_attribute resources are usually applied in parallel.
+var nodeWriteLocks sync.Map
+
+// updateNodeAttribute sets the attribute at keys of a node to value, or
+// removes it.
+func updateNodeAttribute(client *chefClient, name, precedence string, keys []string, value interface{}, remove bool) error {
+	return updateNode(client, name, "attribute "+jsonPointer(keys), func(node *chefc.Node) error {
+		attributes, err := nodeAttributesOf(node, precedence)
+		if err != nil {
+			return err
+		}
+		if remove {
+			deleteAttribute(*attributes, keys)
+			return nil
+		}
+		if *attributes == nil {
+			*attributes = map[string]interface{}{}
+		}
+		return setAttribute(*attributes, keys, value)
+	})
+}
+
+// updateNode applies change to a node with a GET-modify-PUT. The node is
+// fetched again just before the PUT and after it, and the write is retried
+// if anyone else changed the node in between or undid the change. what names
+// the change in errors.
+func updateNode(client *chefClient, name, what string, change func(node *chefc.Node) error) error {
+	lock, _ := nodeWriteLocks.LoadOrStore(name, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	for attempt := 1; ; attempt++ {
+		done, err := tryUpdateNode(client, name, change)
+		if err != nil || done {
+			return err
+		}
+		if attempt == nodeWriteAttempts {
+			return fmt.Errorf("node %s changed during each of %d attempts to update %s", name, attempt, what)
+		}
+		time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+	}
+}
+
+func tryUpdateNode(client *chefClient, name string, change func(node *chefc.Node) error) (bool, error) {
+	node, err := client.Nodes.Get(name)
+	if err != nil {
+		return false, err
+	}
+	updated, changed, err := changedNode(node, change)
+	if err != nil || !changed {
+		return !changed, err
+	}
+
+	current, err := client.Nodes.Get(name)
+	if err != nil {
+		return false, err
+	}
+	if !reflect.DeepEqual(current, node) {
+		return false, nil
+	}
+	if _, err := client.Nodes.Put(updated); err != nil {
+		return false, err
+	}
+
+	written, err := client.Nodes.Get(name)
+	if err != nil {
+		return false, err
+	}
+	_, changed, err = changedNode(written, change)
+	return !changed, err
+}
+
+// changedNode applies change to a copy of a node, and reports whether that
+// made a difference to the JSON of the node.
+func changedNode(node chefc.Node, change func(node *chefc.Node) error) (chefc.Node, bool, error) {
+	updated, err := copyNode(node)
+	if err != nil {
+		return updated, false, err
+	}
+	if err := change(&updated); err != nil {
+		return updated, false, err
+	}
+	before, err := jsonObjectValue(node)
+	if err != nil {
+		return updated, false, err
+	}
+	after, err := jsonObjectValue(updated)
+	if err != nil {
+		return updated, false, err
+	}
+	return updated, !jsonValuesEqual(before, after), nil
+}
+
+// copyNode returns a deep copy of a node, so that the copy can be modified
+// and compared with the original.
+func copyNode(node chefc.Node) (chefc.Node, error) {
+	var c chefc.Node
+	nodeJson, err := json.Marshal(node)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(nodeJson, &c)
+	return c, err
+}
+
+// nodeAttributesOf returns the attributes of a node at a precedence level.
+func nodeAttributesOf(node *chefc.Node, precedence string) (*map[string]interface{}, error) {
+	switch precedence {
+	case "normal":
+		return &node.NormalAttributes, nil
+	case "default":
+		return &node.DefaultAttributes, nil
+	case "override":
+		return &node.OverrideAttributes, nil
+	}
+	return nil, fmt.Errorf("precedence must be one of %s, got %q", strings.Join(nodeAttributePrecedences, ", "), precedence)
+}
+
+// parseAttributePath splits the path of an attribute into its keys. It is
+// either a JSON pointer, which starts with a slash, or keys separated by
+// dots.
+func parseAttributePath(p string) ([]string, error) {
+	var keys []string
+	if strings.HasPrefix(p, "/") {
+		for _, key := range strings.Split(p[1:], "/") {
+			keys = append(keys, strings.NewReplacer("~1", "/", "~0", "~").Replace(key))
+		}
+	} else {
+		keys = strings.Split(p, ".")
+	}
+
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("%q has an empty key; use a JSON pointer such as /nginx/port or dotted keys such as nginx.port", p)
+		}
+	}
+	return keys, nil
+}
+
+// jsonPointer renders keys as a JSON pointer.
+func jsonPointer(keys []string) string {
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(key))
+	}
+	return b.String()
+}
+
+func lookupAttribute(attributes map[string]interface{}, keys []string) (interface{}, bool) {
+	var value interface{} = attributes
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// setAttribute sets the attribute at keys, creating the objects along the
+// way. It fails rather than replace a value that is not an object.
+func setAttribute(attributes map[string]interface{}, keys []string, value interface{}) error {
+	m := attributes
+	for i, key := range keys[:len(keys)-1] {
+		next, ok := m[key]
+		if !ok {
+			next = map[string]interface{}{}
+			m[key] = next
+		}
+		if m, ok = next.(map[string]interface{}); !ok {
+			return fmt.Errorf("attribute %s is not an object", jsonPointer(keys[:i+1]))
+		}
+	}
+	m[keys[len(keys)-1]] = value
+	return nil
+}
+
+// deleteAttribute removes the attribute at keys, and reports whether it was
+// there. The objects along the way are kept.
+func deleteAttribute(attributes map[string]interface{}, keys []string) bool {
+	parent, ok := lookupAttribute(attributes, keys[:len(keys)-1])
+	if !ok {
+		return false
+	}
+	m, ok := parent.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if _, ok := m[keys[len(keys)-1]]; !ok {
+		return false
+	}
+	delete(m, keys[len(keys)-1])
+	return true
+}
+
+// dynamicJsonEquivalent reports whether two dynamic values hold the same
+// JSON, whatever their HCL types.
+func dynamicJsonEquivalent(a, b types.Dynamic) bool {
+	aValue, aKnown, err := jsonFromAttrValue(a)
+	if err != nil || !aKnown || a.IsNull() {
+		return false
+	}
+	bValue, bKnown, err := jsonFromAttrValue(b)
+	if err != nil || !bKnown || b.IsNull() {
+		return false
+	}
+	return jsonValuesEqual(aValue, bValue)
+}
@@ -0,0 +1,250 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	chefc "github.com/go-chef/chef"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccNodeAttribute_basic(t *testing.T) {
+	name := "terraform-acc-test-node-attribute-" + testSuffix
+
+	checkNormal := func(expected map[string]interface{}) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			client, err := testAccClient()
+			if err != nil {
+				return err
+			}
+			node, err := client.Nodes.Get(name)
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(node.NormalAttributes, expected) {
+				return fmt.Errorf("wrong normal attributes; expected %#v, got %#v", expected, node.NormalAttributes)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testSuffixRender(testAccNodeAttributeConfig_invalidPath),
+				ExpectError: regexp.MustCompile("Invalid attribute path"),
+			},
+			{
+				Config: testSuffixRender(testAccNodeAttributeConfig_basic),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("chef_node_attribute.port", "id", name+"/normal/nginx/port"),
+					resource.TestCheckResourceAttr("chef_node_attribute.workers", "id", name+"/normal/nginx/workers"),
+					checkNormal(map[string]interface{}{
+						"nginx": map[string]interface{}{
+							"port":    float64(8080),
+							"workers": []interface{}{"a", "b"},
+						},
+					}),
+				),
+			},
+			{
+				ResourceName:      "chef_node_attribute.workers",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				// The imported path is a JSON pointer, which the next step
+				// updates in place to the dotted keys of the configuration.
+				ResourceName:            "chef_node_attribute.port",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"path"},
+				ImportStatePersist:      true,
+			},
+			{
+				Config: testSuffixRender(testAccNodeAttributeConfig_basic),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("chef_node_attribute.port", "path", "nginx.port"),
+					resource.TestCheckResourceAttr("chef_node_attribute.port", "id", name+"/normal/nginx/port"),
+				),
+			},
+			{
+				Config: testSuffixRender(testAccNodeAttributeConfig_removed),
+				Check: checkNormal(map[string]interface{}{
+					"nginx": map[string]interface{}{
+						"port": float64(8080),
+					},
+				}),
+			},
+		},
+	})
+}
+
+// TestAccNodeAttribute_retry checks that an attribute is written again when
+// the node changes between it being fetched and saved.
+func TestAccNodeAttribute_retry(t *testing.T) {
+	if os.Getenv("TF_ACC") == "" {
+		t.Skip("acceptance tests are skipped unless TF_ACC is set")
+	}
+	if os.Getenv("TF_ACC_CASSETTES") != "" {
+		t.Skip("the test changes the node while it is written, which cassettes cannot record")
+	}
+	testAccPreCheck(t)
+
+	name := "terraform-acc-test-node-attribute-retry-" + testSuffix
+	other, err := testAccClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Nodes.Post(chefc.Node{Name: name, Environment: "_default", ChefType: "node", JsonClass: "Chef::Node"}); err != nil {
+		t.Fatal(err)
+	}
+	defer other.Nodes.Delete(name)
+
+	// The first time the node is fetched again before saving it, someone else
+	// sets another attribute.
+	var gets int
+	defer func(transport func(http.RoundTripper) http.RoundTripper) { chefTransport = transport }(chefTransport)
+	chefTransport = func(base http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/nodes/"+name) {
+				gets++
+				if gets == 2 {
+					node, err := other.Nodes.Get(name)
+					if err != nil {
+						return nil, err
+					}
+					node.NormalAttributes = map[string]interface{}{"other": "set"}
+					if _, err := other.Nodes.Put(node); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return base.RoundTrip(req)
+		})
+	}
+	client, err := testAccClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := updateNodeAttribute(client, name, "normal", []string{"nginx", "port"}, float64(8080), false); err != nil {
+		t.Fatal(err)
+	}
+	if gets != 5 {
+		t.Errorf("expected the node to be fetched twice, then three times when retrying, got %d fetches", gets)
+	}
+	node, err := other.Nodes.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"nginx": map[string]interface{}{"port": float64(8080)},
+		"other": "set",
+	}
+	if !reflect.DeepEqual(node.NormalAttributes, expected) {
+		t.Errorf("wrong normal attributes; expected %#v, got %#v", expected, node.NormalAttributes)
+	}
+}
+
+func TestAttributePathChanged(t *testing.T) {
+	cases := []struct {
+		before, after string
+		replace       bool
+	}{
+		{"/nginx/port", "nginx.port", false},
+		{"nginx.port", "/nginx/port", false},
+		{"/nginx/port", "nginx.workers", true},
+		{"/dots.in.keys", "dots.in.keys", true},
+	}
+	for _, c := range cases {
+		req := planmodifier.StringRequest{
+			StateValue: types.StringValue(c.before),
+			PlanValue:  types.StringValue(c.after),
+		}
+		var resp stringplanmodifier.RequiresReplaceIfFuncResponse
+		attributePathChanged(context.Background(), req, &resp)
+		if resp.RequiresReplace != c.replace {
+			t.Errorf("%q to %q: expected replacement %t, got %t", c.before, c.after, c.replace, resp.RequiresReplace)
+		}
+	}
+}
+
+func TestParseAttributePath(t *testing.T) {
+	cases := map[string][]string{
+		"/nginx/port":   {"nginx", "port"},
+		"nginx.port":    {"nginx", "port"},
+		"/a~1b/c~0d":    {"a/b", "c~d"},
+		"single":        {"single"},
+		"/dots.in.keys": {"dots.in.keys"},
+	}
+	for in, expected := range cases {
+		keys, err := parseAttributePath(in)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", in, err)
+			continue
+		}
+		if !reflect.DeepEqual(keys, expected) {
+			t.Errorf("%q: expected %#v, got %#v", in, expected, keys)
+		}
+		if in[0] == '/' && jsonPointer(keys) != in {
+			t.Errorf("%q: rendered back as %q", in, jsonPointer(keys))
+		}
+	}
+
+	for _, in := range []string{"", "/", "nginx..port", "/nginx//port"} {
+		if _, err := parseAttributePath(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+const testAccNodeAttributeConfig_invalidPath = `
+resource "chef_node_attribute" "test" {
+  node  = "terraform-acc-test-node-attribute-{{.}}"
+  path  = "nginx..port"
+  value = 8080
+}
+`
+
+const testAccNodeAttributeConfig_basic = `
+resource "chef_bootstrap_bundle" "test" {
+  name = "terraform-acc-test-node-attribute-{{.}}"
+}
+
+resource "chef_node_attribute" "port" {
+  node  = chef_bootstrap_bundle.test.id
+  path  = "nginx.port"
+  value = 8080
+}
+
+resource "chef_node_attribute" "workers" {
+  node  = chef_bootstrap_bundle.test.id
+  path  = "/nginx/workers"
+  value = ["a", "b"]
+}
+`
+
+const testAccNodeAttributeConfig_removed = `
+resource "chef_bootstrap_bundle" "test" {
+  name = "terraform-acc-test-node-attribute-{{.}}"
+}
+
+resource "chef_node_attribute" "port" {
+  node  = chef_bootstrap_bundle.test.id
+  path  = "nginx.port"
+  value = 8080
+}
+`
@@ -1,12 +1,20 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"reflect"
+	"regexp"
+	"slices"
 	"testing"
 
 	chefc "github.com/go-chef/chef"
 
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
@@ -15,9 +23,9 @@ func TestAccNode_basic(t *testing.T) {
 	var node chefc.Node
 
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccNodeCheckDestroy(&node),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccNodeCheckDestroy(&node),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccNodeConfig_basic),
@@ -61,10 +69,333 @@ func TestAccNode_basic(t *testing.T) {
 					},
 				),
 			},
+			{
+				ResourceName:            "chef_node.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"automatic_attributes_json", "normal_attributes_json", "default_attributes_json", "override_attributes_json"},
+			},
+		},
+	})
+}
+
+func TestAccNode_attributes(t *testing.T) {
+	var node chefc.Node
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccNodeCheckDestroy(&node),
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccNodeConfig_attributes),
+				Check: resource.ComposeTestCheckFunc(
+					testAccNodeCheckExists("chef_node.test", &node),
+					resource.TestCheckResourceAttr("chef_node.test", "normal_attributes_json", `{"nested":{"list":[1,2]},"terraform_acc_test":true}`),
+					resource.TestCheckResourceAttr("chef_node.test", "default_attributes_json", "{}"),
+					func(s *terraform.State) error {
+						expectedAttributes := map[string]interface{}{
+							"terraform_acc_test": true,
+							"nested": map[string]interface{}{
+								"list": []interface{}{float64(1), float64(2)},
+							},
+						}
+						if !reflect.DeepEqual(node.NormalAttributes, expectedAttributes) {
+							return fmt.Errorf("wrong normal attributes; expected %#v, got %#v", expectedAttributes, node.NormalAttributes)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config:   testSuffixRender(testAccNodeConfig_attributes),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccNode_adoptExisting(t *testing.T) {
+	var node chefc.Node
+	name := "terraform-acc-test-adopt-" + testSuffix
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccNodeCheckDestroy(&node),
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					client, err := testAccClient()
+					if err != nil {
+						t.Fatalf("err: %s", err)
+					}
+					if _, err := client.Nodes.Post(chefc.Node{Name: name, Environment: "_default", RunList: []string{"recipe[knife]"}}); err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				},
+				Config:      testSuffixRender(testAccNodeConfig_adopt(false)),
+				ExpectError: regexp.MustCompile("Node already exists"),
+			},
+			{
+				Config: testSuffixRender(testAccNodeConfig_adopt(true)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccNodeCheckExists("chef_node.test", &node),
+					resource.TestCheckResourceAttr("chef_node.test", "adopt_existing", "true"),
+					func(s *terraform.State) error {
+						if expected := []string{"recipe[terraform]"}; !reflect.DeepEqual(node.RunList, expected) {
+							return fmt.Errorf("wrong runlist; expected %#v, got %#v", expected, node.RunList)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccNode_client(t *testing.T) {
+	var node chefc.Node
+	clientName := "terraform-acc-test-node-client-" + testSuffix
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+
+			client, err := testAccClient()
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if _, err := client.Clients.Create(chefc.ApiNewClient{Name: clientName}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+		},
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy: resource.ComposeTestCheckFunc(
+			testAccNodeCheckDestroy(&node),
+			func(s *terraform.State) error {
+				client, err := testAccClient()
+				if err != nil {
+					return err
+				}
+				if _, err := client.Clients.Get(clientName); !isNotFound(err) {
+					return fmt.Errorf("expected the client of the node to be deleted, got %v", err)
+				}
+				return nil
+			},
+		),
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccNodeConfig_client),
+				Check: resource.ComposeTestCheckFunc(
+					testAccNodeCheckExists("chef_node.test", &node),
+					func(s *terraform.State) error {
+						client, err := testAccClient()
+						if err != nil {
+							return err
+						}
+						acl, err := client.ACLs.Get("nodes", node.Name)
+						if err != nil {
+							return err
+						}
+						for _, perm := range []string{"read", "update"} {
+							if !slices.Contains(acl[perm].Actors, clientName) {
+								return fmt.Errorf("client was not granted %s: %v", perm, acl[perm].Actors)
+							}
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccNode_policy(t *testing.T) {
+	var node chefc.Node
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccNodeCheckDestroy(&node),
+		Steps: []resource.TestStep{
+			{
+				Config:      testSuffixRender(testAccNodeConfig_policyConflict),
+				ExpectError: regexp.MustCompile("Conflicting arguments"),
+			},
+			{
+				Config: testSuffixRender(testAccNodeConfig_policy),
+				Check: resource.ComposeTestCheckFunc(
+					testAccNodeCheckExists("chef_node.test", &node),
+					func(s *terraform.State) error {
+						if node.PolicyName != "base" || node.PolicyGroup != "staging" {
+							return fmt.Errorf("wrong policy; expected base/staging, got %s/%s", node.PolicyName, node.PolicyGroup)
+						}
+						return nil
+					},
+					resource.TestCheckResourceAttr("data.chef_node.test", "policy_name", "base"),
+					resource.TestCheckResourceAttr("data.chef_node.test", "policy_group", "staging"),
+				),
+			},
+			{
+				// chef-client saves the run list expanded from the Policyfile,
+				// which must not show a diff.
+				PreConfig: func() {
+					client, err := testAccClient()
+					if err != nil {
+						t.Fatalf("err: %s", err)
+					}
+					saved, err := client.Nodes.Get(node.Name)
+					if err != nil {
+						t.Fatalf("err: %s", err)
+					}
+					saved.RunList = []string{"recipe[base::default]"}
+					if _, err := client.Nodes.Put(saved); err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				},
+				Config:   testSuffixRender(testAccNodeConfig_policy),
+				PlanOnly: true,
+			},
+			{
+				ResourceName:            "chef_node.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"automatic_attributes_json", "normal_attributes_json", "default_attributes_json", "override_attributes_json"},
+			},
+		},
+	})
+}
+
+func TestAccNode_tags(t *testing.T) {
+	var node chefc.Node
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccNodeCheckDestroy(&node),
+		Steps: []resource.TestStep{
+			{
+				Config:      testSuffixRender(testAccNodeConfig_tagsConflict),
+				ExpectError: regexp.MustCompile("Conflicting arguments"),
+			},
+			{
+				Config: testSuffixRender(testAccNodeConfig_tags),
+				Check: resource.ComposeTestCheckFunc(
+					testAccNodeCheckExists("chef_node.test", &node),
+					func(s *terraform.State) error {
+						expected := map[string]interface{}{
+							"app":  "web",
+							"tags": []interface{}{"frontend", "web"},
+						}
+						if !reflect.DeepEqual(node.NormalAttributes, expected) {
+							return fmt.Errorf("wrong normal attributes; expected %#v, got %#v", expected, node.NormalAttributes)
+						}
+						return nil
+					},
+					resource.TestCheckResourceAttr("chef_node.test", "normal_attributes_json", `{"app":"web"}`),
+					resource.TestCheckTypeSetElemAttr("data.chef_node.test", "tags.*", "frontend"),
+					resource.TestCheckTypeSetElemAttr("data.chef_search.test", "tags.*", "web"),
+					resource.TestCheckResourceAttr("data.chef_search.test", "tags.#", "2"),
+				),
+			},
+		},
+	})
+}
+
+// TestAccNode_tagsOnly checks that a node that only manages its tags keeps
+// the normal attributes saved by others, such as chef-client.
+func TestAccNode_tagsOnly(t *testing.T) {
+	var node chefc.Node
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccNodeCheckDestroy(&node),
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccNodeConfig_tagsOnly),
+				Check:  testAccNodeCheckExists("chef_node.test", &node),
+			},
+			{
+				PreConfig: func() {
+					client, err := testAccClient()
+					if err != nil {
+						t.Fatalf("err: %s", err)
+					}
+					err = updateNodeAttribute(client, node.Name, "normal", []string{"chef_client", "interval"}, 1800.0, false)
+					if err != nil {
+						t.Fatalf("error saving a normal attribute as chef-client would: %s", err)
+					}
+				},
+				Config: testSuffixRender(testAccNodeConfig_tagsOnlyUpdated),
+				Check: resource.ComposeTestCheckFunc(
+					testAccNodeCheckExists("chef_node.test", &node),
+					func(s *terraform.State) error {
+						expected := map[string]interface{}{
+							"chef_client": map[string]interface{}{"interval": 1800.0},
+							"tags":        []interface{}{"db", "web"},
+						}
+						if !reflect.DeepEqual(node.NormalAttributes, expected) {
+							return fmt.Errorf("wrong normal attributes; expected %#v, got %#v", expected, node.NormalAttributes)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				ResourceName:            "chef_node.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"automatic_attributes_json", "normal_attributes_json", "default_attributes_json", "override_attributes_json"},
+			},
 		},
 	})
 }
 
+// TestAccNode_deleteRetry checks that destroying a node can be retried
+// after the node was deleted but deleting its client failed.
+func TestAccNode_deleteRetry(t *testing.T) {
+	if os.Getenv("TF_ACC") == "" {
+		t.Skip("acceptance tests are skipped unless TF_ACC is set")
+	}
+	testAccPreCheck(t)
+	ctx := context.Background()
+	client, err := testAccClient()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	name := "terraform-acc-test-delete-retry-" + testSuffix
+	if _, err := client.Clients.Create(chefc.ApiNewClient{Name: name}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	r := &nodeResource{frameworkResource{client: client}}
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	state := tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+	diags := state.Set(ctx, &nodeResourceModel{
+		ID:                    types.StringValue(name),
+		Name:                  types.StringValue(name),
+		DeleteClientOnDestroy: types.BoolValue(true),
+		RunList:               types.ListNull(types.StringType),
+		Tags:                  types.SetNull(types.StringType),
+	})
+	if diags.HasError() {
+		t.Fatalf("err: %v", diags)
+	}
+
+	var resp fwresource.DeleteResponse
+	r.Delete(ctx, fwresource.DeleteRequest{State: state}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error destroying a node that is already gone: %v", resp.Diagnostics)
+	}
+	if _, err := client.Clients.Get(name); !isNotFound(err) {
+		t.Errorf("expected the client of the node to be deleted, got %v", err)
+	}
+}
+
 func testAccNodeCheckExists(rn string, node *chefc.Node) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[rn]
@@ -76,7 +407,10 @@ func testAccNodeCheckExists(rn string, node *chefc.Node) resource.TestCheckFunc
 			return fmt.Errorf("node id not set")
 		}
 
-		client := testAccProvider.Meta().(*chefClient)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		gotNode, err := client.Nodes.Get(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("error getting node: %s", err)
@@ -90,8 +424,11 @@ func testAccNodeCheckExists(rn string, node *chefc.Node) resource.TestCheckFunc
 
 func testAccNodeCheckDestroy(node *chefc.Node) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
-		client := testAccProvider.Meta().(*chefClient)
-		_, err := client.Nodes.Get(node.Name)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
+		_, err = client.Nodes.Get(node.Name)
 		if err == nil {
 			return fmt.Errorf("node still exists")
 		}
@@ -136,3 +473,93 @@ EOT
   run_list = ["terraform@1.0.0", "recipe[consul]", "role[foo]"]
 }
 `
+
+const testAccNodeConfig_attributes = `
+resource "chef_node" "test" {
+  name = "terraform-acc-test-attributes-{{.}}"
+  normal_attributes = {
+    terraform_acc_test = true
+    nested = {
+      list = [1, 2]
+    }
+  }
+}
+`
+
+func testAccNodeConfig_adopt(adopt bool) string {
+	return fmt.Sprintf(`
+resource "chef_node" "test" {
+  name           = "terraform-acc-test-adopt-{{.}}"
+  run_list       = ["recipe[terraform]"]
+  adopt_existing = %t
+}
+`, adopt)
+}
+
+const testAccNodeConfig_client = `
+resource "chef_node" "test" {
+  name                     = "terraform-acc-test-client-{{.}}"
+  client_name              = "terraform-acc-test-node-client-{{.}}"
+  delete_client_on_destroy = true
+}
+`
+
+const testAccNodeConfig_policy = `
+resource "chef_node" "test" {
+  name         = "terraform-acc-test-policy-{{.}}"
+  policy_name  = "base"
+  policy_group = "staging"
+}
+
+data "chef_node" "test" {
+  name = chef_node.test.id
+}
+`
+
+const testAccNodeConfig_policyConflict = `
+resource "chef_node" "test" {
+  name         = "terraform-acc-test-policy-{{.}}"
+  run_list     = ["recipe[terraform]"]
+  policy_name  = "base"
+  policy_group = "staging"
+}
+`
+
+const testAccNodeConfig_tags = `
+resource "chef_node" "test" {
+  name                   = "terraform-acc-test-tags-{{.}}"
+  normal_attributes_json = jsonencode({ app = "web" })
+  tags                   = ["web", "frontend"]
+}
+
+data "chef_node" "test" {
+  name = chef_node.test.id
+}
+
+data "chef_search" "test" {
+  query  = "tags:frontend AND name:${chef_node.test.id}"
+  unique = true
+}
+`
+
+const testAccNodeConfig_tagsConflict = `
+resource "chef_node" "test" {
+  name                   = "terraform-acc-test-tags-{{.}}"
+  normal_attributes_json = jsonencode({ tags = ["web"] })
+  tags                   = ["web"]
+}
+`
+
+const testAccNodeConfig_tagsOnly = `
+resource "chef_node" "test" {
+  name = "terraform-acc-test-tags-only-{{.}}"
+  tags = ["web"]
+}
+`
+
+const testAccNodeConfig_tagsOnlyUpdated = `
+resource "chef_node" "test" {
+  name = "terraform-acc-test-tags-only-{{.}}"
+  tags = ["web", "db"]
+}
+`
@@ -4,254 +4,675 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-
-	"github.com/hashicorp/go-cty/cty"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	chefc "github.com/go-chef/chef"
 )
 
-func resourceChefRole() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: CreateRole,
-		UpdateContext: UpdateRole,
-		ReadContext:   ReadRole,
-		DeleteContext: DeleteRole,
-		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
-		},
+var (
+	_ resource.ResourceWithImportState    = &roleResource{}
+	_ resource.ResourceWithModifyPlan     = &roleResource{}
+	_ resource.ResourceWithUpgradeState   = &roleResource{}
+	_ resource.ResourceWithValidateConfig = &roleResource{}
+)
+
+func NewRoleResource() resource.Resource {
+	return &roleResource{}
+}
+
+type roleResource struct {
+	frameworkResource
+}
+
+type roleResourceModel struct {
+	ID                     types.String  `tfsdk:"id"`
+	Name                   types.String  `tfsdk:"name"`
+	Description            types.String  `tfsdk:"description"`
+	AdoptExisting          types.Bool    `tfsdk:"adopt_existing"`
+	DeletionProtection     types.Bool    `tfsdk:"deletion_protection"`
+	IgnoreProviderDefaults types.Bool    `tfsdk:"ignore_provider_default_attributes"`
+	DefaultAttributesJson  types.String  `tfsdk:"default_attributes_json"`
+	DefaultAttributes      types.Dynamic `tfsdk:"default_attributes"`
+	OverrideAttributesJson types.String  `tfsdk:"override_attributes_json"`
+	OverrideAttributes     types.Dynamic `tfsdk:"override_attributes"`
+	EnvRunListJson         types.String  `tfsdk:"env_run_list_json"`
+	EnvRunList             types.Set     `tfsdk:"env_run_list"`
+	RunList                types.List    `tfsdk:"run_list"`
+}
+
+// roleEnvRunListModel is an env_run_list block, the run list of a role in
+// one environment.
+type roleEnvRunListModel struct {
+	Environment types.String `tfsdk:"environment"`
+	RunList     types.List   `tfsdk:"run_list"`
+}
+
+var roleEnvRunListType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"environment": types.StringType,
+		"run_list":    types.ListType{ElemType: types.StringType},
+	},
+}
+
+func (m *roleResourceModel) attributePairs() []attributesPair {
+	return []attributesPair{
+		newAttributesPair("default_attributes", &m.DefaultAttributesJson, &m.DefaultAttributes),
+		newAttributesPair("override_attributes", &m.OverrideAttributesJson, &m.OverrideAttributes),
+	}
+}
+
+func (r *roleResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_role"
+}
 
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:     schema.TypeString,
+func (r *roleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
 				Required: true,
-				ForceNew: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"description": {
-				Type:     schema.TypeString,
+			"description": schema.StringAttribute{
 				Optional: true,
-				Default:  "Managed by Terraform",
-			},
-			"default_attributes_json": {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Default:   "{}",
-				StateFunc: jsonStateFunc,
+				Computed: true,
+				Default:  stringdefault.StaticString("Managed by Terraform"),
 			},
-			"override_attributes_json": {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Default:   "{}",
-				StateFunc: jsonStateFunc,
+			"adopt_existing":                     adoptExistingSchema("role"),
+			"deletion_protection":                deletionProtectionSchema("role"),
+			"ignore_provider_default_attributes": ignoreProviderDefaultAttributesSchema("role"),
+			"default_attributes_json":            attributesJsonSchema("default_attributes"),
+			"default_attributes":                 attributesDynamicSchema("default_attributes"),
+			"override_attributes_json":           attributesJsonSchema("override_attributes"),
+			"override_attributes":                attributesDynamicSchema("override_attributes"),
+			"env_run_list_json": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Description: "Run lists per environment as a JSON object. Conflicts with `env_run_list`, " +
+					"and reflects the `env_run_list` blocks when they are used.",
+				DeprecationMessage: "Use env_run_list blocks instead, which normalize run list entries and show a diff per environment.",
 			},
-			"env_run_list_json": {
-				Type:      schema.TypeString,
-				Optional:  true,
-				Default:   "{}",
-				StateFunc: jsonStateFunc,
+			"run_list": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
 			},
-			"run_list": {
-				Type:     schema.TypeList,
-				Optional: true,
-				Elem: &schema.Schema{
-					Type:      schema.TypeString,
-					StateFunc: runListEntryStateFunc,
+		},
+		Blocks: map[string]schema.Block{
+			"env_run_list": schema.SetNestedBlock{
+				Description: "Run list of the role in an environment, used instead of `run_list` by nodes in that environment. Conflicts with `env_run_list_json`.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"environment": schema.StringAttribute{
+							Required: true,
+						},
+						"run_list": schema.ListAttribute{
+							ElementType: types.StringType,
+							Required:    true,
+						},
+					},
 				},
 			},
 		},
 	}
 }
 
-func CreateRole(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+func (r *roleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config roleResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	role, err := roleFromResourceData(d)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity:      diag.Error,
-				Summary:       "Error reading Chef Role from Resource Data",
-				Detail:        fmt.Sprint(err),
-				AttributePath: cty.GetAttrPath("name"),
-			},
+	for _, p := range config.attributePairs() {
+		p.validateConfig(&resp.Diagnostics)
+	}
+
+	if len(config.EnvRunList.Elements()) == 0 {
+		return
+	}
+	if !config.EnvRunListJson.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("env_run_list"), "Conflicting arguments",
+			"env_run_list cannot be combined with env_run_list_json.")
+	}
+	envRunLists, diags := envRunListsFromSet(ctx, config.EnvRunList)
+	resp.Diagnostics.Append(diags...)
+	seen := map[string]bool{}
+	for _, e := range envRunLists {
+		if e.Environment.IsUnknown() {
+			continue
+		}
+		environment := e.Environment.ValueString()
+		if seen[environment] {
+			resp.Diagnostics.AddAttributeError(path.Root("env_run_list"), "Duplicate environment",
+				fmt.Sprintf("The environment %q has more than one env_run_list block.", environment))
 		}
+		seen[environment] = true
 	}
+}
 
-	_, err = client.Roles.Create(role)
-	if err != nil {
-		resp := diag.Diagnostic{Severity: diag.Error, Summary: "Error creating Chef Role", AttributePath: cty.GetAttrPath("name")}
-		if cheferr, ok := err.(*chefc.ErrorResponse); ok {
-			resp.Detail = fmt.Sprintln(cheferr.ErrorMsg, cheferr)
-		} else {
-			resp.Detail = fmt.Sprint(err)
+func (r *roleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var config, plan roleResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state *roleResourceModel
+	if !req.State.Raw.IsNull() {
+		state = &roleResourceModel{}
+		resp.Diagnostics.Append(req.State.Get(ctx, state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	statePairs := (&roleResourceModel{}).attributePairs()
+	if state != nil {
+		statePairs = state.attributePairs()
+		resp.Diagnostics.Append(planEnvRunList(ctx, &plan.EnvRunList, state.EnvRunList)...)
+		resp.Diagnostics.Append(planRunList(ctx, &plan.RunList, state.RunList)...)
+	}
+	if config.EnvRunListJson.IsNull() {
+		resp.Diagnostics.Append(planEnvRunListJson(ctx, &plan)...)
+	}
+	if state != nil {
+		planJsonString(&plan.EnvRunListJson, state.EnvRunListJson)
+		// An imported role only records env_run_list, until it changes.
+		if state.EnvRunListJson.IsNull() && config.EnvRunListJson.IsNull() && plan.EnvRunList.Equal(state.EnvRunList) {
+			plan.EnvRunListJson = types.StringNull()
 		}
-		return diag.Diagnostics{resp}
+	}
+	configPairs, planPairs := config.attributePairs(), plan.attributePairs()
+	for i := range planPairs {
+		planAttributesPair(configPairs[i], statePairs[i], planPairs[i], &resp.Diagnostics)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateRoleReferences(ctx, r.client, &plan, state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client != nil {
+		r.adoption(&plan, nil).plan(ctx, req, &resp.Diagnostics)
 	}
 
-	d.SetId(role.Name)
-	return ReadRole(ctx, d, meta)
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
 }
 
-func UpdateRole(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+func (r *roleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
 
-	role, err := roleFromResourceData(d)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity:      diag.Error,
-				Summary:       "Error reading Chef Role from Resource Data",
-				Detail:        fmt.Sprint(err),
-				AttributePath: cty.GetAttrPath("name"),
-			},
-		}
+	var plan roleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	role, diags := roleFromModel(ctx, &plan, r.client.defaultAttributesFor(plan.IgnoreProviderDefaults))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.adoption(&plan, role).create(ctx, func() error {
+		_, err := r.client.Roles.Create(role)
+		return err
+	})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.readInto(ctx, role.Name, &plan, resp.Private, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *roleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if r.client == nil {
+		return
+	}
+
+	var state roleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	_, err = client.Roles.Put(role)
+	role, err := r.client.Roles.Get(state.ID.ValueString())
 	if err != nil {
-		resp := diag.Diagnostic{Severity: diag.Error, Summary: "Error updating Chef Role", AttributePath: cty.GetAttrPath("name")}
-		if cheferr, ok := err.(*chefc.ErrorResponse); ok {
-			resp.Detail = fmt.Sprintln(cheferr.ErrorMsg, cheferr)
-		} else {
-			resp.Detail = fmt.Sprint(err)
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
 		}
-		return diag.Diagnostics{resp}
+		resp.Diagnostics.Append(chefDiagnostic("Error reading Chef Role", err, path.Root("name")))
+		return
 	}
 
-	d.SetId(role.Name)
-	return ReadRole(ctx, d, meta)
+	defaultFalse(&state.AdoptExisting)
+	defaultFalse(&state.DeletionProtection)
+	defaultFalse(&state.IgnoreProviderDefaults)
+	resp.Diagnostics.Append(roleToModel(ctx, role, &state, r.client.defaultAttributesFor(state.IgnoreProviderDefaults))...)
+	resp.Diagnostics.Append(setSnapshot(ctx, resp.Private, role)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
-func ReadRole(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+func (r *roleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
 
-	name := d.Id()
+	var plan, state roleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	role, err := client.Roles.Get(name)
-	if err != nil {
-		if errRes, ok := err.(*chefc.ErrorResponse); ok {
-			if errRes.Response.StatusCode == 404 {
-				d.SetId("")
-				return nil
-			}
-		} else {
-			return diag.Diagnostics{
-				{
-					Severity:      diag.Error,
-					Summary:       "Error reading Chef Role",
-					Detail:        fmt.Sprint(err),
-					AttributePath: cty.GetAttrPath("name"),
-				},
-			}
+	role, diags := roleFromModel(ctx, &plan, r.client.defaultAttributesFor(plan.IgnoreProviderDefaults))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	base, diags := roleFromModel(ctx, &state, r.client.defaultAttributesFor(state.IgnoreProviderDefaults))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	merged, diags := r.client.checkConflicts(ctx, req.Private, "role", base, role, func() (interface{}, error) {
+		return r.client.Roles.Get(role.Name)
+	})
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if merged != nil {
+		role = &chefc.Role{}
+		if err := json.Unmarshal(merged, role); err != nil {
+			resp.Diagnostics.AddError("Error merging changes", err.Error())
+			return
 		}
 	}
 
-	d.Set("name", role.Name)
-	d.Set("description", role.Description)
+	if _, err := r.client.Roles.Put(role); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error updating Chef Role", err, path.Root("name")))
+		return
+	}
+
+	// Merged changes made outside of Terraform are left for the next plan,
+	// as the state must match the plan.
+	read := plan
+	r.readInto(ctx, role.Name, &read, resp.Private, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if merged == nil {
+		plan = read
+	} else {
+		fillUnknown(&plan, &read)
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *roleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !requireClient(r.client, &resp.Diagnostics) {
+		return
+	}
+
+	var state roleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !checkDeletionProtection(state.DeletionProtection, "role", state.ID.ValueString(), &resp.Diagnostics) {
+		return
+	}
+
+	if err := r.client.Roles.Delete(state.ID.ValueString()); err != nil {
+		resp.Diagnostics.Append(chefDiagnostic("Error deleting Chef Role", err, path.Root("name")))
+	}
+}
+
+func (r *roleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	resp.Diagnostics.Append(importAttributesPairs(ctx, (&roleResourceModel{}).attributePairs(), &resp.State)...)
+}
+
+// adoption takes over an existing role by replacing it with role.
+func (r *roleResource) adoption(m *roleResourceModel, role *chefc.Role) adoption {
+	return adoption{
+		Kind:      "role",
+		Name:      m.Name.ValueString(),
+		Adopt:     m.AdoptExisting,
+		Attribute: path.Root("name"),
+		Get: func() (interface{}, error) {
+			return r.client.Roles.Get(m.Name.ValueString())
+		},
+		Update: func() error {
+			_, err := r.client.Roles.Put(role)
+			return err
+		},
+	}
+}
 
-	defaultAttrJson, err := json.Marshal(role.DefaultAttributes)
+// readInto reads a role back from the Chef server after it was written.
+func (r *roleResource) readInto(ctx context.Context, name string, m *roleResourceModel, private privateStateSetter, diags *diag.Diagnostics) {
+	role, err := r.client.Roles.Get(name)
 	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity:      diag.Error,
-				Summary:       "Error reading Chef Role default attributes as JSON",
-				Detail:        fmt.Sprint(err),
-				AttributePath: cty.GetAttrPath("name"),
-			},
+		diags.Append(chefDiagnostic("Error reading Chef Role", err, path.Root("name")))
+		return
+	}
+	diags.Append(roleToModel(ctx, role, m, r.client.defaultAttributesFor(m.IgnoreProviderDefaults))...)
+	diags.Append(setSnapshot(ctx, private, role)...)
+}
+
+// roleFromModel returns the role to write to the Chef server, with the
+// default attributes of the provider merged in.
+func roleFromModel(ctx context.Context, m *roleResourceModel, defaults defaultAttributes) (*chefc.Role, diag.Diagnostics) {
+	role := &chefc.Role{
+		Name:        m.Name.ValueString(),
+		Description: m.Description.ValueString(),
+		ChefType:    "role",
+	}
+
+	targets := []*interface{}{
+		&role.DefaultAttributes,
+		&role.OverrideAttributes,
+	}
+	precedences := []map[string]interface{}{defaults.Default, defaults.Override}
+	for i, p := range m.attributePairs() {
+		v, diags := p.value()
+		if diags.HasError() {
+			return nil, diags
 		}
+		*targets[i] = mergeDefaultAttributes(precedences[i], v)
 	}
-	d.Set("default_attributes_json", string(defaultAttrJson))
 
-	overrideAttrJson, err := json.Marshal(role.OverrideAttributes)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity:      diag.Error,
-				Summary:       "Error reading Chef Role override attributes as JSON",
-				Detail:        fmt.Sprint(err),
-				AttributePath: cty.GetAttrPath("name"),
-			},
+	// The blocks take precedence, as env_run_list_json is only known after
+	// apply when they contain values that are.
+	envRunLists, diags := envRunListsFromSet(ctx, m.EnvRunList)
+	if diags.HasError() {
+		return nil, diags
+	}
+	if len(envRunLists) > 0 {
+		role.EnvRunList = chefc.EnvRunList{}
+		for _, e := range envRunLists {
+			runList, diags := runListFromList(ctx, e.RunList)
+			if diags.HasError() {
+				return nil, diags
+			}
+			role.EnvRunList[e.Environment.ValueString()] = runList
 		}
+	} else if m.EnvRunListJson.IsNull() {
+		role.EnvRunList = chefc.EnvRunList{}
+	} else if err := json.Unmarshal([]byte(m.EnvRunListJson.ValueString()), &role.EnvRunList); err != nil {
+		diags.AddAttributeError(path.Root("env_run_list_json"), "Invalid JSON", err.Error())
+		return nil, diags
 	}
-	d.Set("override_attributes_json", string(overrideAttrJson))
 
-	envRunlistJson, err := json.Marshal(role.EnvRunList)
-	if err != nil {
-		return diag.Diagnostics{
-			{
-				Severity:      diag.Error,
-				Summary:       "Error reading Chef Role ENV Run List as JSON",
-				Detail:        fmt.Sprint(err),
-				AttributePath: cty.GetAttrPath("name"),
-			},
+	runList, diags := runListFromList(ctx, m.RunList)
+	if diags.HasError() {
+		return nil, diags
+	}
+	role.RunList = runList
+
+	return role, nil
+}
+
+// roleToModel records a role read from the Chef server, leaving out the
+// default attributes that the provider merged in.
+func roleToModel(ctx context.Context, role *chefc.Role, m *roleResourceModel, defaults defaultAttributes) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	m.ID = types.StringValue(role.Name)
+	m.Name = types.StringValue(role.Name)
+	m.Description = types.StringValue(role.Description)
+
+	values := []interface{}{
+		role.DefaultAttributes,
+		role.OverrideAttributes,
+	}
+	precedences := []map[string]interface{}{defaults.Default, defaults.Override}
+	for i, p := range m.attributePairs() {
+		if err := setAttributesPair(p, withoutDefaultAttributes(values[i], precedences[i], p)); err != nil {
+			diags.AddAttributeError(p.JsonPath, "Error reading Chef Role attributes as JSON", err.Error())
 		}
 	}
-	d.Set("env_run_list_json", string(envRunlistJson))
 
-	runList := make([]string, len(role.RunList))
-	copy(runList, role.RunList)
+	// env_run_list_json is null for imported roles, which only record the
+	// env_run_list blocks.
+	if !m.EnvRunListJson.IsNull() {
+		if err := setJsonString(&m.EnvRunListJson, role.EnvRunList); err != nil {
+			diags.AddAttributeError(path.Root("env_run_list_json"), "Error reading Chef Role ENV Run List as JSON", err.Error())
+		}
+	}
+	diags.Append(setEnvRunList(ctx, &m.EnvRunList, role.EnvRunList)...)
 
-	d.Set("run_list", runList)
+	diags.Append(setRunList(ctx, &m.RunList, role.RunList)...)
+	return diags
+}
 
-	return nil
+func envRunListsFromSet(ctx context.Context, s types.Set) ([]roleEnvRunListModel, diag.Diagnostics) {
+	envRunLists := []roleEnvRunListModel{}
+	if s.IsNull() || s.IsUnknown() {
+		return envRunLists, nil
+	}
+	diags := s.ElementsAs(ctx, &envRunLists, false)
+	return envRunLists, diags
 }
 
-func DeleteRole(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*chefClient)
+// planEnvRunList keeps the prior state of the run list of each environment
+// when the configuration only differs in whether recipes are written naked
+// or qualified, as planRunList does for run_list.
+func planEnvRunList(ctx context.Context, plan *types.Set, state types.Set) diag.Diagnostics {
+	if plan.IsNull() || plan.IsUnknown() || state.IsNull() || state.IsUnknown() {
+		return nil
+	}
 
-	name := d.Id()
+	planned, diags := envRunListsFromSet(ctx, *plan)
+	prior, d := envRunListsFromSet(ctx, state)
+	diags.Append(d...)
+	if diags.HasError() {
+		return diags
+	}
 
-	err := client.Roles.Delete(name)
-	if err != nil {
-		resp := diag.Diagnostic{Severity: diag.Error, Summary: "Error deleting Chef Role", AttributePath: cty.GetAttrPath("name")}
-		if cheferr, ok := err.(*chefc.ErrorResponse); ok {
-			resp.Detail = fmt.Sprintln(cheferr.ErrorMsg, cheferr)
-		} else {
-			resp.Detail = fmt.Sprint(err)
+	priorByEnvironment := make(map[string]roleEnvRunListModel, len(prior))
+	for _, e := range prior {
+		priorByEnvironment[e.Environment.ValueString()] = e
+	}
+	for i, e := range planned {
+		if e.Environment.IsUnknown() {
+			continue
+		}
+		if p, ok := priorByEnvironment[e.Environment.ValueString()]; ok {
+			diags.Append(planRunList(ctx, &planned[i].RunList, p.RunList)...)
 		}
-		return diag.Diagnostics{resp}
 	}
-	return nil
+	if diags.HasError() {
+		return diags
+	}
+
+	s, d := types.SetValueFrom(ctx, roleEnvRunListType, planned)
+	diags.Append(d...)
+	*plan = s
+	return diags
 }
 
-func roleFromResourceData(d *schema.ResourceData) (*chefc.Role, error) {
+// planEnvRunListJson plans env_run_list_json when it is not configured: it
+// reflects the env_run_list blocks, or is empty without any.
+func planEnvRunListJson(ctx context.Context, plan *roleResourceModel) diag.Diagnostics {
+	if plan.EnvRunList.IsUnknown() {
+		plan.EnvRunListJson = types.StringUnknown()
+		return nil
+	}
+	envRunLists, diags := envRunListsFromSet(ctx, plan.EnvRunList)
+	if diags.HasError() {
+		return diags
+	}
 
-	role := &chefc.Role{
-		Name:        d.Get("name").(string),
-		Description: d.Get("description").(string),
-		ChefType:    "role",
+	value := map[string][]string{}
+	for _, e := range envRunLists {
+		if e.Environment.IsUnknown() || e.RunList.IsUnknown() {
+			plan.EnvRunListJson = types.StringUnknown()
+			return nil
+		}
+		runList := []string{}
+		for _, entry := range e.RunList.Elements() {
+			s, ok := entry.(types.String)
+			if !ok || s.IsUnknown() {
+				plan.EnvRunListJson = types.StringUnknown()
+				return nil
+			}
+			runList = append(runList, runListEntryStateFunc(s.ValueString()))
+		}
+		value[e.Environment.ValueString()] = runList
 	}
 
-	err := json.Unmarshal(
-		[]byte(d.Get("default_attributes_json").(string)),
-		&role.DefaultAttributes,
-	)
+	b, err := json.Marshal(value)
 	if err != nil {
-		return nil, fmt.Errorf("default_attributes_json: %s", err)
+		diags.AddAttributeError(path.Root("env_run_list"), "Error encoding environment run lists as JSON", err.Error())
+		return diags
 	}
+	plan.EnvRunListJson = types.StringValue(string(b))
+	return diags
+}
 
-	err = json.Unmarshal(
-		[]byte(d.Get("override_attributes_json").(string)),
-		&role.OverrideAttributes,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("override_attributes_json: %s", err)
+// setEnvRunList records the environment run lists read from the Chef server
+// in the env_run_list blocks, if they are used or not yet known, as after an
+// import; otherwise they are only recorded in env_run_list_json. Run lists
+// that are equivalent to the current ones are kept.
+func setEnvRunList(ctx context.Context, current *types.Set, envRunList chefc.EnvRunList) diag.Diagnostics {
+	if !current.IsNull() && (current.IsUnknown() || len(current.Elements()) == 0) {
+		*current = types.SetValueMust(roleEnvRunListType, []attr.Value{})
+		return nil
 	}
 
-	err = json.Unmarshal(
-		[]byte(d.Get("env_run_list_json").(string)),
-		&role.EnvRunList,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("env_run_list_json: %s", err)
+	existing, diags := envRunListsFromSet(ctx, *current)
+	if diags.HasError() {
+		return diags
+	}
+	existingRunLists := make(map[string]types.List, len(existing))
+	for _, e := range existing {
+		existingRunLists[e.Environment.ValueString()] = e.RunList
 	}
 
-	runListI := d.Get("run_list").([]interface{})
-	role.RunList = make([]string, len(runListI))
-	for i, vI := range runListI {
-		role.RunList[i] = vI.(string)
+	environments := make([]string, 0, len(envRunList))
+	for environment := range envRunList {
+		environments = append(environments, environment)
 	}
+	sort.Strings(environments)
 
-	return role, nil
+	envRunLists := make([]roleEnvRunListModel, 0, len(environments))
+	for _, environment := range environments {
+		runList, ok := existingRunLists[environment]
+		if !ok {
+			runList = types.ListUnknown(types.StringType)
+		}
+		diags.Append(setRunList(ctx, &runList, envRunList[environment])...)
+		envRunLists = append(envRunLists, roleEnvRunListModel{
+			Environment: types.StringValue(environment),
+			RunList:     runList,
+		})
+	}
+	if diags.HasError() {
+		return diags
+	}
+
+	s, d := types.SetValueFrom(ctx, roleEnvRunListType, envRunLists)
+	diags.Append(d...)
+	*current = s
+	return diags
+}
+
+func (r *roleResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// Version 0 is the state written by the SDKv2 implementation,
+		// which only had the *_json arguments.
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":                       schema.StringAttribute{Computed: true},
+					"name":                     schema.StringAttribute{Required: true},
+					"description":              schema.StringAttribute{Optional: true},
+					"default_attributes_json":  schema.StringAttribute{Optional: true},
+					"override_attributes_json": schema.StringAttribute{Optional: true},
+					"env_run_list_json":        schema.StringAttribute{Optional: true},
+					"run_list":                 schema.ListAttribute{ElementType: types.StringType, Optional: true},
+				},
+			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior struct {
+					ID                     types.String `tfsdk:"id"`
+					Name                   types.String `tfsdk:"name"`
+					Description            types.String `tfsdk:"description"`
+					DefaultAttributesJson  types.String `tfsdk:"default_attributes_json"`
+					OverrideAttributesJson types.String `tfsdk:"override_attributes_json"`
+					EnvRunListJson         types.String `tfsdk:"env_run_list_json"`
+					RunList                types.List   `tfsdk:"run_list"`
+				}
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				state := roleResourceModel{
+					ID:                     prior.ID,
+					Name:                   prior.Name,
+					Description:            prior.Description,
+					AdoptExisting:          types.BoolValue(false),
+					DeletionProtection:     types.BoolValue(false),
+					IgnoreProviderDefaults: types.BoolValue(false),
+					DefaultAttributesJson:  prior.DefaultAttributesJson,
+					OverrideAttributesJson: prior.OverrideAttributesJson,
+					EnvRunListJson:         prior.EnvRunListJson,
+					EnvRunList:             types.SetValueMust(roleEnvRunListType, []attr.Value{}),
+					RunList:                prior.RunList,
+				}
+				if state.EnvRunListJson.IsNull() {
+					state.EnvRunListJson = types.StringValue("{}")
+				}
+				for _, p := range state.attributePairs() {
+					upgradeAttributesPair(p, &resp.Diagnostics)
+				}
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			},
+		},
+	}
 }
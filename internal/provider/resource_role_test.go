@@ -1,12 +1,15 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"regexp"
 	"testing"
 
 	chefc "github.com/go-chef/chef"
 
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
@@ -15,9 +18,9 @@ func TestAccRole_basic(t *testing.T) {
 	var role chefc.Role
 
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccRoleCheckDestroy(&role),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccRoleCheckDestroy(&role),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccRoleConfig_basic),
@@ -59,10 +62,135 @@ func TestAccRole_basic(t *testing.T) {
 					},
 				),
 			},
+			{
+				ResourceName:            "chef_role.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"default_attributes_json", "override_attributes_json", "env_run_list_json"},
+			},
+		},
+	})
+}
+
+func TestAccRole_deletionProtection(t *testing.T) {
+	var role chefc.Role
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccRoleCheckDestroy(&role),
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccRoleConfig_deletionProtection(true)),
+				Check:  testAccRoleCheckExists("chef_role.test", &role),
+			},
+			{
+				Config:      testSuffixRender(testAccRoleConfig_deletionProtection(true)),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("Cannot delete protected role"),
+			},
+			{
+				Config: testSuffixRender(testAccRoleConfig_deletionProtection(false)),
+			},
+		},
+	})
+}
+
+func TestAccRole_envRunList(t *testing.T) {
+	var role chefc.Role
+
+	checkEnvRunList := func(s *terraform.State) error {
+		expected := chefc.EnvRunList{
+			"production": chefc.RunList{"recipe[terraform]", "role[foo]"},
+			"staging":    chefc.RunList{"recipe[terraform::test]"},
+		}
+		if !reflect.DeepEqual(role.EnvRunList, expected) {
+			return fmt.Errorf("wrong environment run list; expected %#v, got %#v", expected, role.EnvRunList)
+		}
+		return nil
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccRoleCheckDestroy(&role),
+		Steps: []resource.TestStep{
+			{
+				Config: testSuffixRender(testAccRoleConfig_envRunListJson),
+				Check: resource.ComposeTestCheckFunc(
+					testAccRoleCheckExists("chef_role.test", &role),
+					checkEnvRunList,
+				),
+			},
+			{
+				// Moving from env_run_list_json to the blocks leaves the role
+				// unchanged, and naked recipes do not cause a diff.
+				Config: testSuffixRender(testAccRoleConfig_envRunList),
+				Check: resource.ComposeTestCheckFunc(
+					testAccRoleCheckExists("chef_role.test", &role),
+					checkEnvRunList,
+					resource.TestCheckResourceAttr("chef_role.test", "env_run_list.#", "2"),
+					resource.TestCheckTypeSetElemNestedAttrs("chef_role.test", "env_run_list.*", map[string]string{
+						"environment": "staging",
+						"run_list.0":  "terraform::test",
+					}),
+				),
+			},
+			{
+				// The imported blocks have qualified recipes, which the next
+				// step checks are planned without a diff.
+				ResourceName:            "chef_role.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"env_run_list", "default_attributes_json", "override_attributes_json", "env_run_list_json"},
+				ImportStatePersist:      true,
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if n := states[0].Attributes["env_run_list.#"]; n != "2" {
+						return fmt.Errorf("expected 2 imported env_run_list blocks, got %s", n)
+					}
+					return nil
+				},
+			},
+			{
+				Config:   testSuffixRender(testAccRoleConfig_envRunList),
+				PlanOnly: true,
+			},
+			{
+				Config:      testSuffixRender(testAccRoleConfig_envRunListConflict),
+				ExpectError: regexp.MustCompile("Conflicting arguments"),
+			},
 		},
 	})
 }
 
+func TestPlanEnvRunList(t *testing.T) {
+	ctx := context.Background()
+	envRunList := func(runList ...string) types.Set {
+		l, _ := types.ListValueFrom(ctx, types.StringType, runList)
+		s, _ := types.SetValueFrom(ctx, roleEnvRunListType, []roleEnvRunListModel{
+			{Environment: types.StringValue("production"), RunList: l},
+		})
+		return s
+	}
+
+	state := envRunList("recipe[foo]", "role[bar]")
+	plan := envRunList("foo", "role[bar]")
+	if diags := planEnvRunList(ctx, &plan, state); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if !plan.Equal(state) {
+		t.Errorf("expected the prior state to be kept, got %s", plan)
+	}
+
+	plan = envRunList("foo", "role[baz]")
+	if diags := planEnvRunList(ctx, &plan, state); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if expected := envRunList("foo", "role[baz]"); !plan.Equal(expected) {
+		t.Errorf("expected the configuration to be planned, got %s", plan)
+	}
+}
+
 func testAccRoleCheckExists(rn string, role *chefc.Role) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[rn]
@@ -74,7 +202,10 @@ func testAccRoleCheckExists(rn string, role *chefc.Role) resource.TestCheckFunc
 			return fmt.Errorf("role id not set")
 		}
 
-		client := testAccProvider.Meta().(*chefClient)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		gotRole, err := client.Roles.Get(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("error getting role: %s", err)
@@ -88,8 +219,11 @@ func testAccRoleCheckExists(rn string, role *chefc.Role) resource.TestCheckFunc
 
 func testAccRoleCheckDestroy(role *chefc.Role) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
-		client := testAccProvider.Meta().(*chefClient)
-		_, err := client.Roles.Get(role.Name)
+		client, err := testAccClient()
+		if err != nil {
+			return err
+		}
+		_, err = client.Roles.Get(role.Name)
 		if err == nil {
 			return fmt.Errorf("role still exists")
 		}
@@ -127,3 +261,49 @@ EOT
   run_list = ["terraform@1.0.0", "recipe[consul]", "role[foo]"]
 }
 `
+
+func testAccRoleConfig_deletionProtection(protect bool) string {
+	return fmt.Sprintf(`
+resource "chef_role" "test" {
+  name                = "terraform-acc-test-deletion-protection-{{.}}"
+  deletion_protection = %t
+}
+`, protect)
+}
+
+const testAccRoleConfig_envRunListJson = `
+resource "chef_role" "test" {
+  name              = "terraform-acc-test-env-run-list-{{.}}"
+  env_run_list_json = jsonencode({
+    production = ["recipe[terraform]", "role[foo]"]
+    staging    = ["recipe[terraform::test]"]
+  })
+}
+`
+
+const testAccRoleConfig_envRunList = `
+resource "chef_role" "test" {
+  name = "terraform-acc-test-env-run-list-{{.}}"
+
+  env_run_list {
+    environment = "production"
+    run_list    = ["terraform", "role[foo]"]
+  }
+  env_run_list {
+    environment = "staging"
+    run_list    = ["terraform::test"]
+  }
+}
+`
+
+const testAccRoleConfig_envRunListConflict = `
+resource "chef_role" "test" {
+  name              = "terraform-acc-test-env-run-list-{{.}}"
+  env_run_list_json = "{}"
+
+  env_run_list {
+    environment = "production"
+    run_list    = ["terraform"]
+  }
+}
+`
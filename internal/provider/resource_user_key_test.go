@@ -10,20 +10,26 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
+// chefUserKey is the key read back from the Chef server by the checks below.
+type chefUserKey struct {
+	User string
+	Key  chefc.AccessKey
+}
+
 func TestAccUserKey_basic(t *testing.T) {
 	var key chefUserKey
 
 	resource.Test(t, resource.TestCase{
-		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: providerFactories,
-		CheckDestroy:      testAccUserKeyCheckDestroy(&key),
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		CheckDestroy:             testAccUserKeyCheckDestroy(&key),
 		Steps: []resource.TestStep{
 			{
 				Config: testSuffixRender(testAccUserKeyConfig_basic),
 				Check: resource.ComposeTestCheckFunc(
 					testAccUserKeyCheckExists("chef_user_key.test", &key),
 					func(s *terraform.State) error {
-						if expected := "bdwyertech-github"; key.User != expected {
+						if expected := testAccUser; key.User != expected {
 							return fmt.Errorf("wrong name; expected %v, got %v", expected, key.User)
 						}
 						if expected := "testing" + testSuffix; key.Key.Name != expected {
@@ -33,6 +39,11 @@ func TestAccUserKey_basic(t *testing.T) {
 					},
 				),
 			},
+			{
+				ResourceName:      "chef_user_key.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 }
@@ -48,7 +59,10 @@ func testAccUserKeyCheckExists(rn string, key *chefUserKey) resource.TestCheckFu
 			return fmt.Errorf("key id not set")
 		}
 
-		c := testAccProvider.Meta().(*chefClient)
+		c, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		gotClient, err := c.Users.Get(rs.Primary.Attributes["user"])
 		if err != nil {
 			return fmt.Errorf("error getting client: %s", err)
@@ -69,7 +83,10 @@ func testAccUserKeyCheckExists(rn string, key *chefUserKey) resource.TestCheckFu
 
 func testAccUserKeyCheckDestroy(key *chefUserKey) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
-		c := testAccProvider.Meta().(*chefClient)
+		c, err := testAccClient()
+		if err != nil {
+			return err
+		}
 		o, err := c.Global.Users.GetKey(key.User, key.Key.Name)
 		if err == nil {
 			return fmt.Errorf("key still exists: %#v", o)
@@ -0,0 +1 @@
+default['fixture']['version'] = '1.2.0'
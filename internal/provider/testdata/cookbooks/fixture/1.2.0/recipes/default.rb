@@ -0,0 +1,3 @@
+package 'fixture' do
+  version node['fixture']['version']
+end
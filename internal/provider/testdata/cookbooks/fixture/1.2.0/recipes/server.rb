@@ -0,0 +1,5 @@
+include_recipe 'fixture::default'
+
+service 'fixture' do
+  action [:enable, :start]
+end
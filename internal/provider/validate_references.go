@@ -0,0 +1,381 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+var (
+	runListRecipeRegexp = regexp.MustCompile(`^recipe\[([\w.-]+)(?:::([\w.-]+))?(?:@(\d+\.\d+(?:\.\d+)?))?\]$`)
+	runListRoleRegexp   = regexp.MustCompile(`^role\[([\w.-]+)\]$`)
+)
+
+// validateRunListEntrySyntax checks that a normalized run list entry has the
+// form recipe[cookbook::recipe@version] or role[name].
+func validateRunListEntrySyntax(entry string) error {
+	if runListRecipeRegexp.MatchString(entry) || runListRoleRegexp.MatchString(entry) {
+		return nil
+	}
+	return fmt.Errorf("%q is not a valid run list entry, expected recipe[cookbook::recipe@version] or role[name]", entry)
+}
+
+// referenceValidator checks the objects that a node or role refers to
+// against the Chef server. Lookups are cached for the lifetime of the
+// validator, which is a single ModifyPlan call. Each invalid reference is
+// reported as an error on the attribute, or list entry, that holds it.
+type referenceValidator struct {
+	client       *chefClient
+	roles        map[string]bool
+	environments map[string]bool
+	recipes      map[string]map[string]bool
+	diags        diag.Diagnostics
+}
+
+func newReferenceValidator(client *chefClient) *referenceValidator {
+	return &referenceValidator{
+		client:       client,
+		roles:        map[string]bool{},
+		environments: map[string]bool{},
+		recipes:      map[string]map[string]bool{},
+	}
+}
+
+func (v *referenceValidator) errorf(p path.Path, format string, a ...interface{}) {
+	v.diags.AddAttributeError(p, "Invalid reference", fmt.Sprintf(format, a...))
+}
+
+func (v *referenceValidator) environmentExists(name string) (bool, error) {
+	if exists, ok := v.environments[name]; ok {
+		return exists, nil
+	}
+	_, err := v.client.Environments.Get(name)
+	exists, err := existsFromError(err)
+	if err != nil {
+		return false, err
+	}
+	v.environments[name] = exists
+	return exists, nil
+}
+
+func (v *referenceValidator) roleExists(name string) (bool, error) {
+	if exists, ok := v.roles[name]; ok {
+		return exists, nil
+	}
+	_, err := v.client.Roles.Get(name)
+	exists, err := existsFromError(err)
+	if err != nil {
+		return false, err
+	}
+	v.roles[name] = exists
+	return exists, nil
+}
+
+// recipeAvailable reports whether a recipe is available in an environment.
+// An empty environment means any version of any cookbook on the server.
+func (v *referenceValidator) recipeAvailable(environment, recipe string) (bool, error) {
+	recipes, ok := v.recipes[environment]
+	if !ok {
+		var result []string
+		var err error
+		if environment == "" {
+			result, err = v.client.Cookbooks.ListAllRecipes()
+		} else {
+			result, err = v.client.Environments.ListRecipes(environment)
+		}
+		if err != nil {
+			return false, err
+		}
+		recipes = make(map[string]bool, len(result))
+		for _, r := range result {
+			recipes[strings.TrimSuffix(r, "::default")] = true
+		}
+		v.recipes[environment] = recipes
+	}
+	return recipes[strings.TrimSuffix(recipe, "::default")], nil
+}
+
+// validateEnvironment checks that an environment exists and reports whether
+// recipes can be checked against it.
+func (v *referenceValidator) validateEnvironment(p path.Path, attr, environment string) (bool, error) {
+	exists, err := v.environmentExists(environment)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		v.errorf(p, "%s: environment %q does not exist", attr, environment)
+	}
+	return exists, nil
+}
+
+// validateRunList checks each entry of a run list, which entryPath locates.
+// Entries for which known returns false are values that will only be known
+// after apply, typically because they refer to an object created in the same
+// plan, and are skipped. If checkRecipes is false only syntax and roles are
+// checked.
+func (v *referenceValidator) validateRunList(entryPath func(i int) path.Path, attr string, runList []string, known func(i int) bool, environment string, checkRecipes bool) error {
+	for i, entry := range runList {
+		if !known(i) {
+			continue
+		}
+
+		entry = runListEntryStateFunc(entry)
+		if err := validateRunListEntrySyntax(entry); err != nil {
+			v.errorf(entryPath(i), "%s.%d: %s", attr, i, err)
+			continue
+		}
+
+		if m := runListRoleRegexp.FindStringSubmatch(entry); m != nil {
+			exists, err := v.roleExists(m[1])
+			if err != nil {
+				return err
+			}
+			if !exists {
+				v.errorf(entryPath(i), "%s.%d: role %q does not exist", attr, i, m[1])
+			}
+			continue
+		}
+
+		if !checkRecipes {
+			continue
+		}
+		m := runListRecipeRegexp.FindStringSubmatch(entry)
+		recipe := m[1]
+		if m[2] != "" {
+			recipe += "::" + m[2]
+		}
+		available, err := v.recipeAvailable(environment, recipe)
+		if err != nil {
+			return err
+		}
+		if !available {
+			if environment == "" {
+				v.errorf(entryPath(i), "%s.%d: recipe %q is not available on the Chef server", attr, i, recipe)
+			} else {
+				v.errorf(entryPath(i), "%s.%d: recipe %q is not available in environment %q", attr, i, recipe, environment)
+			}
+		}
+	}
+	return nil
+}
+
+func existsFromError(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if isNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// runListFromPlan returns the entries of a planned run list along with a
+// function reporting which of them are known.
+func runListFromPlan(l types.List) ([]string, func(i int) bool) {
+	elems := l.Elements()
+	runList := make([]string, len(elems))
+	for i, e := range elems {
+		if s, ok := e.(types.String); ok {
+			runList[i] = s.ValueString()
+		}
+	}
+	return runList, func(i int) bool {
+		return !elems[i].IsUnknown()
+	}
+}
+
+// listEntryPath locates the entries of a list attribute.
+func listEntryPath(p path.Path) func(i int) path.Path {
+	return func(i int) path.Path {
+		return p.AtListIndex(i)
+	}
+}
+
+func (v *referenceValidator) diagnostics(err error) diag.Diagnostics {
+	if err != nil {
+		var diags diag.Diagnostics
+		diags.AddError("Error validating references", err.Error())
+		return diags
+	}
+	return v.diags
+}
+
+func validateNodeReferences(ctx context.Context, client *chefClient, plan, state *nodeResourceModel) diag.Diagnostics {
+	if client == nil || !client.ValidateReferences {
+		return nil
+	}
+	if state != nil && plan.EnvironmentName.Equal(state.EnvironmentName) && plan.RunList.Equal(state.RunList) {
+		return nil
+	}
+	if plan.RunList.IsUnknown() {
+		return nil
+	}
+
+	v := newReferenceValidator(client)
+
+	environment := plan.EnvironmentName.ValueString()
+	checkRecipes := !plan.EnvironmentName.IsUnknown()
+	if checkRecipes {
+		exists, err := v.validateEnvironment(path.Root("environment_name"), "environment_name", environment)
+		if err != nil {
+			return v.diagnostics(err)
+		}
+		checkRecipes = exists
+	}
+
+	runList, known := runListFromPlan(plan.RunList)
+	return v.diagnostics(v.validateRunList(listEntryPath(path.Root("run_list")), "run_list", runList, known, environment, checkRecipes))
+}
+
+func validateRoleReferences(ctx context.Context, client *chefClient, plan, state *roleResourceModel) diag.Diagnostics {
+	if client == nil || !client.ValidateReferences {
+		return nil
+	}
+	if state != nil && plan.RunList.Equal(state.RunList) && plan.EnvRunListJson.Equal(state.EnvRunListJson) && plan.EnvRunList.Equal(state.EnvRunList) {
+		return nil
+	}
+
+	v := newReferenceValidator(client)
+
+	if !plan.RunList.IsUnknown() {
+		runList, known := runListFromPlan(plan.RunList)
+		if err := v.validateRunList(listEntryPath(path.Root("run_list")), "run_list", runList, known, "", true); err != nil {
+			return v.diagnostics(err)
+		}
+	}
+
+	if elems := plan.EnvRunList.Elements(); len(elems) > 0 {
+		// Blocks are checked by environment, and located by their value, as
+		// they are elements of a set.
+		envRunLists := make([]roleEnvRunListModel, len(elems))
+		for i, elem := range elems {
+			obj, ok := elem.(types.Object)
+			if !ok {
+				continue
+			}
+			if diags := obj.As(ctx, &envRunLists[i], basetypes.ObjectAsOptions{}); diags.HasError() {
+				return diags
+			}
+		}
+		order := make([]int, len(elems))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return envRunLists[order[i]].Environment.ValueString() < envRunLists[order[j]].Environment.ValueString()
+		})
+
+		for _, i := range order {
+			e := envRunLists[i]
+			if e.Environment.IsUnknown() || e.RunList.IsUnknown() {
+				continue
+			}
+			environment := e.Environment.ValueString()
+			attr := fmt.Sprintf("env_run_list[%q]", environment)
+			block := path.Root("env_run_list").AtSetValue(elems[i])
+			exists, err := v.validateEnvironment(block.AtName("environment"), attr, environment)
+			if err != nil {
+				return v.diagnostics(err)
+			}
+			runList, known := runListFromPlan(e.RunList)
+			if err := v.validateRunList(listEntryPath(block.AtName("run_list")), attr, runList, known, environment, exists); err != nil {
+				return v.diagnostics(err)
+			}
+		}
+	} else if !plan.EnvRunListJson.IsUnknown() && !plan.EnvRunListJson.IsNull() {
+		var envRunList map[string][]string
+		if err := json.Unmarshal([]byte(plan.EnvRunListJson.ValueString()), &envRunList); err != nil {
+			v.errorf(path.Root("env_run_list_json"), "env_run_list_json: %s", err)
+			return v.diagnostics(nil)
+		}
+
+		environments := make([]string, 0, len(envRunList))
+		for environment := range envRunList {
+			environments = append(environments, environment)
+		}
+		sort.Strings(environments)
+
+		// Entries within the JSON string cannot be located more precisely
+		// than the argument; the message names them.
+		jsonPath := path.Root("env_run_list_json")
+		for _, environment := range environments {
+			attr := fmt.Sprintf("env_run_list_json[%q]", environment)
+			exists, err := v.validateEnvironment(jsonPath, attr, environment)
+			if err != nil {
+				return v.diagnostics(err)
+			}
+			known := func(int) bool { return true }
+			entryPath := func(int) path.Path { return jsonPath }
+			if err := v.validateRunList(entryPath, attr, envRunList[environment], known, environment, exists); err != nil {
+				return v.diagnostics(err)
+			}
+		}
+	}
+
+	return v.diagnostics(nil)
+}
+
+func validateEnvironmentReferences(ctx context.Context, client *chefClient, plan, state *environmentResourceModel) diag.Diagnostics {
+	if client == nil || !client.ValidateReferences {
+		return nil
+	}
+	if state != nil && plan.CookbookConstraints.Equal(state.CookbookConstraints) {
+		return nil
+	}
+
+	v := newReferenceValidator(client)
+
+	elems := plan.CookbookConstraints.Elements()
+	names := make([]string, 0, len(elems))
+	for name := range elems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s, ok := elems[name].(types.String)
+		if !ok || s.IsUnknown() || s.IsNull() {
+			continue
+		}
+		c, err := parseChefVersionConstraint(s.ValueString())
+		if err != nil {
+			// Reported by ValidateConfig.
+			continue
+		}
+
+		attr := fmt.Sprintf("cookbook_constraints[%q]", name)
+		constraintPath := path.Root("cookbook_constraints").AtMapKey(name)
+		available, err := client.Cookbooks.GetAvailableVersions(name, "all")
+		if isNotFound(err) {
+			v.errorf(constraintPath, "%s: cookbook %q does not exist", attr, name)
+			continue
+		}
+		if err != nil {
+			return v.diagnostics(err)
+		}
+
+		var versions []string
+		satisfied := false
+		for _, cv := range available[name].Versions {
+			version, err := parseChefVersion(cv.Version)
+			if err != nil {
+				continue
+			}
+			versions = append(versions, cv.Version)
+			satisfied = satisfied || c.satisfiedBy(version)
+		}
+		if !satisfied {
+			v.errorf(constraintPath, "%s: no version of cookbook %q satisfies %q, available versions are %s", attr, name, c.normalize(), strings.Join(versions, ", "))
+		}
+	}
+
+	return v.diagnostics(nil)
+}
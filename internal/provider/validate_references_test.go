@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestValidateRunListEntrySyntax(t *testing.T) {
+	valid := []string{
+		"recipe[apache2]",
+		"recipe[apache2::mod_ssl]",
+		"recipe[apache2@1.0]",
+		"recipe[apache2::mod_ssl@1.0.3]",
+		"recipe[my-cookbook.v2::default]",
+		"role[web_server]",
+	}
+	for _, entry := range valid {
+		if err := validateRunListEntrySyntax(entry); err != nil {
+			t.Errorf("%q: unexpected error: %s", entry, err)
+		}
+	}
+
+	invalid := []string{
+		"recipe[]",
+		"recipe[apache2@latest]",
+		"recipe[apache2::]",
+		"recipe[apache2 ]",
+		"role[web@1.0.0]",
+		"role[a::b]",
+		"policy[foo]",
+		"apache2",
+	}
+	for _, entry := range invalid {
+		if err := validateRunListEntrySyntax(entry); err == nil {
+			t.Errorf("%q: expected an error", entry)
+		}
+	}
+}
+
+func TestAccValidateReferences_node(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testSuffixRender(testAccValidateReferencesConfig_missingRole),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`run_list\.0: role "terraform-acc-test-missing-[0-9a-f]+" does not exist`),
+			},
+			{
+				Config:      testSuffixRender(testAccValidateReferencesConfig_missingEnvironment),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`environment_name: environment "terraform-acc-test-missing-[0-9a-f]+" does not exist`),
+			},
+			{
+				Config: testSuffixRender(testAccValidateReferencesConfig_samePlan),
+			},
+		},
+	})
+}
+
+func TestAccValidateReferences_environment(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: protoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testSuffixRender(testAccValidateReferencesConfig_missingCookbook),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`cookbook_constraints\["terraform-acc-test-missing-[0-9a-f]+"\]: cookbook "terraform-acc-test-missing-[0-9a-f]+" does not exist`),
+			},
+		},
+	})
+}
+
+// TestAccValidateReferences_paths checks that each invalid reference is
+// reported on the run list entry or block that holds it.
+func TestAccValidateReferences_paths(t *testing.T) {
+	if os.Getenv("TF_ACC") == "" {
+		t.Skip("acceptance tests are skipped unless TF_ACC is set")
+	}
+	testAccPreCheck(t)
+
+	client, err := testAccClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ValidateReferences = true
+	ctx := context.Background()
+
+	runList := types.ListValueMust(types.StringType, []attr.Value{
+		types.StringValue("role[terraform-acc-test-missing-a]"),
+		types.StringValue("not a run list entry"),
+		types.StringValue("role[terraform-acc-test-missing-b]"),
+	})
+	diags := validateNodeReferences(ctx, client, &nodeResourceModel{
+		EnvironmentName: types.StringValue("_default"),
+		RunList:         runList,
+	}, nil)
+	want := []path.Path{
+		path.Root("run_list").AtListIndex(0),
+		path.Root("run_list").AtListIndex(1),
+		path.Root("run_list").AtListIndex(2),
+	}
+	checkDiagnosticPaths(t, diags, want)
+
+	block := types.ObjectValueMust(roleEnvRunListType.AttrTypes, map[string]attr.Value{
+		"environment": types.StringValue("terraform-acc-test-missing"),
+		"run_list":    runList,
+	})
+	diags = validateRoleReferences(ctx, client, &roleResourceModel{
+		RunList:        types.ListNull(types.StringType),
+		EnvRunList:     types.SetValueMust(roleEnvRunListType, []attr.Value{block}),
+		EnvRunListJson: types.StringNull(),
+	}, nil)
+	blockPath := path.Root("env_run_list").AtSetValue(block)
+	want = []path.Path{
+		blockPath.AtName("environment"),
+		blockPath.AtName("run_list").AtListIndex(0),
+		blockPath.AtName("run_list").AtListIndex(1),
+		blockPath.AtName("run_list").AtListIndex(2),
+	}
+	checkDiagnosticPaths(t, diags, want)
+}
+
+func checkDiagnosticPaths(t *testing.T, diags diag.Diagnostics, want []path.Path) {
+	t.Helper()
+	if len(diags) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %v", len(want), diags)
+	}
+	for i, d := range diags {
+		withPath, ok := d.(diag.DiagnosticWithPath)
+		if !ok || !withPath.Path().Equal(want[i]) {
+			t.Errorf("diagnostic %d: expected it on %s, got %v", i, want[i], d)
+		}
+	}
+}
+
+const testAccValidateReferencesConfig_missingRole = `
+provider "chef" {
+  validate_references = true
+}
+
+resource "chef_node" "test" {
+  name     = "terraform-acc-test-references-{{.}}"
+  run_list = ["role[terraform-acc-test-missing-{{.}}]"]
+}
+`
+
+const testAccValidateReferencesConfig_missingEnvironment = `
+provider "chef" {
+  validate_references = true
+}
+
+resource "chef_node" "test" {
+  name             = "terraform-acc-test-references-{{.}}"
+  environment_name = "terraform-acc-test-missing-{{.}}"
+}
+`
+
+const testAccValidateReferencesConfig_samePlan = `
+provider "chef" {
+  validate_references = true
+}
+
+resource "chef_environment" "test" {
+  name = "terraform-acc-test-references-{{.}}"
+}
+
+resource "chef_role" "test" {
+  name = "terraform-acc-test-references-{{.}}"
+}
+
+resource "chef_node" "test" {
+  name             = "terraform-acc-test-references-{{.}}"
+  environment_name = chef_environment.test.id
+  run_list         = ["role[${chef_role.test.id}]"]
+}
+`
+
+const testAccValidateReferencesConfig_missingCookbook = `
+provider "chef" {
+  validate_references = true
+}
+
+resource "chef_environment" "test" {
+  name = "terraform-acc-test-references-{{.}}"
+  cookbook_constraints = {
+    "terraform-acc-test-missing-{{.}}" = "~> 1.0"
+  }
+}
+`
@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// chefVersion is a cookbook version as understood by Chef::Version: two or
+// three dot separated integers. Parts records how many components were
+// written so that the constraint can be rendered back the way the user
+// wrote it, which matters for the pessimistic "~>" operator.
+type chefVersion struct {
+	Major, Minor, Patch int
+	Parts               int
+}
+
+var chefVersionRegexp = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?$`)
+
+func parseChefVersion(s string) (chefVersion, error) {
+	m := chefVersionRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return chefVersion{}, fmt.Errorf("%q is not a valid cookbook version, expected x.y or x.y.z", s)
+	}
+
+	v := chefVersion{Parts: 2}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+		v.Parts = 3
+	}
+	return v, nil
+}
+
+func (v chefVersion) compare(o chefVersion) int {
+	switch {
+	case v.Major != o.Major:
+		return compareInt(v.Major, o.Major)
+	case v.Minor != o.Minor:
+		return compareInt(v.Minor, o.Minor)
+	default:
+		return compareInt(v.Patch, o.Patch)
+	}
+}
+
+func (v chefVersion) String() string {
+	if v.Parts == 2 {
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// chefVersionConstraint is a single Chef::VersionConstraint such as
+// "~> 1.2" or ">= 2.0.0".
+type chefVersionConstraint struct {
+	Op      string
+	Version chefVersion
+}
+
+var chefVersionConstraintRegexp = regexp.MustCompile(`^(~>|>=|<=|=|>|<)?\s*(\S+)$`)
+
+// parseChefVersionConstraint parses a constraint the way the Chef server
+// does. A bare version is treated as an exact match.
+func parseChefVersionConstraint(s string) (chefVersionConstraint, error) {
+	m := chefVersionConstraintRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return chefVersionConstraint{}, fmt.Errorf("%q is not a valid version constraint", s)
+	}
+
+	v, err := parseChefVersion(m[2])
+	if err != nil {
+		return chefVersionConstraint{}, fmt.Errorf("%q is not a valid version constraint: %s", s, err)
+	}
+
+	op := m[1]
+	if op == "" {
+		op = "="
+	}
+	return chefVersionConstraint{Op: op, Version: v}, nil
+}
+
+func (c chefVersionConstraint) satisfiedBy(v chefVersion) bool {
+	cmp := v.compare(c.Version)
+	switch c.Op {
+	case "=":
+		return cmp == 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "~>":
+		if cmp < 0 {
+			return false
+		}
+		if c.Version.Parts == 2 {
+			return v.Major == c.Version.Major
+		}
+		return v.Major == c.Version.Major && v.Minor == c.Version.Minor
+	}
+	return false
+}
+
+func (c chefVersionConstraint) String() string {
+	return c.Op + " " + c.Version.String()
+}
+
+// normalize returns the constraint in the form the Chef server stores it,
+// such as "= 1.0.0". The pessimistic operator keeps the number of components
+// it was written with, as that changes which versions it allows.
+func (c chefVersionConstraint) normalize() string {
+	if c.Op != "~>" {
+		c.Version.Parts = 3
+	}
+	return c.String()
+}
+
+// cookbookConstraintsEquivalent reports whether two sets of cookbook
+// constraints only differ in how the constraints are written.
+func cookbookConstraintsEquivalent(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, constraint := range a {
+		other, ok := b[name]
+		if !ok {
+			return false
+		}
+		if constraint == other {
+			continue
+		}
+		c, err := parseChefVersionConstraint(constraint)
+		if err != nil {
+			return false
+		}
+		o, err := parseChefVersionConstraint(other)
+		if err != nil || c.normalize() != o.normalize() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package provider
+
+import "testing"
+
+func TestChefVersionConstraintNormalize(t *testing.T) {
+	cases := map[string]string{
+		"= 1.0.0":  "= 1.0.0",
+		"1.0":      "= 1.0.0",
+		"~>1.0":    "~> 1.0",
+		"~> 1.0.2": "~> 1.0.2",
+		">=2.1":    ">= 2.1.0",
+		" < 3.0 ":  "< 3.0.0",
+	}
+	for in, expected := range cases {
+		c, err := parseChefVersionConstraint(in)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", in, err)
+			continue
+		}
+		if got := c.normalize(); got != expected {
+			t.Errorf("%q: expected %q, got %q", in, expected, got)
+		}
+	}
+
+	for _, in := range []string{"^1.0", "!= 1.0", "~> 1", "= 1.0.0.0", ""} {
+		if _, err := parseChefVersionConstraint(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestCookbookConstraintsEquivalent(t *testing.T) {
+	a := map[string]string{"apache2": "1.0", "nginx": "~>2.1"}
+	if !cookbookConstraintsEquivalent(a, map[string]string{"apache2": "= 1.0.0", "nginx": "~> 2.1"}) {
+		t.Errorf("expected constraints that are written differently to be equivalent")
+	}
+	if cookbookConstraintsEquivalent(a, map[string]string{"apache2": "= 1.0.0", "nginx": "~> 2.1.0"}) {
+		t.Errorf("expected pessimistic constraints with a different number of components to differ")
+	}
+	if cookbookConstraintsEquivalent(a, map[string]string{"apache2": "= 1.0.0"}) {
+		t.Errorf("expected a missing constraint to differ")
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"log"
 
 	"github.com/bdwyertech/terraform-provider-chef/internal/provider"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6/tf6server"
 )
 
 // Run "go generate" to format example terraform files and generate the docs for the registry/website
@@ -27,13 +29,25 @@ var (
 )
 
 func main() {
-	opts := &plugin.ServeOpts{
-		ProviderAddr: "registry.terraform.io/bdwyertech/chef",
-		ProviderFunc: provider.New(version),
-	}
+	var debug bool
 
-	flag.BoolVar(&opts.Debug, "debug", false, "set to true to run the provider with support for debuggers like delve")
+	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")
 	flag.Parse()
 
-	plugin.Serve(opts)
+	ctx := context.Background()
+
+	muxServer, err := provider.NewMuxServer(ctx, version)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var serveOpts []tf6server.ServeOpt
+	if debug {
+		serveOpts = append(serveOpts, tf6server.WithManagedDebug())
+	}
+
+	err = tf6server.Serve("registry.terraform.io/bdwyertech/chef", muxServer, serveOpts...)
+	if err != nil {
+		log.Fatal(err)
+	}
 }